@@ -11,22 +11,42 @@ import (
 )
 
 type ControllerManagerConfig struct {
-	KubeOptions      *config.KubernetesOptions
-	HarborOptions    *config.HarborOptions
-	GitlabOptions    *config.GitlabOptions
-	IntegrateOptions []*config.IntegrateOption
-	LeaderElect      bool
-	LeaderElection   *leaderelection.LeaderElectionConfig
+	KubeOptions           *config.KubernetesOptions
+	SCMProvider           string
+	RegistryProvider      string
+	HarborOptions         *config.HarborOptions
+	DistributionOptions   *config.DistributionOptions
+	GitlabOptions         *config.GitlabOptions
+	GiteaOptions          *config.GiteaOptions
+	IntegrateOptions      []*config.IntegrateOption
+	IngressOptions        *config.IngressOptions
+	EnvironmentOverrides  []*config.EnvironmentOverride
+	NamespaceOptions      *config.NamespaceOptions
+	RoleOptions           *config.RoleOptions
+	ProjectDeletionPolicy string
+	Controllers           map[string]*config.ControllerOptions
+	EnabledControllers    []string
+	Integrations          config.Integrations
+	LeaderElect           bool
+	LeaderElection        *leaderelection.LeaderElectionConfig
+	EnableWebhook         bool
+	WebhookCertDir        string
 }
 
 func NewControllerManagerConfigOptions() *ControllerManagerConfig {
 
 	return &ControllerManagerConfig{
-		KubeOptions:      config.NewKubernetesConfig(),
-		HarborOptions:    nil,
-		GitlabOptions:    nil,
-		IntegrateOptions: nil,
-		LeaderElect:      false,
+		KubeOptions:        config.NewKubernetesConfig(),
+		HarborOptions:      nil,
+		GitlabOptions:      nil,
+		IntegrateOptions:   nil,
+		EnabledControllers: []string{"*"},
+		Integrations: config.Integrations{
+			SCM:        true,
+			Registry:   true,
+			Federation: true,
+		},
+		LeaderElect: false,
 		LeaderElection: &leaderelection.LeaderElectionConfig{
 			LeaseDuration: 30 * time.Second,
 			RenewDeadline: 15 * time.Second,
@@ -38,13 +58,27 @@ func NewControllerManagerConfigOptions() *ControllerManagerConfig {
 func (c *ControllerManagerConfig) Validate() []error {
 	var errs []error
 	errs = append(errs, c.KubeOptions.Validate()...)
-	errs = append(errs, c.GitlabOptions.Validate()...)
-	errs = append(errs, c.HarborOptions.Validate()...)
+	errs = append(errs, c.IntegrationConfig().Validate(c.Integrations)...)
+	return errs
+}
 
-	for _, ingegrateOption := range c.IntegrateOptions {
-		errs = append(errs, ingegrateOption.Validate()...)
+// IntegrationConfig returns the hot reloadable part of the config.
+func (c *ControllerManagerConfig) IntegrationConfig() *config.IntegrationConfig {
+	return &config.IntegrationConfig{
+		SCMProvider:           c.SCMProvider,
+		RegistryProvider:      c.RegistryProvider,
+		HarborOptions:         c.HarborOptions,
+		DistributionOptions:   c.DistributionOptions,
+		GitlabOptions:         c.GitlabOptions,
+		GiteaOptions:          c.GiteaOptions,
+		IntegrateOptions:      c.IntegrateOptions,
+		IngressOptions:        c.IngressOptions,
+		EnvironmentOverrides:  c.EnvironmentOverrides,
+		NamespaceOptions:      c.NamespaceOptions,
+		RoleOptions:           c.RoleOptions,
+		ProjectDeletionPolicy: c.ProjectDeletionPolicy,
+		Controllers:           c.Controllers,
 	}
-	return errs
 }
 
 func (c *ControllerManagerConfig) Flags() cliflag.NamedFlagSets {
@@ -57,6 +91,20 @@ func (c *ControllerManagerConfig) Flags() cliflag.NamedFlagSets {
 		"Whether to enable leader election. This field should be enabled when controller manager "+
 		"deployed with multiple replicas.")
 
+	cfs := fss.FlagSet("controllers")
+	cfs.StringSliceVar(&c.EnabledControllers, "controllers", c.EnabledControllers, ""+
+		"A list of controllers to enable. '*' enables all controllers not disabled in the "+
+		"Controllers config, 'foo' enables the controller named 'foo', '-foo' disables the "+
+		"controller named 'foo'.")
+
+	wfs := fss.FlagSet("webhook")
+	wfs.BoolVar(&c.EnableWebhook, "enable-webhook", c.EnableWebhook, ""+
+		"Whether to serve the admission webhook validating workspace, application and user names "+
+		"on port 9443.")
+	wfs.StringVar(&c.WebhookCertDir, "webhook-cert-dir", c.WebhookCertDir, ""+
+		"Directory of the tls.crt and tls.key of the admission webhook, default to "+
+		"/tmp/k8s-webhook-server/serving-certs.")
+
 	kfs := fss.FlagSet("klog")
 	local := flag.NewFlagSet("klog", flag.ExitOnError)
 	klog.InitFlags(local)
@@ -9,9 +9,11 @@ import (
 	"github.com/hchenc/iceberg/pkg/constants"
 	icecontroller "github.com/hchenc/iceberg/pkg/controllers"
 	"github.com/hchenc/iceberg/pkg/utils/term"
+	icewebhook "github.com/hchenc/iceberg/pkg/webhook"
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/klog"
 	"k8s.io/klog/klogr"
@@ -27,12 +29,26 @@ func NewControllerManagerCommandOptions() *cobra.Command {
 	conf, err := config.TryLoadFromDisk()
 	if err == nil {
 		s = &options.ControllerManagerConfig{
-			KubeOptions:      s.KubeOptions,
-			HarborOptions:    conf.HarborOptions,
-			GitlabOptions:    conf.GitlabOptions,
-			IntegrateOptions: conf.IntegrateOptions,
-			LeaderElect:      s.LeaderElect,
-			LeaderElection:   s.LeaderElection,
+			KubeOptions:           s.KubeOptions,
+			SCMProvider:           conf.SCMProvider,
+			RegistryProvider:      conf.RegistryProvider,
+			HarborOptions:         conf.HarborOptions,
+			DistributionOptions:   conf.DistributionOptions,
+			GitlabOptions:         conf.GitlabOptions,
+			GiteaOptions:          conf.GiteaOptions,
+			IntegrateOptions:      conf.IntegrateOptions,
+			IngressOptions:        conf.IngressOptions,
+			EnvironmentOverrides:  conf.EnvironmentOverrides,
+			NamespaceOptions:      conf.NamespaceOptions,
+			RoleOptions:           conf.RoleOptions,
+			ProjectDeletionPolicy: conf.ProjectDeletionPolicy,
+			Controllers:           conf.Controllers,
+			EnabledControllers:    s.EnabledControllers,
+			Integrations:          s.Integrations,
+			LeaderElect:           s.LeaderElect,
+			LeaderElection:        s.LeaderElection,
+			EnableWebhook:         s.EnableWebhook,
+			WebhookCertDir:        s.WebhookCertDir,
 		}
 	} else {
 		klog.Fatal("Failed to load configuration from disk", err)
@@ -43,12 +59,20 @@ func NewControllerManagerCommandOptions() *cobra.Command {
 		Short: "",
 		Long:  "Iceberg controller-manager",
 		Run: func(cmd *cobra.Command, args []string) {
+			enabled, err := icecontroller.EnabledReconcilers(s.EnabledControllers, s.IntegrationConfig())
+			if err != nil {
+				klog.Error(err)
+				os.Exit(1)
+			}
+			// options and clients of the systems no enabled controller uses
+			// aren't required
+			s.Integrations = icecontroller.IntegrationsOf(enabled)
 			if errs := s.Validate(); len(errs) != 0 {
 				klog.Error(utilerrors.NewAggregate(errs))
 				os.Exit(1)
 			}
 
-			if err = run(s, signals.SetupSignalHandler()); err != nil {
+			if err = run(s, enabled, signals.SetupSignalHandler()); err != nil {
 				klog.Error(err)
 				os.Exit(1)
 			}
@@ -72,12 +96,13 @@ func NewControllerManagerCommandOptions() *cobra.Command {
 	return cmd
 }
 
-func run(s *options.ControllerManagerConfig, ctx context.Context) error {
+func run(s *options.ControllerManagerConfig, enabled []string, ctx context.Context) error {
 	scheme := runtime.NewScheme()
 
 	mgrOptions := manager.Options{
-		Scheme: scheme,
-		Port:   9443,
+		Scheme:  scheme,
+		Port:    9443,
+		CertDir: s.WebhookCertDir,
 	}
 	if s.LeaderElect {
 		mgrOptions.LeaderElection = s.LeaderElect
@@ -95,8 +120,30 @@ func run(s *options.ControllerManagerConfig, ctx context.Context) error {
 	if err != nil {
 		klog.Fatalf("unable to set up overall controller manager: %v", err)
 	}
-	cs := clientset.NewClientSetForControllerManagerConfigOptions(s)
-	controller := icecontroller.NewControllerOrDie(cs, mgr)
+	integrationConfig := s.IntegrationConfig()
+	// credentials kept in secrets can only be read once kubeconfig is ready
+	if err = integrationConfig.ResolveCredentials(kubernetes.NewForConfigOrDie(s.KubeOptions.KubeConfig)); err != nil {
+		klog.Fatalf("unable to resolve credentials: %v", err)
+	}
+
+	cs, err := clientset.NewClientSetForControllerManagerConfigOptions(s)
+	if err != nil {
+		klog.Fatalf("unable to set up clientset: %v", err)
+	}
+	if err = cs.CheckConnectivity(); err != nil {
+		klog.Fatalf("integration check failed: %v", err)
+	}
+
+	watcher := config.NewWatcher(cs.Kubeclient, integrationConfig, s.Integrations, func(conf *config.IntegrationConfig) {
+		if err := cs.Reload(conf); err != nil {
+			klog.Errorf("unable to reload integration clients: %v", err)
+		}
+	})
+	go watcher.Start(ctx)
+	controller := icecontroller.NewControllerOrDie(cs, mgr, enabled)
+	if s.EnableWebhook {
+		icewebhook.SetUp(mgr, cs.Config)
+	}
 
 	if err = controller.Reconcile(ctx); err != nil {
 		klog.Fatalf("unable to run the manager: %v", err)
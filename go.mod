@@ -3,7 +3,10 @@ module github.com/hchenc/iceberg
 go 1.16
 
 require (
+	github.com/antihax/optional v1.0.0
 	github.com/docker/docker v0.7.3-0.20190327010347-be7ac8be2ae0
+	github.com/evanphx/json-patch v4.11.0+incompatible
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/ghodss/yaml v1.0.0
 	github.com/go-logr/logr v0.4.0
 	github.com/hchenc/application v1.0.1
@@ -43,10 +43,20 @@ type GenericPlacement struct {
 	Spec GenericPlacementSpec `json:"spec,omitempty"`
 }
 
+// ClusterOverride is a json patch operation applied to the resources of a
+// member cluster, Kind and Selector limit the resources it's applied to.
 type ClusterOverride struct {
 	Op    string               `json:"op,omitempty"`
 	Path  string               `json:"path"`
 	Value runtime.RawExtension `json:"value,omitempty"`
+	// Kind of the resources the override is applied to, e.g. Deployment,
+	// every kind when empty.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// Selector of the labels of the resources the override is applied to,
+	// every resource when nil.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
 }
 
 type GenericOverrideItem struct {
@@ -74,6 +84,10 @@ type PropagationStatus string
 type GenericClusterStatus struct {
 	Name   string            `json:"name"`
 	Status PropagationStatus `json:"status,omitempty"`
+	// Overrides which failed to apply, the resources are propagated
+	// without them.
+	// +optional
+	FailedOverrides []string `json:"failedOverrides,omitempty"`
 }
 
 type GenericCondition struct {
@@ -91,6 +105,9 @@ type GenericCondition struct {
 	// (brief) reason for the condition's last transition.
 	// +optional
 	Reason AggregateReason `json:"reason,omitempty"`
+	// Human readable message of the condition.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 type GenericFederatedStatus struct {
@@ -29,6 +29,11 @@ import (
 func (in *ClusterOverride) DeepCopyInto(out *ClusterOverride) {
 	*out = *in
 	in.Value.DeepCopyInto(&out.Value)
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterOverride.
@@ -146,6 +151,11 @@ func (in *GenericClusterReference) DeepCopy() *GenericClusterReference {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GenericClusterStatus) DeepCopyInto(out *GenericClusterStatus) {
 	*out = *in
+	if in.FailedOverrides != nil {
+		in, out := &in.FailedOverrides, &out.FailedOverrides
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GenericClusterStatus.
@@ -212,7 +222,9 @@ func (in *GenericFederatedStatus) DeepCopyInto(out *GenericFederatedStatus) {
 	if in.Clusters != nil {
 		in, out := &in.Clusters, &out.Clusters
 		*out = make([]GenericClusterStatus, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -2,10 +2,17 @@ package clientset
 
 import (
 	"context"
+	"errors"
 	"github.com/hchenc/application/pkg/client/clientset/versioned"
 	"github.com/hchenc/iceberg/cmd/controller-manager/app/options"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/constants"
 	versioned2 "github.com/hchenc/pager/pkg/client/clientset/versioned"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"sync"
+	"sync/atomic"
 )
 
 type ClientSet struct {
@@ -13,30 +20,177 @@ type ClientSet struct {
 
 	Kubeclient *kubernetes.Clientset
 
+	DynamicClient dynamic.Interface
+
+	// MemberClusters are the clusters workspaces can be placed in besides
+	// the host, only set when an enabled controller uses them
+	MemberClusters *MemberClusters
+
 	AppClient *versioned.Clientset
 
 	PagerClient *versioned2.Clientset
 
+	// SCMProvider is the source code management backend in use, only the
+	// client of that backend is set and only when an enabled controller uses
+	// it
+	SCMProvider string
+
 	GitlabClient *GitlabClient
 
+	GiteaClient *GiteaClient
+
+	IntegrateClient *IntegrateClient
+
+	// RegistryProvider is the image registry backend in use, only the client
+	// of that backend is set and only when an enabled controller uses it
+	RegistryProvider string
+
 	HarborClient *HarborClient
+
+	DistributionClient *DistributionClient
+
+	// config is the integration config the clients were built from
+	config atomic.Value
+
+	lock      sync.Mutex
+	listeners []func(old, new *config.IntegrationConfig)
 }
 
-func NewClientSetForControllerManagerConfigOptions(conf *options.ControllerManagerConfig) *ClientSet {
+// Config returns the current integration config, it's replaced by Reload so
+// callers should not keep it across reconciles.
+func (cs *ClientSet) Config() *config.IntegrationConfig {
+	return cs.config.Load().(*config.IntegrationConfig)
+}
+
+func NewClientSetForControllerManagerConfigOptions(conf *options.ControllerManagerConfig) (*ClientSet, error) {
 
 	var cs ClientSet
+	var err error
 
 	cs.Ctx = context.Background()
 
 	cs.Kubeclient = kubernetes.NewForConfigOrDie(conf.KubeOptions.KubeConfig)
 
+	cs.DynamicClient = dynamic.NewForConfigOrDie(conf.KubeOptions.KubeConfig)
+
+	if conf.Integrations.Federation {
+		cs.MemberClusters = NewMemberClusters(cs.Kubeclient, constants.DevopsNamespace)
+	}
+
 	cs.AppClient = versioned.NewForConfigOrDie(conf.KubeOptions.KubeConfig)
 
 	cs.PagerClient = versioned2.NewForConfigOrDie(conf.KubeOptions.KubeConfig)
 
-	cs.GitlabClient = NewGitlabClient(conf.GitlabOptions, conf.IntegrateOptions)
+	cs.SCMProvider = config.GetSCMProvider(conf.SCMProvider)
+	if conf.Integrations.SCM {
+		switch cs.SCMProvider {
+		case config.SCMProviderGitea:
+			cs.GiteaClient, err = NewGiteaClient(conf.GiteaOptions)
+		default:
+			cs.GitlabClient, err = NewGitlabClient(conf.GitlabOptions)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		cs.IntegrateClient = NewIntegrateClient(conf.IntegrateOptions)
+	}
+
+	cs.config.Store(conf.IntegrationConfig())
+
+	cs.RegistryProvider = config.GetRegistryProvider(conf.RegistryProvider)
+	if conf.Integrations.Registry {
+		switch cs.RegistryProvider {
+		case config.RegistryProviderDistribution:
+			cs.DistributionClient, err = NewDistributionClient(conf.DistributionOptions)
+		default:
+			if conf.HarborOptions == nil {
+				err = errors.New("harbor options not found")
+			} else {
+				cs.HarborClient = NewHarborClient(conf.HarborOptions, cs.Ctx)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &cs, nil
+}
+
+// CheckConnectivity authenticates against every integrated system and checks
+// the permissions iceberg requires, all failures are reported together.
+func (cs *ClientSet) CheckConnectivity() error {
+	var errs []error
+	if cs.GitlabClient != nil {
+		if err := cs.GitlabClient.CheckPermissions(); err != nil {
+			errs = append(errs, err)
+		}
+		if parentGroup := cs.Config().GitlabOptions.ParentGroup; len(parentGroup) != 0 {
+			if err := cs.GitlabClient.CheckParentGroup(parentGroup); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if cs.GiteaClient != nil {
+		if err := cs.GiteaClient.CheckPermissions(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cs.HarborClient != nil {
+		if err := cs.HarborClient.CheckPermissions(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cs.DistributionClient != nil {
+		if err := cs.DistributionClient.CheckPermissions(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
 
-	cs.HarborClient = NewHarborClient(conf.HarborOptions, cs.Ctx)
+// Reload rebuilds the integration clients from a new config, generators keep
+// their references to the clients and see the new credentials. Switching the
+// scm or registry provider requires a restart.
+func (cs *ClientSet) Reload(conf *config.IntegrationConfig) error {
+	if cs.GitlabClient != nil {
+		if err := cs.GitlabClient.Reload(conf.GitlabOptions); err != nil {
+			return err
+		}
+	}
+	if cs.GiteaClient != nil {
+		if err := cs.GiteaClient.Reload(conf.GiteaOptions); err != nil {
+			return err
+		}
+	}
+	if cs.IntegrateClient != nil {
+		cs.IntegrateClient.Reload(conf.IntegrateOptions)
+	}
+	if cs.HarborClient != nil && conf.HarborOptions != nil {
+		cs.HarborClient.Reload(conf.HarborOptions)
+	}
+	if cs.DistributionClient != nil {
+		if err := cs.DistributionClient.Reload(conf.DistributionOptions); err != nil {
+			return err
+		}
+	}
+	old := cs.Config()
+	cs.config.Store(conf)
+
+	cs.lock.Lock()
+	listeners := cs.listeners
+	cs.lock.Unlock()
+	for _, listener := range listeners {
+		listener(old, conf)
+	}
+	return nil
+}
 
-	return &cs
+// OnReload registers listener to be called with the previous and the new
+// config after every reload.
+func (cs *ClientSet) OnReload(listener func(old, new *config.IntegrationConfig)) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	cs.listeners = append(cs.listeners, listener)
 }
@@ -0,0 +1,103 @@
+package clientset
+
+import (
+	"context"
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/apis/types/v1beta1"
+	"github.com/hchenc/iceberg/pkg/constants"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"sort"
+	"sync"
+)
+
+// MemberCluster is a cluster workspaces are placed in besides the host.
+type MemberCluster struct {
+	Name   string
+	Client dynamic.Interface
+}
+
+type memberClusterCache struct {
+	resourceVersion string
+	cluster         *MemberCluster
+}
+
+// MemberClusters builds the clients of member clusters from their kubeconfig
+// secrets, clients are rebuilt when a secret changes.
+type MemberClusters struct {
+	kubeClient kubernetes.Interface
+	namespace  string
+
+	lock  sync.Mutex
+	cache map[string]*memberClusterCache
+}
+
+// Select returns the member clusters of placement and the names of the
+// clusters it lists which have no kubeconfig secret.
+func (m *MemberClusters) Select(ctx context.Context, placement v1beta1.GenericPlacementFields) ([]*MemberCluster, []string, error) {
+	if len(placement.Clusters) == 0 && placement.ClusterSelector == nil {
+		return nil, nil, nil
+	}
+	selector := labels.Nothing()
+	if placement.ClusterSelector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(placement.ClusterSelector); err != nil {
+			return nil, nil, err
+		}
+	}
+	names := map[string]bool{}
+	for _, cluster := range placement.Clusters {
+		names[cluster.Name] = true
+	}
+
+	secrets, err := m.kubeClient.CoreV1().Secrets(m.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: constants.IcebergMemberCluster + "=true",
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var clusters []*MemberCluster
+	for index := range secrets.Items {
+		secret := &secrets.Items[index]
+		if !names[secret.Name] && !selector.Matches(labels.Set(secret.Labels)) {
+			continue
+		}
+		delete(names, secret.Name)
+		if cached := m.cache[secret.Name]; cached != nil && cached.resourceVersion == secret.ResourceVersion {
+			clusters = append(clusters, cached.cluster)
+			continue
+		}
+		config, err := clientcmd.RESTConfigFromKubeConfig(secret.Data[constants.MemberClusterKubeconfig])
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid kubeconfig of member cluster %s: %v", secret.Name, err)
+		}
+		client, err := dynamic.NewForConfig(config)
+		if err != nil {
+			return nil, nil, err
+		}
+		cluster := &MemberCluster{Name: secret.Name, Client: client}
+		m.cache[secret.Name] = &memberClusterCache{resourceVersion: secret.ResourceVersion, cluster: cluster}
+		clusters = append(clusters, cluster)
+	}
+
+	var missing []string
+	for name := range names {
+		missing = append(missing, name)
+	}
+	sort.Strings(missing)
+	return clusters, missing, nil
+}
+
+func NewMemberClusters(kubeClient kubernetes.Interface, namespace string) *MemberClusters {
+	return &MemberClusters{
+		kubeClient: kubeClient,
+		namespace:  namespace,
+		cache:      map[string]*memberClusterCache{},
+	}
+}
@@ -0,0 +1,93 @@
+package clientset
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/config"
+	"net/http"
+	"sync"
+)
+
+type distributionCredential struct {
+	baseURL  string
+	server   string
+	user     string
+	password string
+	client   *http.Client
+}
+
+// DistributionClient is a minimal client of the docker distribution api,
+// which zot implements as well.
+type DistributionClient struct {
+	lock       sync.RWMutex
+	credential *distributionCredential
+}
+
+// Server returns the registry host images are pulled from.
+func (d *DistributionClient) Server() string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.credential.server
+}
+
+// Credential returns the configured account of the registry.
+func (d *DistributionClient) Credential() (username, password string) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.credential.user, d.credential.password
+}
+
+// Do sends a request to the registry, path is relative to /v2.
+func (d *DistributionClient) Do(method, path string, in, out interface{}) error {
+	d.lock.RLock()
+	credential := d.credential
+	d.lock.RUnlock()
+
+	return doJSON(credential.client, config.RegistryProviderDistribution, method, credential.baseURL+"/v2"+path, func(req *http.Request) {
+		if len(credential.user) != 0 {
+			req.SetBasicAuth(credential.user, credential.password)
+		}
+	}, in, out)
+}
+
+// Reload rebuilds the distribution client with new options, e.g. rotated credentials.
+func (d *DistributionClient) Reload(distributionOptions *config.DistributionOptions) error {
+	distributionClient, err := NewDistributionClient(distributionOptions)
+	if err != nil {
+		return err
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.credential = distributionClient.credential
+	return nil
+}
+
+// CheckPermissions authenticates against the registry's version check
+// endpoint, the distribution api has no notion of administrators.
+func (d *DistributionClient) CheckPermissions() error {
+	if err := d.Do(http.MethodGet, "/", nil, nil); err != nil {
+		return fmt.Errorf("distribution: failed to authenticate: %v", err)
+	}
+	return nil
+}
+
+func NewDistributionClient(distributionOptions *config.DistributionOptions) (*DistributionClient, error) {
+	if distributionOptions == nil {
+		return nil, errors.New("distribution options not found")
+	}
+
+	httpClient, err := newHTTPClient(distributionOptions.CAFile, distributionOptions.InsecureSkipVerify, distributionOptions.Timeout, distributionOptions.Retries)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DistributionClient{
+		credential: &distributionCredential{
+			baseURL:  distributionOptions.BaseURL(),
+			server:   distributionOptions.Server(),
+			user:     distributionOptions.User,
+			password: distributionOptions.Password,
+			client:   httpClient,
+		},
+	}, nil
+}
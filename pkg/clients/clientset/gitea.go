@@ -0,0 +1,89 @@
+package clientset
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/config"
+	"net/http"
+	"sync"
+)
+
+type giteaCredential struct {
+	baseURL  string
+	token    string
+	user     string
+	password string
+	client   *http.Client
+}
+
+// GiteaClient is a minimal client of gitea's v1 rest api.
+type GiteaClient struct {
+	lock       sync.RWMutex
+	credential *giteaCredential
+}
+
+// Do sends a request to gitea's api, path is relative to /api/v1. in is
+// encoded as the json body when not nil and the response is decoded into out
+// when not nil.
+func (g *GiteaClient) Do(method, path string, in, out interface{}) error {
+	g.lock.RLock()
+	credential := g.credential
+	g.lock.RUnlock()
+
+	return doJSON(credential.client, config.SCMProviderGitea, method, credential.baseURL+"/api/v1"+path, func(req *http.Request) {
+		if len(credential.token) != 0 {
+			req.Header.Set("Authorization", "token "+credential.token)
+		} else {
+			req.SetBasicAuth(credential.user, credential.password)
+		}
+	}, in, out)
+}
+
+// Reload rebuilds the gitea client with new options, e.g. rotated credentials.
+func (g *GiteaClient) Reload(giteaOptions *config.GiteaOptions) error {
+	giteaClient, err := NewGiteaClient(giteaOptions)
+	if err != nil {
+		return err
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.credential = giteaClient.credential
+	return nil
+}
+
+// CheckPermissions authenticates against gitea and verifies the account is
+// an administrator, which is required to create users and organizations.
+func (g *GiteaClient) CheckPermissions() error {
+	user := struct {
+		Login   string `json:"login"`
+		IsAdmin bool   `json:"is_admin"`
+	}{}
+	if err := g.Do(http.MethodGet, "/user", nil, &user); err != nil {
+		return fmt.Errorf("gitea: failed to authenticate: %v", err)
+	}
+	if !user.IsAdmin {
+		return fmt.Errorf("gitea: user %s is not an administrator, which is required to create users and organizations", user.Login)
+	}
+	return nil
+}
+
+func NewGiteaClient(giteaOptions *config.GiteaOptions) (*GiteaClient, error) {
+	if giteaOptions == nil {
+		return nil, errors.New("gitea options not found")
+	}
+
+	httpClient, err := newHTTPClient(giteaOptions.CAFile, giteaOptions.InsecureSkipVerify, giteaOptions.Timeout, giteaOptions.Retries)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GiteaClient{
+		credential: &giteaCredential{
+			baseURL:  giteaOptions.BaseURL(),
+			token:    giteaOptions.Token,
+			user:     giteaOptions.User,
+			password: giteaOptions.Password,
+			client:   httpClient,
+		},
+	}, nil
+}
@@ -2,65 +2,91 @@ package clientset
 
 import (
 	"errors"
+	"fmt"
 	"github.com/hchenc/iceberg/pkg/config"
-	"github.com/hchenc/iceberg/pkg/constants"
 	"github.com/xanzy/go-gitlab"
-	"strings"
+	"sync"
 )
 
 type GitlabClient struct {
-	Client           *gitlab.Client
-	Username         string
-	Password         string
-	IntegrateOptions []*config.IntegrateOption
+	lock   sync.RWMutex
+	client *gitlab.Client
 }
 
-func convertAppType(appType string) string {
-	if len(appType) == 0 {
-		appType = constants.DefaultPipeline
-	} else {
-		for _, value := range []string{
-			"java",
-			"python",
-			"nodejs",
-			"go",
-		} {
-			if strings.Contains(appType, value) {
-				appType = value
-				break
-			}
-		}
+// Client returns the current gitlab client, it may be replaced by Reload
+// so callers should not keep it across reconciles.
+func (g *GitlabClient) Client() *gitlab.Client {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.client
+}
+
+// Reload rebuilds the gitlab client with new options, e.g. rotated credentials.
+func (g *GitlabClient) Reload(gitlabOptions *config.GitlabOptions) error {
+	gitlabClient, err := NewGitlabClient(gitlabOptions)
+	if err != nil {
+		return err
 	}
-	return appType
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.client = gitlabClient.client
+	return nil
 }
 
-func (g *GitlabClient) GetIntegrateOption(appType string) (*config.IntegrateOption, error) {
-	appType = convertAppType(appType)
+// CheckPermissions authenticates against gitlab and verifies the account is
+// an administrator, which is required to create users and top level groups.
+func (g *GitlabClient) CheckPermissions() error {
+	user, _, err := g.Client().Users.CurrentUser()
+	if err != nil {
+		return fmt.Errorf("gitlab: failed to authenticate: %v", err)
+	}
+	if !user.IsAdmin {
+		return fmt.Errorf("gitlab: user %s is not an administrator, which is required to create users and groups", user.Username)
+	}
+	return nil
+}
 
-	for _, integrateOption := range g.IntegrateOptions {
-		if integrateOption.Pipeline == appType {
-			return integrateOption, nil
-		}
+// CheckParentGroup verifies the group workspace groups are created in exists
+// and is visible to the account.
+func (g *GitlabClient) CheckParentGroup(parentGroup string) error {
+	if _, _, err := g.Client().Groups.GetGroup(parentGroup); err != nil {
+		return fmt.Errorf("gitlab: failed to get parent group %s: %v", parentGroup, err)
 	}
-	return nil, errors.New("pipeline not found")
+	return nil
 }
 
-func NewGitlabClient(gitlabOptions *config.GitlabOptions, integrateOptions []*config.IntegrateOption) *GitlabClient {
-	var gitlabClient GitlabClient
+func NewGitlabClient(gitlabOptions *config.GitlabOptions) (*GitlabClient, error) {
+	if gitlabOptions == nil {
+		return nil, errors.New("gitlab options not found")
+	}
 
-	gc, err := gitlab.NewBasicAuthClient(gitlabOptions.User,
-		gitlabOptions.Password,
-		gitlab.WithBaseURL("http://"+gitlabOptions.Host+":"+gitlabOptions.Port),
-		gitlab.WithoutRetries())
+	httpClient, err := newHTTPClient(gitlabOptions.CAFile, gitlabOptions.InsecureSkipVerify, gitlabOptions.Timeout, gitlabOptions.Retries)
 	if err != nil {
-		//panic(err)
-		return nil
+		return nil, err
+	}
+
+	clientOptions := []gitlab.ClientOptionFunc{
+		gitlab.WithBaseURL(gitlabOptions.BaseURL()),
+		gitlab.WithHTTPClient(httpClient),
+		gitlab.WithoutRetries(),
 	}
 
-	gitlabClient.Client = gc
-	gitlabClient.Username = gitlabOptions.User
-	gitlabClient.Password = gitlabOptions.Password
-	gitlabClient.IntegrateOptions = integrateOptions
+	var gc *gitlab.Client
+	switch gitlabOptions.GetAuthType() {
+	case config.GitlabBasicAuth:
+		gc, err = gitlab.NewBasicAuthClient(gitlabOptions.User, gitlabOptions.Password, clientOptions...)
+	case config.GitlabTokenAuth:
+		gc, err = gitlab.NewClient(gitlabOptions.Token, clientOptions...)
+	case config.GitlabOAuth:
+		gc, err = gitlab.NewOAuthClient(gitlabOptions.Token, clientOptions...)
+	default:
+		err = fmt.Errorf("unsupported gitlab auth type %s", gitlabOptions.AuthType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gitlab client: %v", err)
+	}
 
-	return &gitlabClient
+	return &GitlabClient{
+		client: gc,
+	}, nil
 }
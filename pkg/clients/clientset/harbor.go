@@ -2,28 +2,90 @@ package clientset
 
 import (
 	"context"
+	"fmt"
 	harbor2 "github.com/hchenc/go-harbor"
 	"github.com/hchenc/iceberg/pkg/config"
+	"net/http"
+	"strings"
+	"sync"
 )
 
 type HarborClient struct {
-	*harbor2.APIClient
+	lock    sync.RWMutex
+	ctx     context.Context
+	client  *harbor2.APIClient
+	options config.HarborOptions
+}
+
+// Client returns the current harbor client, it may be replaced by Reload
+// so callers should not keep it across reconciles.
+func (h *HarborClient) Client() *harbor2.APIClient {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return h.client
+}
+
+// Server returns the registry host images are pulled from.
+func (h *HarborClient) Server() string {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return h.options.Server()
+}
+
+// Do sends a request to harbor's api for endpoints the generated client can't
+// express, path is relative to the configured api address.
+func (h *HarborClient) Do(method, path string, in, out interface{}) error {
+	h.lock.RLock()
+	options := h.options
+	h.lock.RUnlock()
+
+	return doJSON(http.DefaultClient, config.RegistryProviderHarbor, method, strings.TrimSuffix(options.Host, "/")+path, func(req *http.Request) {
+		req.SetBasicAuth(options.User, options.Password)
+	}, in, out)
+}
+
+// Reload rebuilds the harbor client with new options, e.g. rotated credentials.
+func (h *HarborClient) Reload(harborOptions *config.HarborOptions) {
+	client := newHarborAPIClient(harborOptions, h.ctx)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.client = client
+	h.options = *harborOptions
+}
+
+// CheckPermissions authenticates against harbor and verifies the account is
+// a system administrator, which is required to manage projects and quotas.
+func (h *HarborClient) CheckPermissions() error {
+	user, _, err := h.Client().UserApi.GetCurrentUserInfo(&harbor2.UserApiGetCurrentUserInfoOpts{})
+	if err != nil {
+		return fmt.Errorf("harbor: failed to authenticate: %v", err)
+	}
+	if !user.SysadminFlag && !user.AdminRoleInAuth {
+		return fmt.Errorf("harbor: user %s is not a system administrator, which is required to manage projects", user.Username)
+	}
+	return nil
 }
 
 func NewHarborClient(harborOptions *config.HarborOptions, ctx context.Context) *HarborClient {
+	return &HarborClient{
+		ctx:     ctx,
+		client:  newHarborAPIClient(harborOptions, ctx),
+		options: *harborOptions,
+	}
+}
+
+func newHarborAPIClient(harborOptions *config.HarborOptions, ctx context.Context) *harbor2.APIClient {
 	basicAuth := harbor2.BasicAuth{
 		UserName: harborOptions.User,
 		Password: harborOptions.Password,
 	}
-	return &HarborClient{
-		harbor2.NewAPIClient(
-			harbor2.NewConfigurationWithContext(
-				harborOptions.Host,
-				context.WithValue(
-					ctx,
-					harbor2.ContextBasicAuth,
-					basicAuth),
-			),
+	return harbor2.NewAPIClient(
+		harbor2.NewConfigurationWithContext(
+			harborOptions.Host,
+			context.WithValue(
+				ctx,
+				harbor2.ContextBasicAuth,
+				basicAuth),
 		),
-	}
+	)
 }
@@ -0,0 +1,153 @@
+package clientset
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	certutil "k8s.io/client-go/util/cert"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const retryWaitPeriod = 200 * time.Millisecond
+
+// newHTTPClient builds the http client shared by scm clients, with a custom
+// CA bundle, an optional timeout and retries on transient failures.
+func newHTTPClient(caFile string, insecureSkipVerify bool, timeout time.Duration, retries int) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+	if len(caFile) != 0 {
+		pool, err := certutil.NewPool(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA bundle %s: %v", caFile, err)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Transport: &retryTransport{
+			next:    transport,
+			retries: retries,
+		},
+		Timeout: timeout,
+	}, nil
+}
+
+// retryTransport retries requests failed by connection errors or server side
+// errors, requests with a body are only retried when the body can be rewound.
+type retryTransport struct {
+	next    http.RoundTripper
+	retries int
+}
+
+func (r *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rewindable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	for attempt := 0; ; attempt++ {
+		// the request of the caller is never modified, retries send a clone
+		// with a fresh body
+		attemptReq := req
+		if attempt > 0 {
+			attemptReq = req.Clone(req.Context())
+			if req.Body != nil && req.Body != http.NoBody {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				attemptReq.Body = body
+			}
+		}
+		resp, err := r.next.RoundTrip(attemptReq)
+		if attempt >= r.retries || !rewindable || !shouldRetry(resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(time.Duration(attempt+1) * retryWaitPeriod):
+		}
+	}
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// APIError is returned by the json api clients when the server answers with a
+// non 2xx status.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %d %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// doJSON sends a json request, in is encoded as the body when not nil and the
+// response is decoded into out when not nil. authorize sets the credential.
+func doJSON(client *http.Client, provider, method, url string, authorize func(req *http.Request), in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if authorize != nil {
+		authorize(req)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message := struct {
+			Message string `json:"message"`
+		}{}
+		if json.Unmarshal(data, &message) != nil || len(message.Message) == 0 {
+			message.Message = strings.TrimSpace(string(data))
+		}
+		return &APIError{
+			Provider:   provider,
+			StatusCode: resp.StatusCode,
+			Message:    message.Message,
+		}
+	}
+	if out != nil && len(data) != 0 {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
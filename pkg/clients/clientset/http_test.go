@@ -0,0 +1,61 @@
+package clientset
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRetryTransport(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := newHTTPClient("", false, 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := req.Body
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(bodies) != 3 {
+		t.Errorf("expected success after 3 attempts, got %d after %d", resp.StatusCode, len(bodies))
+	}
+	for _, b := range bodies {
+		if b != "payload" {
+			t.Errorf("expected every attempt to send the body, got %q", b)
+		}
+	}
+	if req.Body != body {
+		t.Error("expected the request of the caller to be left untouched")
+	}
+
+	// bodies which can't be rewound are sent once
+	bodies = nil
+	req, _ = http.NewRequest(http.MethodPost, server.URL, ioutil.NopCloser(strings.NewReader("payload")))
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || len(bodies) != 1 {
+		t.Errorf("expected a single attempt, got %d after %d", resp.StatusCode, len(bodies))
+	}
+}
@@ -0,0 +1,59 @@
+package clientset
+
+import (
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"sync"
+)
+
+// PipelineNotFoundError is returned when no integrate option selects an
+// application and there's no default.
+type PipelineNotFoundError struct {
+	AppType   string
+	Workspace string
+}
+
+func (e *PipelineNotFoundError) Error() string {
+	return fmt.Sprintf("no pipeline selects application type %q in workspace %s and there's no default pipeline", e.AppType, e.Workspace)
+}
+
+// IsPipelineNotFound returns true if err is a PipelineNotFoundError.
+func IsPipelineNotFound(err error) bool {
+	_, ok := err.(*PipelineNotFoundError)
+	return ok
+}
+
+// IntegrateClient selects the pipeline an application's repository is
+// created with, it is shared by every scm provider.
+type IntegrateClient struct {
+	lock             sync.RWMutex
+	integrateOptions []*config.IntegrateOption
+}
+
+// GetIntegrateOption returns the integrate option of an application in
+// workspace with labels, see config.SelectIntegrateOption.
+func (i *IntegrateClient) GetIntegrateOption(workspace string, labels map[string]string) (*config.IntegrateOption, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	if integrateOption := config.SelectIntegrateOption(i.integrateOptions, workspace, labels); integrateOption != nil {
+		return integrateOption, nil
+	}
+	return nil, &PipelineNotFoundError{
+		AppType:   labels[constants.KubesphereAppType],
+		Workspace: workspace,
+	}
+}
+
+// Reload replaces the integrate options.
+func (i *IntegrateClient) Reload(integrateOptions []*config.IntegrateOption) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.integrateOptions = integrateOptions
+}
+
+func NewIntegrateClient(integrateOptions []*config.IntegrateOption) *IntegrateClient {
+	return &IntegrateClient{
+		integrateOptions: integrateOptions,
+	}
+}
@@ -6,14 +6,20 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"io/ioutil"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	certutil "k8s.io/client-go/util/cert"
 	"k8s.io/client-go/util/homedir"
 	"net"
+	"net/url"
 	"os"
 	"os/user"
 	"path"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
@@ -24,16 +30,123 @@ const (
 	defaultConfigurationPath = "/etc/iceberg"
 )
 
+const (
+	SCMProviderGitlab = "gitlab"
+	SCMProviderGitea  = "gitea"
+)
+
+const (
+	RegistryProviderHarbor = "harbor"
+	// RegistryProviderDistribution is a plain docker distribution registry or
+	// any registry implementing its api, e.g. zot
+	RegistryProviderDistribution = "distribution"
+	RegistryProviderZot          = "zot"
+)
+
+const (
+	// DefaultBootstrapCommitMessage is the message of the commit of rendered
+	// templates in new projects
+	DefaultBootstrapCommitMessage = "Bootstrap project from template"
+
+	// ProjectDeletionPolicyArchive archives the scm project of a deleted
+	// application, making it read-only
+	ProjectDeletionPolicyArchive = "Archive"
+	// ProjectDeletionPolicyRetain leaves the scm project of a deleted
+	// application untouched
+	ProjectDeletionPolicyRetain = "Retain"
+)
+
 type IntegrationConfig struct {
-	HarborOptions    *HarborOptions     `json:"harbor_options" yaml:"HarborOptions"`
-	GitlabOptions    *GitlabOptions     `json:"gitlab_options" yaml:"GitlabOptions"`
-	IntegrateOptions []*IntegrateOption `json:"integrate_options" yaml:"IntegrateOptions"`
+	// SCMProvider selects the source code management backend, gitlab or gitea,
+	// default to gitlab
+	// +optional
+	SCMProvider string `json:"scm_provider" yaml:"SCMProvider"`
+	// RegistryProvider selects the image registry backend, harbor, distribution
+	// or zot, default to harbor
+	// +optional
+	RegistryProvider    string               `json:"registry_provider" yaml:"RegistryProvider"`
+	HarborOptions       *HarborOptions       `json:"harbor_options" yaml:"HarborOptions"`
+	DistributionOptions *DistributionOptions `json:"distribution_options" yaml:"DistributionOptions"`
+	GitlabOptions       *GitlabOptions       `json:"gitlab_options" yaml:"GitlabOptions"`
+	GiteaOptions        *GiteaOptions        `json:"gitea_options" yaml:"GiteaOptions"`
+	IntegrateOptions    []*IntegrateOption   `json:"integrate_options" yaml:"IntegrateOptions"`
+	// IngressOptions configures how ingresses of the environments are patched
+	// +optional
+	IngressOptions *IngressOptions `json:"ingress_options" yaml:"IngressOptions"`
+	// EnvironmentOverrides patch the copies of resources propagated to the
+	// environments
+	// +optional
+	EnvironmentOverrides []*EnvironmentOverride `json:"environment_overrides" yaml:"EnvironmentOverrides"`
+	// NamespaceOptions are the quota, limits and network isolation of the
+	// environment namespaces
+	// +optional
+	NamespaceOptions *NamespaceOptions `json:"namespace_options" yaml:"NamespaceOptions"`
+	// RoleOptions map the workspace roles of members to the roles bound in
+	// the environment namespaces
+	// +optional
+	RoleOptions *RoleOptions `json:"role_options" yaml:"RoleOptions"`
+	// ProjectDeletionPolicy is what happens to the scm project of a deleted
+	// application, Archive or Retain, default to Archive
+	// +optional
+	ProjectDeletionPolicy string `json:"project_deletion_policy" yaml:"ProjectDeletionPolicy"`
+	// Controllers configure the controllers by name, e.g. the objects
+	// ServiceToEnv reconciles
+	// +optional
+	Controllers map[string]*ControllerOptions `json:"controllers" yaml:"Controllers"`
+}
+
+// GetSCMProvider returns the configured scm provider, default to gitlab.
+func (c *IntegrationConfig) GetSCMProvider() string {
+	return GetSCMProvider(c.SCMProvider)
+}
+
+func GetSCMProvider(provider string) string {
+	if len(provider) == 0 {
+		return SCMProviderGitlab
+	}
+	return strings.ToLower(provider)
+}
+
+// GetProjectDeletionPolicy returns the configured deletion policy of scm
+// projects, default to Archive.
+func (c *IntegrationConfig) GetProjectDeletionPolicy() string {
+	return GetProjectDeletionPolicy(c.ProjectDeletionPolicy)
+}
+
+func GetProjectDeletionPolicy(policy string) string {
+	switch {
+	case len(policy) == 0, strings.EqualFold(policy, ProjectDeletionPolicyArchive):
+		return ProjectDeletionPolicyArchive
+	case strings.EqualFold(policy, ProjectDeletionPolicyRetain):
+		return ProjectDeletionPolicyRetain
+	}
+	return policy
+}
+
+// GetRegistryProvider returns the configured registry provider, default to
+// harbor. zot is served by the distribution backend.
+func (c *IntegrationConfig) GetRegistryProvider() string {
+	return GetRegistryProvider(c.RegistryProvider)
+}
+
+func GetRegistryProvider(provider string) string {
+	switch provider = strings.ToLower(provider); provider {
+	case "":
+		return RegistryProviderHarbor
+	case RegistryProviderZot:
+		return RegistryProviderDistribution
+	}
+	return provider
 }
 
 type HarborOptions struct {
 	User     string `json:"user" yaml:"User"`
 	Password string `json:"password" yaml:"Password"`
 	Host     string `json:"host" yaml:"Host"`
+
+	// PasswordFrom reads Password from a secret, a file or an environment variable
+	// +optional
+	PasswordFrom *CredentialSource `json:"password_from" yaml:"PasswordFrom"`
 }
 
 type GitlabOptions struct {
@@ -43,6 +156,115 @@ type GitlabOptions struct {
 	User     string `json:"user" yaml:"User"`
 	Version  string `json:"version" yaml:"Version"`
 	Host     string `json:"host" yaml:"Host"`
+
+	// PasswordFrom reads Password from a secret, a file or an environment variable
+	// +optional
+	PasswordFrom *CredentialSource `json:"password_from" yaml:"PasswordFrom"`
+
+	// TokenFrom reads Token from a secret, a file or an environment variable
+	// +optional
+	TokenFrom *CredentialSource `json:"token_from" yaml:"TokenFrom"`
+
+	// Scheme is the protocol used to reach gitlab, http or https.
+	// +optional
+	Scheme string `json:"scheme" yaml:"Scheme"`
+
+	// AuthType selects the credential used to authenticate against gitlab,
+	// one of basic, token(personal access token) or oauth. If left blank,
+	// token is used when Token is set and basic otherwise.
+	// +optional
+	AuthType string `json:"auth_type" yaml:"AuthType"`
+
+	// CAFile is the path of a PEM encoded CA bundle used to verify gitlab's
+	// certificate, only used with https scheme.
+	// +optional
+	CAFile string `json:"ca_file" yaml:"CAFile"`
+
+	// InsecureSkipVerify disables the verification of gitlab's certificate.
+	// +optional
+	InsecureSkipVerify bool `json:"insecure_skip_verify" yaml:"InsecureSkipVerify"`
+
+	// Timeout of every request sent to gitlab, zero means no timeout.
+	// +optional
+	Timeout time.Duration `json:"timeout" yaml:"Timeout"`
+
+	// Retries is the number of times a failed request will be retried.
+	// +optional
+	Retries int `json:"retries" yaml:"Retries"`
+
+	// ParentGroup is the full path or ID of the group that workspace groups
+	// are created in as subgroups, top level groups are created if left blank.
+	// +optional
+	ParentGroup string `json:"parent_group" yaml:"ParentGroup"`
+
+	// SubGroups are created in every workspace group, e.g. one per
+	// environment or team.
+	// +optional
+	SubGroups []string `json:"sub_groups" yaml:"SubGroups"`
+}
+
+const (
+	GitlabBasicAuth = "basic"
+	GitlabTokenAuth = "token"
+	GitlabOAuth     = "oauth"
+)
+
+// GetAuthType returns the configured auth type, falling back to token when
+// a token is present and basic otherwise.
+func (g *GitlabOptions) GetAuthType() string {
+	if len(g.AuthType) != 0 {
+		return strings.ToLower(g.AuthType)
+	}
+	if len(g.Token) != 0 {
+		return GitlabTokenAuth
+	}
+	return GitlabBasicAuth
+}
+
+// BaseURL returns the gitlab address built from Scheme, Host and Port.
+func (g *GitlabOptions) BaseURL() string {
+	return baseURL(g.Scheme, g.Host, g.Port)
+}
+
+type GiteaOptions struct {
+	Scheme string `json:"scheme" yaml:"Scheme"`
+	Host   string `json:"host" yaml:"Host"`
+	Port   string `json:"port" yaml:"Port"`
+
+	// Token is an access token of an administrator, it has priority over
+	// User and Password.
+	Token string `json:"token" yaml:"Token"`
+	// +optional
+	TokenFrom *CredentialSource `json:"token_from" yaml:"TokenFrom"`
+
+	User     string `json:"user" yaml:"User"`
+	Password string `json:"password" yaml:"Password"`
+	// +optional
+	PasswordFrom *CredentialSource `json:"password_from" yaml:"PasswordFrom"`
+
+	// +optional
+	CAFile string `json:"ca_file" yaml:"CAFile"`
+	// +optional
+	InsecureSkipVerify bool `json:"insecure_skip_verify" yaml:"InsecureSkipVerify"`
+	// +optional
+	Timeout time.Duration `json:"timeout" yaml:"Timeout"`
+	// +optional
+	Retries int `json:"retries" yaml:"Retries"`
+}
+
+// BaseURL returns the gitea address built from Scheme, Host and Port.
+func (g *GiteaOptions) BaseURL() string {
+	return baseURL(g.Scheme, g.Host, g.Port)
+}
+
+func baseURL(scheme, host, port string) string {
+	if len(scheme) == 0 {
+		scheme = "http"
+	}
+	if len(port) != 0 {
+		host = net.JoinHostPort(host, port)
+	}
+	return scheme + "://" + host
 }
 
 type IntegrateOptions struct {
@@ -53,6 +275,61 @@ type IntegrateOption struct {
 	CiConfigPath string `json:"ci_config_path" yaml:"CiConfigPath"`
 	Pipeline     string `json:"pipeline" yaml:"Pipeline"`
 	Template     string `json:"template" yaml:"Template"`
+
+	// Bootstrap renders the files of a template directory or repository and
+	// commits them to the projects created for the pipeline
+	// +optional
+	Bootstrap *BootstrapOptions `json:"bootstrap" yaml:"Bootstrap"`
+
+	// Match selects the applications of the pipeline, without it the types
+	// containing the pipeline name are selected
+	// +optional
+	Match *PipelineMatch `json:"match" yaml:"Match"`
+
+	// Priority decides between pipelines selecting the same application,
+	// the highest wins
+	// +optional
+	Priority int `json:"priority" yaml:"Priority"`
+
+	// Default makes the pipeline the fallback of unselected applications,
+	// default to the pipeline named default
+	// +optional
+	Default bool `json:"default" yaml:"Default"`
+
+	// Workspaces limits the pipeline to these workspaces, where it takes
+	// precedence over the pipelines of every workspace
+	// +optional
+	Workspaces []string `json:"workspaces" yaml:"Workspaces"`
+}
+
+// BootstrapOptions locate the templates of the first commit of new projects.
+// Every file is a go template, a .tmpl suffix is dropped from its path.
+type BootstrapOptions struct {
+	// Dir is a local directory of templates, e.g. a mounted ConfigMap
+	// +optional
+	Dir string `json:"dir" yaml:"Dir"`
+
+	// Repository is the full path of a scm repository of templates
+	// +optional
+	Repository string `json:"repository" yaml:"Repository"`
+
+	// Ref is the branch, tag or commit of Repository, default to its default
+	// branch
+	// +optional
+	Ref string `json:"ref" yaml:"Ref"`
+
+	// CommitMessage is the message of the commit, default to
+	// DefaultBootstrapCommitMessage
+	// +optional
+	CommitMessage string `json:"commit_message" yaml:"CommitMessage"`
+}
+
+// GetCommitMessage returns the message of the bootstrap commit.
+func (b *BootstrapOptions) GetCommitMessage() string {
+	if len(b.CommitMessage) == 0 {
+		return DefaultBootstrapCommitMessage
+	}
+	return b.CommitMessage
 }
 
 type KubernetesOptions struct {
@@ -77,21 +354,413 @@ type KubernetesOptions struct {
 	Burst int `json:"burst,omitempty" yaml:"burst"`
 }
 
+// Server returns the registry host images are pulled from, derived from the
+// api address.
+func (h *HarborOptions) Server() string {
+	if u, err := url.Parse(h.Host); err == nil && len(u.Host) != 0 {
+		return u.Host
+	}
+	return h.Host
+}
+
+// DistributionOptions configures a registry implementing the docker
+// distribution api. Repositories are created on push, so namespaces are plain
+// path prefixes and every namespace is pulled with the same credential.
+type DistributionOptions struct {
+	Scheme string `json:"scheme" yaml:"Scheme"`
+	Host   string `json:"host" yaml:"Host"`
+	Port   string `json:"port" yaml:"Port"`
+
+	// User and Password are used to check the registry and written to the
+	// pull secrets of every environment, a read only account is recommended
+	User     string `json:"user" yaml:"User"`
+	Password string `json:"password" yaml:"Password"`
+
+	// PasswordFrom reads Password from a secret, a file or an environment variable
+	// +optional
+	PasswordFrom *CredentialSource `json:"password_from" yaml:"PasswordFrom"`
+
+	// +optional
+	CAFile string `json:"ca_file" yaml:"CAFile"`
+	// +optional
+	InsecureSkipVerify bool `json:"insecure_skip_verify" yaml:"InsecureSkipVerify"`
+	// +optional
+	Timeout time.Duration `json:"timeout" yaml:"Timeout"`
+	// +optional
+	Retries int `json:"retries" yaml:"Retries"`
+}
+
+// BaseURL returns the registry address built from Scheme, Host and Port.
+func (d *DistributionOptions) BaseURL() string {
+	return baseURL(d.Scheme, d.Host, d.Port)
+}
+
+// Server returns the registry host images are pulled from.
+func (d *DistributionOptions) Server() string {
+	if len(d.Port) != 0 {
+		return net.JoinHostPort(d.Host, d.Port)
+	}
+	return d.Host
+}
+
+func (d *DistributionOptions) Validate() []error {
+	var errs field.ErrorList
+	fldPath := field.NewPath("DistributionOptions")
+
+	errs = append(errs, validateServer(fldPath, d.Scheme, d.Host, d.Port)...)
+	if len(d.User) == 0 && (len(d.Password) != 0 || d.PasswordFrom != nil) {
+		errs = append(errs, field.Required(fldPath.Child("User"), "User must be set with Password"))
+	}
+	errs = append(errs, validateCredentialSource(d.PasswordFrom, fldPath.Child("PasswordFrom"))...)
+	errs = append(errs, validateTransport(fldPath, d.Scheme, d.CAFile, d.InsecureSkipVerify, d.Timeout, d.Retries)...)
+
+	return toErrors(errs)
+}
+
 func (h *HarborOptions) Validate() []error {
-	var errs []error
+	var errs field.ErrorList
+	fldPath := field.NewPath("HarborOptions")
 
-	return errs
+	if len(h.Host) == 0 {
+		errs = append(errs, field.Required(fldPath.Child("Host"), "harbor api address is required"))
+	} else if u, err := url.Parse(h.Host); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("Host"), h.Host, "must be an absolute http(s) url, e.g. https://harbor.example.com/api/v2.0"))
+	}
+	if len(h.User) == 0 {
+		errs = append(errs, field.Required(fldPath.Child("User"), ""))
+	}
+	if len(h.Password) == 0 && h.PasswordFrom == nil {
+		errs = append(errs, field.Required(fldPath.Child("Password"), "either Password or PasswordFrom must be set"))
+	}
+	errs = append(errs, validateCredentialSource(h.PasswordFrom, fldPath.Child("PasswordFrom"))...)
+
+	return toErrors(errs)
 }
 
 func (g *GitlabOptions) Validate() []error {
-	var errs []error
+	var errs field.ErrorList
+	fldPath := field.NewPath("GitlabOptions")
+
+	errs = append(errs, validateServer(fldPath, g.Scheme, g.Host, g.Port)...)
+
+	hasPassword := len(g.Password) != 0 || g.PasswordFrom != nil
+	hasToken := len(g.Token) != 0 || g.TokenFrom != nil
+	switch authType := g.GetAuthType(); authType {
+	case GitlabBasicAuth:
+		if len(g.User) == 0 {
+			errs = append(errs, field.Required(fldPath.Child("User"), "required by basic auth"))
+		}
+		if !hasPassword {
+			errs = append(errs, field.Required(fldPath.Child("Password"), "either Password or PasswordFrom is required by basic auth"))
+		}
+		if hasToken {
+			errs = append(errs, field.Forbidden(fldPath.Child("Token"), "basic auth and token auth are mutually exclusive"))
+		}
+	case GitlabTokenAuth, GitlabOAuth:
+		if !hasToken {
+			errs = append(errs, field.Required(fldPath.Child("Token"), fmt.Sprintf("either Token or TokenFrom is required by %s auth", authType)))
+		}
+		if hasPassword {
+			errs = append(errs, field.Forbidden(fldPath.Child("Password"), fmt.Sprintf("basic auth and %s auth are mutually exclusive", authType)))
+		}
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("AuthType"), g.AuthType, []string{GitlabBasicAuth, GitlabTokenAuth, GitlabOAuth}))
+	}
+	errs = append(errs, validateCredentialSource(g.PasswordFrom, fldPath.Child("PasswordFrom"))...)
+	errs = append(errs, validateCredentialSource(g.TokenFrom, fldPath.Child("TokenFrom"))...)
+
+	errs = append(errs, validateTransport(fldPath, g.Scheme, g.CAFile, g.InsecureSkipVerify, g.Timeout, g.Retries)...)
+
+	if strings.HasPrefix(g.ParentGroup, "/") || strings.HasSuffix(g.ParentGroup, "/") {
+		errs = append(errs, field.Invalid(fldPath.Child("ParentGroup"), g.ParentGroup, "must be a full path without leading or trailing slash, or an ID"))
+	}
+	subGroups := map[string]bool{}
+	for index, subGroup := range g.SubGroups {
+		subGroupPath := fldPath.Child("SubGroups").Index(index)
+		if len(subGroup) == 0 || strings.Contains(subGroup, "/") {
+			errs = append(errs, field.Invalid(subGroupPath, subGroup, "must be a non-empty group path without slash"))
+		} else if subGroups[subGroup] {
+			errs = append(errs, field.Duplicate(subGroupPath, subGroup))
+		}
+		subGroups[subGroup] = true
+	}
+
+	return toErrors(errs)
+}
+
+func (g *GiteaOptions) Validate() []error {
+	var errs field.ErrorList
+	fldPath := field.NewPath("GiteaOptions")
+
+	errs = append(errs, validateServer(fldPath, g.Scheme, g.Host, g.Port)...)
+
+	hasToken := len(g.Token) != 0 || g.TokenFrom != nil
+	hasPassword := len(g.Password) != 0 || g.PasswordFrom != nil
+	if !hasToken {
+		if len(g.User) == 0 {
+			errs = append(errs, field.Required(fldPath.Child("User"), "either Token or User and Password must be set"))
+		}
+		if !hasPassword {
+			errs = append(errs, field.Required(fldPath.Child("Password"), "either Token or User and Password must be set"))
+		}
+	} else if hasPassword {
+		errs = append(errs, field.Forbidden(fldPath.Child("Password"), "token auth and basic auth are mutually exclusive"))
+	}
+	errs = append(errs, validateCredentialSource(g.PasswordFrom, fldPath.Child("PasswordFrom"))...)
+	errs = append(errs, validateCredentialSource(g.TokenFrom, fldPath.Child("TokenFrom"))...)
+
+	errs = append(errs, validateTransport(fldPath, g.Scheme, g.CAFile, g.InsecureSkipVerify, g.Timeout, g.Retries)...)
+
+	return toErrors(errs)
+}
+
+// validateServer validates an address given as separated scheme, host and port.
+func validateServer(fldPath *field.Path, scheme, host, port string) field.ErrorList {
+	var errs field.ErrorList
+
+	if len(host) == 0 {
+		errs = append(errs, field.Required(fldPath.Child("Host"), ""))
+	} else if strings.Contains(host, "/") || strings.Contains(host, ":") {
+		errs = append(errs, field.Invalid(fldPath.Child("Host"), host, "must be a bare host name, set scheme and port with Scheme and Port"))
+	} else if msgs := validation.IsDNS1123Subdomain(host); len(msgs) != 0 && net.ParseIP(host) == nil {
+		errs = append(errs, field.Invalid(fldPath.Child("Host"), host, strings.Join(msgs, ", ")))
+	}
+	if len(port) != 0 {
+		if portNum, err := strconv.Atoi(port); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("Port"), port, "must be a number"))
+		} else {
+			for _, msg := range validation.IsValidPortNum(portNum) {
+				errs = append(errs, field.Invalid(fldPath.Child("Port"), port, msg))
+			}
+		}
+	}
+	if len(scheme) != 0 && scheme != "http" && scheme != "https" {
+		errs = append(errs, field.NotSupported(fldPath.Child("Scheme"), scheme, []string{"http", "https"}))
+	}
+
+	return errs
+}
+
+// validateTransport validates the tls, timeout and retry settings of a client.
+func validateTransport(fldPath *field.Path, scheme, caFile string, insecureSkipVerify bool, timeout time.Duration, retries int) field.ErrorList {
+	var errs field.ErrorList
+
+	if len(caFile) != 0 {
+		if scheme != "https" {
+			errs = append(errs, field.Forbidden(fldPath.Child("CAFile"), "only used with https scheme"))
+		}
+		if insecureSkipVerify {
+			errs = append(errs, field.Forbidden(fldPath.Child("InsecureSkipVerify"), "CAFile and InsecureSkipVerify are mutually exclusive"))
+		}
+		if _, err := os.Stat(caFile); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("CAFile"), caFile, err.Error()))
+		}
+	}
+	if timeout < 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("Timeout"), timeout.String(), "must not be negative"))
+	}
+	if retries < 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("Retries"), retries, "must not be negative"))
+	}
 
 	return errs
 }
 
 func (i *IntegrateOption) Validate() []error {
+	return toErrors(i.validate(field.NewPath("IntegrateOptions").Key(i.Pipeline)))
+}
+
+func (i *IntegrateOption) validate(fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	if len(i.Pipeline) == 0 {
+		errs = append(errs, field.Required(fldPath.Child("Pipeline"), ""))
+	}
+	if len(i.CiConfigPath) == 0 {
+		errs = append(errs, field.Required(fldPath.Child("CiConfigPath"), ""))
+	} else if msg := validateCiConfigPath(i.CiConfigPath); len(msg) != 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("CiConfigPath"), i.CiConfigPath, msg))
+	}
+	if i.Match != nil {
+		errs = append(errs, i.Match.validate(fldPath.Child("Match"))...)
+	}
+	if bootstrap := i.Bootstrap; bootstrap != nil {
+		bootstrapPath := fldPath.Child("Bootstrap")
+		switch {
+		case len(bootstrap.Dir) == 0 && len(bootstrap.Repository) == 0:
+			errs = append(errs, field.Required(bootstrapPath, "either Dir or Repository is required"))
+		case len(bootstrap.Dir) != 0 && len(bootstrap.Repository) != 0:
+			errs = append(errs, field.Forbidden(bootstrapPath, "Dir and Repository are mutually exclusive"))
+		case len(bootstrap.Dir) != 0 && !path.IsAbs(bootstrap.Dir):
+			errs = append(errs, field.Invalid(bootstrapPath.Child("Dir"), bootstrap.Dir, "must be an absolute path"))
+		}
+		if len(bootstrap.Ref) != 0 && len(bootstrap.Repository) == 0 {
+			errs = append(errs, field.Forbidden(bootstrapPath.Child("Ref"), "only applies to Repository"))
+		}
+	}
+
+	return errs
+}
+
+// ValidateIntegrateOptions validates every integrate option and the set as a
+// whole, pipelines must be unique and at most one default is shared or
+// limited to a workspace.
+func ValidateIntegrateOptions(integrateOptions []*IntegrateOption) []error {
+	var errs field.ErrorList
+	fldPath := field.NewPath("IntegrateOptions")
+
+	pipelines := map[string]bool{}
+	for index, integrateOption := range integrateOptions {
+		if integrateOption == nil {
+			errs = append(errs, field.Required(fldPath.Index(index), ""))
+			continue
+		}
+		errs = append(errs, integrateOption.validate(fldPath.Index(index))...)
+		// a workspace override may reuse the name of a shared pipeline
+		for _, scope := range integrateOption.scopes() {
+			key := scope + "/" + integrateOption.Pipeline
+			if pipelines[key] {
+				errs = append(errs, field.Duplicate(fldPath.Index(index).Child("Pipeline"), integrateOption.Pipeline))
+				break
+			}
+			pipelines[key] = true
+		}
+	}
+	errs = append(errs, validateDefaults(fldPath, integrateOptions)...)
+
+	return toErrors(errs)
+}
+
+// validateCiConfigPath accepts a yaml file in the repository, a yaml file in
+// another project(path@group/project) or a remote http(s) yaml file.
+func validateCiConfigPath(ciConfigPath string) string {
+	if !strings.HasSuffix(strings.SplitN(ciConfigPath, "@", 2)[0], ".yml") &&
+		!strings.HasSuffix(strings.SplitN(ciConfigPath, "@", 2)[0], ".yaml") {
+		return "must point to a .yml or .yaml file"
+	}
+	if strings.HasPrefix(ciConfigPath, "http://") || strings.HasPrefix(ciConfigPath, "https://") {
+		if u, err := url.Parse(ciConfigPath); err != nil || len(u.Host) == 0 {
+			return "must be a valid url"
+		}
+		return ""
+	}
+	filePath := ciConfigPath
+	if parts := strings.SplitN(ciConfigPath, "@", 2); len(parts) == 2 {
+		filePath = parts[0]
+		if project := parts[1]; !strings.Contains(project, "/") || strings.HasPrefix(project, "/") || strings.HasSuffix(project, "/") {
+			return "project after @ must be a full path like group/project"
+		}
+	}
+	if strings.HasPrefix(filePath, "/") {
+		return "repository path must be relative"
+	}
+	for _, segment := range strings.Split(filePath, "/") {
+		if segment == ".." {
+			return "repository path must not contain .."
+		}
+	}
+	return ""
+}
+
+func validateCredentialSource(source *CredentialSource, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if source == nil {
+		return errs
+	}
+	if source.SecretRef == nil && len(source.File) == 0 && len(source.Env) == 0 {
+		errs = append(errs, field.Required(fldPath, "one of SecretRef, File or Env must be set"))
+	}
+	if ref := source.SecretRef; ref != nil {
+		if len(ref.Name) == 0 {
+			errs = append(errs, field.Required(fldPath.Child("SecretRef", "Name"), ""))
+		}
+		if len(ref.Key) == 0 {
+			errs = append(errs, field.Required(fldPath.Child("SecretRef", "Key"), ""))
+		}
+	}
+	return errs
+}
+
+// Validate validates the options of the integrations in use and of the
+// controllers, it runs at startup and on every reload.
+func (c *IntegrationConfig) Validate(integrations Integrations) []error {
 	var errs []error
+	errs = append(errs, c.validateSCM(integrations)...)
+	errs = append(errs, c.validateRegistry(integrations)...)
+	if c.IngressOptions != nil {
+		errs = append(errs, c.IngressOptions.Validate()...)
+	}
+	errs = append(errs, ValidateEnvironmentOverrides(c.EnvironmentOverrides)...)
+	if c.NamespaceOptions != nil {
+		errs = append(errs, c.NamespaceOptions.Validate()...)
+	}
+	if c.RoleOptions != nil {
+		errs = append(errs, c.RoleOptions.Validate()...)
+	}
+	switch policy := GetProjectDeletionPolicy(c.ProjectDeletionPolicy); policy {
+	case ProjectDeletionPolicyArchive, ProjectDeletionPolicyRetain:
+	default:
+		errs = append(errs, fmt.Errorf("ProjectDeletionPolicy: Unsupported value: %q: supported values: %q, %q", policy, ProjectDeletionPolicyArchive, ProjectDeletionPolicyRetain))
+	}
+	errs = append(errs, ValidateControllers(c.Controllers)...)
+	return errs
+}
 
+func (c *IntegrationConfig) validateSCM(integrations Integrations) []error {
+	if !integrations.SCM {
+		return nil
+	}
+	var errs []error
+	switch provider := GetSCMProvider(c.SCMProvider); provider {
+	case SCMProviderGitlab:
+		if c.GitlabOptions != nil {
+			errs = append(errs, c.GitlabOptions.Validate()...)
+		} else {
+			errs = append(errs, errors.New("GitlabOptions: Required value: gitlab options are not configured"))
+		}
+	case SCMProviderGitea:
+		if c.GiteaOptions != nil {
+			errs = append(errs, c.GiteaOptions.Validate()...)
+		} else {
+			errs = append(errs, errors.New("GiteaOptions: Required value: gitea options are not configured"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("SCMProvider: Unsupported value: %q: supported values: %q, %q", provider, SCMProviderGitlab, SCMProviderGitea))
+	}
+	// pipelines are only selected for the projects of the scm
+	errs = append(errs, ValidateIntegrateOptions(c.IntegrateOptions)...)
+	return errs
+}
+
+func (c *IntegrationConfig) validateRegistry(integrations Integrations) []error {
+	if !integrations.Registry {
+		return nil
+	}
+	var errs []error
+	switch provider := GetRegistryProvider(c.RegistryProvider); provider {
+	case RegistryProviderHarbor:
+		if c.HarborOptions != nil {
+			errs = append(errs, c.HarborOptions.Validate()...)
+		} else {
+			errs = append(errs, errors.New("HarborOptions: Required value: harbor options are not configured"))
+		}
+	case RegistryProviderDistribution:
+		if c.DistributionOptions != nil {
+			errs = append(errs, c.DistributionOptions.Validate()...)
+		} else {
+			errs = append(errs, errors.New("DistributionOptions: Required value: distribution options are not configured"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("RegistryProvider: Unsupported value: %q: supported values: %q, %q, %q", provider, RegistryProviderHarbor, RegistryProviderDistribution, RegistryProviderZot))
+	}
+	return errs
+}
+
+func toErrors(errList field.ErrorList) []error {
+	var errs []error
+	for _, err := range errList {
+		errs = append(errs, err)
+	}
 	return errs
 }
 
@@ -170,7 +839,9 @@ func (k *KubernetesOptions) AddFlags(fs *pflag.FlagSet, c *KubernetesOptions) {
 }
 
 // TryLoadFromDisk loads configuration from default location after server startup
-// return nil error if configuration file not exists
+// return nil error if configuration file not exists. Credentials read from
+// files or environment variables are resolved here, credentials kept in
+// secrets are resolved by ResolveCredentials once a kubernetes client exists.
 func TryLoadFromDisk() (*IntegrationConfig, error) {
 	viper.SetConfigName(defaultConfigurationName)
 	viper.AddConfigPath(defaultConfigurationPath)
@@ -186,6 +857,19 @@ func TryLoadFromDisk() (*IntegrationConfig, error) {
 		}
 	}
 
+	conf, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conf.ResolveCredentials(nil); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+func load() (*IntegrationConfig, error) {
 	conf := &IntegrationConfig{}
 
 	if err := viper.Unmarshal(conf); err != nil {
@@ -193,5 +877,4 @@ func TryLoadFromDisk() (*IntegrationConfig, error) {
 	}
 
 	return conf, nil
-
 }
@@ -2,6 +2,12 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"os"
+	"path"
 	"testing"
 )
 
@@ -9,3 +15,354 @@ func TestNewKubernetesConfig(t *testing.T) {
 	config := NewKubernetesConfig()
 	fmt.Println(config)
 }
+
+func TestGitlabOptions(t *testing.T) {
+	options := &GitlabOptions{
+		Host: "gitlab.hchenc.com",
+		Port: "80",
+	}
+	if url := options.BaseURL(); url != "http://gitlab.hchenc.com:80" {
+		t.Errorf("expected http://gitlab.hchenc.com:80, got %s", url)
+	}
+	if authType := options.GetAuthType(); authType != GitlabBasicAuth {
+		t.Errorf("expected %s, got %s", GitlabBasicAuth, authType)
+	}
+
+	options = &GitlabOptions{
+		Host:   "gitlab.hchenc.com",
+		Scheme: "https",
+		Token:  "token",
+	}
+	if url := options.BaseURL(); url != "https://gitlab.hchenc.com" {
+		t.Errorf("expected https://gitlab.hchenc.com, got %s", url)
+	}
+	if authType := options.GetAuthType(); authType != GitlabTokenAuth {
+		t.Errorf("expected %s, got %s", GitlabTokenAuth, authType)
+	}
+
+	options.AuthType = "OAuth"
+	if authType := options.GetAuthType(); authType != GitlabOAuth {
+		t.Errorf("expected %s, got %s", GitlabOAuth, authType)
+	}
+}
+
+func TestResolveCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "iceberg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tokenFile := path.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("file-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("ICEBERG_TEST_HARBOR_PASSWORD", "env-password")
+	defer os.Unsetenv("ICEBERG_TEST_HARBOR_PASSWORD")
+
+	conf := &IntegrationConfig{
+		HarborOptions: &HarborOptions{
+			Password:     "plain",
+			PasswordFrom: &CredentialSource{Env: "ICEBERG_TEST_HARBOR_PASSWORD"},
+		},
+		GitlabOptions: &GitlabOptions{
+			TokenFrom: &CredentialSource{File: tokenFile},
+			PasswordFrom: &CredentialSource{SecretRef: &SecretKeyRef{
+				Name: "gitlab",
+				Key:  "password",
+			}},
+		},
+	}
+
+	if err := conf.ResolveCredentials(nil); err != nil {
+		t.Fatal(err)
+	}
+	if conf.HarborOptions.Password != "env-password" {
+		t.Errorf("expected env-password, got %s", conf.HarborOptions.Password)
+	}
+	if conf.GitlabOptions.Token != "file-token" {
+		t.Errorf("expected file-token, got %s", conf.GitlabOptions.Token)
+	}
+	if conf.GitlabOptions.Password != "" {
+		t.Errorf("expected secret to be skipped without client, got %s", conf.GitlabOptions.Password)
+	}
+
+	client := fake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "gitlab",
+			Namespace: "devops-system",
+		},
+		Data: map[string][]byte{
+			"password": []byte("secret-password"),
+		},
+	})
+	if err := conf.ResolveCredentials(client); err != nil {
+		t.Fatal(err)
+	}
+	if conf.GitlabOptions.Password != "secret-password" {
+		t.Errorf("expected secret-password, got %s", conf.GitlabOptions.Password)
+	}
+
+	conf.GitlabOptions.PasswordFrom.SecretRef.Key = "missing"
+	if err := conf.ResolveCredentials(client); err == nil {
+		t.Error("expected error for missing secret key")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	gitlabOptions := &GitlabOptions{
+		Host:     "gitlab.hchenc.com",
+		Port:     "80",
+		User:     "root",
+		Password: "password",
+	}
+	if errs := gitlabOptions.Validate(); len(errs) != 0 {
+		t.Errorf("expected no error, got %v", errs)
+	}
+
+	gitlabOptions = &GitlabOptions{
+		Host:     "http://gitlab.hchenc.com",
+		Port:     "abc",
+		User:     "root",
+		Password: "password",
+		Token:    "token",
+		AuthType: "token",
+	}
+	if errs := gitlabOptions.Validate(); len(errs) != 3 {
+		t.Errorf("expected 3 errors, got %v", errs)
+	}
+
+	gitlabOptions = &GitlabOptions{
+		Host:        "gitlab.hchenc.com",
+		Token:       "token",
+		ParentGroup: "/platform/",
+		SubGroups:   []string{"fat", "fat", "team/a"},
+	}
+	if errs := gitlabOptions.Validate(); len(errs) != 3 {
+		t.Errorf("expected 3 errors, got %v", errs)
+	}
+
+	giteaOptions := &GiteaOptions{
+		Host: "gitea.hchenc.com",
+		User: "root",
+	}
+	if errs := giteaOptions.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, got %v", errs)
+	}
+
+	ingressOptions := &IngressOptions{
+		DomainPattern: "{{.Service}}.{{.Environment}}.example.com",
+		Classes: []*IngressClassOptions{
+			{Name: "haproxy", VhostAnnotation: "haproxy-ingress.github.io/upstream-vhost"},
+			{Name: "haproxy", VhostAnnotation: "invalid annotation"},
+		},
+	}
+	if errs := ingressOptions.Validate(); len(errs) != 3 {
+		t.Errorf("expected 3 errors, got %v", errs)
+	}
+
+	harborOptions := &HarborOptions{
+		Host: "harbor.hchenc.com",
+		User: "admin",
+	}
+	if errs := harborOptions.Validate(); len(errs) != 2 {
+		t.Errorf("expected 2 errors, got %v", errs)
+	}
+
+	namespaceOptions := &NamespaceOptions{
+		Envs:         []string{"dev", "prod", "dev", "Prod"},
+		Environments: []*NamespaceEnvironmentOptions{{Env: "dev"}, {Env: "fat"}},
+	}
+	if errs := namespaceOptions.Validate(); len(errs) != 3 {
+		t.Errorf("expected 3 errors, got %v", errs)
+	}
+	if envs := (*NamespaceOptions)(nil).GetEnvs(); len(envs) != 3 || envs[0] != "fat" {
+		t.Errorf("expected the default environments, got %v", envs)
+	}
+
+	integrateOptions := []*IntegrateOption{
+		{Pipeline: "java", CiConfigPath: "http://gitlab.hchenc.com/devops/devops/-/raw/main/java.yaml"},
+		{Pipeline: "java", CiConfigPath: ".gitlab-ci.yml@devops/templates"},
+		{Pipeline: "python", CiConfigPath: "../python.yml"},
+		{Pipeline: "java", CiConfigPath: ".gitlab-ci.yml", Workspaces: []string{"devops"}},
+		{Pipeline: "go", CiConfigPath: ".gitlab-ci.yml", Default: true},
+		{Pipeline: "nodejs", CiConfigPath: ".gitlab-ci.yml", Default: true, Match: &PipelineMatch{TypePatterns: []string{"node("}}},
+	}
+	if errs := ValidateIntegrateOptions(integrateOptions); len(errs) != 4 {
+		t.Errorf("expected 4 errors, got %v", errs)
+	}
+
+	integrateOptions = []*IntegrateOption{
+		{Pipeline: "default", CiConfigPath: ".gitlab-ci.yml", Bootstrap: &BootstrapOptions{Dir: "/etc/iceberg/templates/default"}},
+		{Pipeline: "java", CiConfigPath: ".gitlab-ci.yml", Bootstrap: &BootstrapOptions{Repository: "devops/java-template", Ref: "v1"}},
+		{Pipeline: "go", CiConfigPath: ".gitlab-ci.yml", Bootstrap: &BootstrapOptions{Dir: "templates/go", Ref: "main"}},
+		{Pipeline: "node", CiConfigPath: ".gitlab-ci.yml", Bootstrap: &BootstrapOptions{}},
+	}
+	if errs := ValidateIntegrateOptions(integrateOptions); len(errs) != 3 {
+		t.Errorf("expected 3 errors, got %v", errs)
+	}
+
+	environmentOverrides := []*EnvironmentOverride{
+		{Env: "uat", JSONPatch: []*PatchOperation{{Path: "/spec/replicas", Value: 1}}},
+		{JSONPatch: []*PatchOperation{{Op: "move", Path: "spec/replicas"}}},
+		{Env: "sit", Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "-"}}},
+	}
+	if errs := ValidateEnvironmentOverrides(environmentOverrides); len(errs) != 5 {
+		t.Errorf("expected 5 errors, got %v", errs)
+	}
+
+	roleOptions := &RoleOptions{
+		Templates: []*RoleTemplate{
+			{Name: "edit", Rules: []*PolicyRule{{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}}}},
+			{Name: "edit", Rules: []*PolicyRule{{Resources: []string{"pods"}}}},
+		},
+		Environments: []*RoleEnvironmentOptions{
+			{Env: "uat", Roles: []*RoleMapping{{WorkspaceRole: "regular", Kind: "Group", Name: "view"}}},
+			{Env: "uat", Default: &RoleRef{Kind: "ClusterRole"}},
+		},
+	}
+	if errs := roleOptions.Validate(); len(errs) != 5 {
+		t.Errorf("expected 5 errors, got %v", errs)
+	}
+
+	for _, ciConfigPath := range []string{".gitlab-ci.yml", "ci/build.yaml", ".gitlab-ci.yml@devops/templates", "https://gitlab.hchenc.com/ci.yml"} {
+		if msg := validateCiConfigPath(ciConfigPath); len(msg) != 0 {
+			t.Errorf("expected %s to be valid, got %s", ciConfigPath, msg)
+		}
+	}
+	for _, ciConfigPath := range []string{"/.gitlab-ci.yml", "Dockerfile", ".gitlab-ci.yml@templates"} {
+		if msg := validateCiConfigPath(ciConfigPath); len(msg) == 0 {
+			t.Errorf("expected %s to be invalid", ciConfigPath)
+		}
+	}
+}
+
+func TestSelectIntegrateOption(t *testing.T) {
+	integrateOptions := []*IntegrateOption{
+		{Pipeline: "default", CiConfigPath: ".gitlab-ci.yml"},
+		{Pipeline: "java", CiConfigPath: "java.yml"},
+		{Pipeline: "spring", CiConfigPath: "spring.yml", Priority: 10, Match: &PipelineMatch{
+			TypePatterns: []string{"^java-(web|batch)$"},
+			Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"framework": "spring"}},
+		}},
+		{Pipeline: "java", CiConfigPath: "java-legacy.yml", Workspaces: []string{"legacy"}},
+		{Pipeline: "golang", CiConfigPath: "go.yml", Match: &PipelineMatch{Types: []string{"Go"}}},
+	}
+	for _, c := range []struct {
+		workspace string
+		labels    map[string]string
+		expected  string
+	}{
+		{"devops", map[string]string{"app.kubernetes.io/type": "java-web"}, "java.yml"},
+		{"devops", map[string]string{"app.kubernetes.io/type": "java-web", "framework": "spring"}, "spring.yml"},
+		{"legacy", map[string]string{"app.kubernetes.io/type": "java-web", "framework": "spring"}, "java-legacy.yml"},
+		{"devops", map[string]string{"app.kubernetes.io/type": "go"}, "go.yml"},
+		{"devops", map[string]string{"app.kubernetes.io/type": "rust"}, ".gitlab-ci.yml"},
+		{"devops", nil, ".gitlab-ci.yml"},
+	} {
+		selected := SelectIntegrateOption(integrateOptions, c.workspace, c.labels)
+		if selected == nil || selected.CiConfigPath != c.expected {
+			t.Errorf("expected %s for %v in %s, got %+v", c.expected, c.labels, c.workspace, selected)
+		}
+	}
+
+	integrateOptions[0].Pipeline = "blank"
+	if selected := SelectIntegrateOption(integrateOptions, "devops", map[string]string{"app.kubernetes.io/type": "rust"}); selected != nil {
+		t.Errorf("expected no pipeline without a default, got %+v", selected)
+	}
+	integrateOptions = append(integrateOptions, &IntegrateOption{Pipeline: "legacy", CiConfigPath: "legacy.yml", Default: true, Workspaces: []string{"legacy"}})
+	if selected := SelectIntegrateOption(integrateOptions, "legacy", map[string]string{"app.kubernetes.io/type": "rust"}); selected == nil || selected.Pipeline != "legacy" {
+		t.Errorf("expected the default of the workspace, got %+v", selected)
+	}
+}
+
+func TestMatchName(t *testing.T) {
+	for _, c := range []struct {
+		patterns []string
+		name     string
+		expected bool
+	}{
+		{[]string{"fat"}, "fat", true},
+		{[]string{"fat"}, "fatal-ops", false},
+		{[]string{"*-fat"}, "sales-fat", true},
+		{[]string{"kube-*"}, "kubeflow-team", false},
+		{[]string{"/^team-(a|b)$/"}, "team-b", true},
+		{[]string{"/^team-(a|b)$/"}, "team-c", false},
+		{nil, "any", false},
+	} {
+		if matched := MatchName(c.patterns, c.name); matched != c.expected {
+			t.Errorf("%v %s: expected %v, got %v", c.patterns, c.name, c.expected, matched)
+		}
+	}
+
+	errs := ValidateControllers(map[string]*ControllerOptions{
+		"ServiceToEnv": {Filter: &FilterOptions{
+			IncludeNamespaces: []string{"/team-(/", "[a-"},
+			Selector:          &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "tier", Operator: "Is"}}},
+		}},
+		"UserToUser":  {Filter: &FilterOptions{ExcludeNames: []string{"admin", ""}}},
+		"JobToEnv":    nil,
+		"SecretToEnv": {MaxConcurrentReconciles: -1},
+	})
+	if len(errs) != 5 {
+		t.Errorf("expected 5 errors, got %v", errs)
+	}
+}
+
+func TestGetFilter(t *testing.T) {
+	filter := &FilterOptions{ExcludeNames: []string{"admin"}}
+	defaults := &FilterOptions{}
+	conf := &IntegrationConfig{Controllers: map[string]*ControllerOptions{
+		"usertouser":   {Filter: filter},
+		"ServiceToEnv": {},
+	}}
+	if got := conf.GetFilter("UserToUser", defaults); got != filter {
+		t.Errorf("expected the filter of usertouser, got %v", got)
+	}
+	if got := conf.GetFilter("ServiceToEnv", defaults); got != defaults {
+		t.Errorf("expected the defaults without filter, got %v", got)
+	}
+	if got := conf.GetFilter("JobToEnv", nil); got != nil {
+		t.Errorf("expected no filter, got %v", got)
+	}
+}
+
+func TestControllerEnabled(t *testing.T) {
+	disabled := false
+	conf := &IntegrationConfig{Controllers: map[string]*ControllerOptions{
+		"patchingress": {Enabled: &disabled},
+	}}
+	for _, c := range []struct {
+		controller  string
+		controllers []string
+		expected    bool
+	}{
+		{"ServiceToEnv", nil, true},
+		{"ServiceToEnv", []string{"*"}, true},
+		{"PatchIngress", []string{"*"}, false},
+		{"PatchIngress", []string{"*", "PatchIngress"}, true},
+		{"ServiceToEnv", []string{"*", "-servicetoenv"}, false},
+		{"ServiceToEnv", []string{"UserToUser"}, false},
+		{"UserToUser", []string{"UserToUser"}, true},
+	} {
+		if enabled := conf.ControllerEnabled(c.controller, c.controllers); enabled != c.expected {
+			t.Errorf("%s %v: expected %v, got %v", c.controller, c.controllers, c.expected, enabled)
+		}
+	}
+}
+
+func TestValidateIntegrations(t *testing.T) {
+	conf := &IntegrationConfig{
+		GitlabOptions:    &GitlabOptions{Host: "gitlab.hchenc.com", Token: "token"},
+		IntegrateOptions: []*IntegrateOption{{Pipeline: "java", CiConfigPath: "Dockerfile"}},
+	}
+	if errs := conf.Validate(Integrations{}); len(errs) != 0 {
+		t.Errorf("expected the options of unused integrations to be ignored, got %v", errs)
+	}
+	if errs := conf.Validate(Integrations{SCM: true}); len(errs) != 1 {
+		t.Errorf("expected 1 error, got %v", errs)
+	}
+	if errs := conf.Validate(Integrations{Registry: true}); len(errs) != 1 {
+		t.Errorf("expected 1 error for the missing harbor options, got %v", errs)
+	}
+}
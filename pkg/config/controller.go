@@ -0,0 +1,108 @@
+package config
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sort"
+	"strings"
+)
+
+// ControllerOptions configure a controller, keyed by its name in
+// Controllers, e.g. ServiceToEnv.
+type ControllerOptions struct {
+	// Enabled turns the controller off when false, the --controllers flag
+	// takes precedence. Changes apply after a restart
+	// +optional
+	Enabled *bool `json:"enabled" yaml:"Enabled"`
+	// MaxConcurrentReconciles is the number of objects reconciled at the
+	// same time, 1 when not set. Changes apply after a restart
+	// +optional
+	MaxConcurrentReconciles int `json:"max_concurrent_reconciles" yaml:"MaxConcurrentReconciles"`
+	// Filter selects the objects the controller reconciles, replacing the
+	// built-in filter of the controller
+	// +optional
+	Filter *FilterOptions `json:"filter" yaml:"Filter"`
+}
+
+// Integrations are the external systems the enabled controllers talk to,
+// the clients of the others aren't built and their options aren't required.
+type Integrations struct {
+	// SCM is the provider selected by SCMProvider
+	SCM bool
+	// Registry is the provider selected by RegistryProvider
+	Registry bool
+	// Federation is the member clusters workspaces are placed in
+	Federation bool
+}
+
+// Merge returns the integrations used by either i or other.
+func (i Integrations) Merge(other Integrations) Integrations {
+	return Integrations{
+		SCM:        i.SCM || other.SCM,
+		Registry:   i.Registry || other.Registry,
+		Federation: i.Federation || other.Federation,
+	}
+}
+
+// GetController returns the options of the controller, nil when it isn't
+// configured. Names are matched ignoring case, the loader lowercases keys.
+func (c *IntegrationConfig) GetController(controller string) *ControllerOptions {
+	if options, exists := c.Controllers[controller]; exists {
+		return options
+	}
+	for name, options := range c.Controllers {
+		if strings.EqualFold(name, controller) {
+			return options
+		}
+	}
+	return nil
+}
+
+// ControllerEnabled reports whether the controller is enabled by
+// controllers, a list in the style of kube-controller-manager: foo enables
+// foo, -foo disables foo and * enables the controllers which aren't turned
+// off by their Enabled option. An empty list is *.
+func (c *IntegrationConfig) ControllerEnabled(controller string, controllers []string) bool {
+	star := len(controllers) == 0
+	for _, name := range controllers {
+		switch {
+		case name == "*":
+			star = true
+		case strings.EqualFold(name, controller):
+			return true
+		case strings.EqualFold(name, "-"+controller):
+			return false
+		}
+	}
+	if !star {
+		return false
+	}
+	if options := c.GetController(controller); options != nil && options.Enabled != nil {
+		return *options.Enabled
+	}
+	return true
+}
+
+// ValidateControllers validates the options of the configured controllers.
+func ValidateControllers(controllers map[string]*ControllerOptions) []error {
+	var errs field.ErrorList
+	fldPath := field.NewPath("Controllers")
+
+	names := make([]string, 0, len(controllers))
+	for name := range controllers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		options := controllers[name]
+		if options == nil {
+			continue
+		}
+		if options.MaxConcurrentReconciles < 0 {
+			errs = append(errs, field.Invalid(fldPath.Key(name).Child("MaxConcurrentReconciles"), options.MaxConcurrentReconciles, "must be greater than or equal to 0"))
+		}
+		if options.Filter != nil {
+			errs = append(errs, options.Filter.validate(fldPath.Key(name).Child("Filter"))...)
+		}
+	}
+	return toErrors(errs)
+}
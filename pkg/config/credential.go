@@ -0,0 +1,141 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"io/ioutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"os"
+	"strings"
+)
+
+// CredentialSource references a credential kept outside of the configuration
+// file. When more than one source is set, SecretRef wins over File and File
+// wins over Env.
+type CredentialSource struct {
+	// Env is the name of an environment variable holding the credential
+	// +optional
+	Env string `json:"env" yaml:"Env"`
+
+	// File is the path of a mounted file holding the credential
+	// +optional
+	File string `json:"file" yaml:"File"`
+
+	// SecretRef references a key of a kubernetes secret holding the credential
+	// +optional
+	SecretRef *SecretKeyRef `json:"secret_ref" yaml:"SecretRef"`
+}
+
+type SecretKeyRef struct {
+	// Namespace of the secret, default to devops-system
+	// +optional
+	Namespace string `json:"namespace" yaml:"Namespace"`
+	Name      string `json:"name" yaml:"Name"`
+	Key       string `json:"key" yaml:"Key"`
+}
+
+func (s *SecretKeyRef) GetNamespace() string {
+	if len(s.Namespace) == 0 {
+		return constants.DevopsNamespace
+	}
+	return s.Namespace
+}
+
+// Resolve reads the credential from its source. Secrets are only read when a
+// kubernetes client is given, otherwise resolved is false.
+func (c *CredentialSource) Resolve(client kubernetes.Interface) (value string, resolved bool, err error) {
+	switch {
+	case c.SecretRef != nil:
+		if client == nil {
+			return "", false, nil
+		}
+		secret, err := client.CoreV1().Secrets(c.SecretRef.GetNamespace()).Get(context.Background(), c.SecretRef.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read secret %s/%s: %v", c.SecretRef.GetNamespace(), c.SecretRef.Name, err)
+		}
+		data, exists := secret.Data[c.SecretRef.Key]
+		if !exists {
+			return "", false, fmt.Errorf("key %s not found in secret %s/%s", c.SecretRef.Key, c.SecretRef.GetNamespace(), c.SecretRef.Name)
+		}
+		return string(data), true, nil
+	case len(c.File) != 0:
+		data, err := ioutil.ReadFile(c.File)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read credential file %s: %v", c.File, err)
+		}
+		return strings.TrimSpace(string(data)), true, nil
+	case len(c.Env) != 0:
+		value, exists := os.LookupEnv(c.Env)
+		if !exists {
+			return "", false, fmt.Errorf("environment variable %s not set", c.Env)
+		}
+		return value, true, nil
+	}
+	return "", false, nil
+}
+
+// ResolveCredentials replaces every credential which has a source with the
+// value read from that source. Secret sources are skipped when client is nil.
+func (c *IntegrationConfig) ResolveCredentials(client kubernetes.Interface) error {
+	var errs []string
+
+	resolve := func(target *string, source *CredentialSource) {
+		if source == nil {
+			return
+		}
+		value, resolved, err := source.Resolve(client)
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else if resolved {
+			*target = value
+		}
+	}
+
+	if c.HarborOptions != nil {
+		resolve(&c.HarborOptions.Password, c.HarborOptions.PasswordFrom)
+	}
+	if c.DistributionOptions != nil {
+		resolve(&c.DistributionOptions.Password, c.DistributionOptions.PasswordFrom)
+	}
+	if c.GitlabOptions != nil {
+		resolve(&c.GitlabOptions.Password, c.GitlabOptions.PasswordFrom)
+		resolve(&c.GitlabOptions.Token, c.GitlabOptions.TokenFrom)
+	}
+	if c.GiteaOptions != nil {
+		resolve(&c.GiteaOptions.Password, c.GiteaOptions.PasswordFrom)
+		resolve(&c.GiteaOptions.Token, c.GiteaOptions.TokenFrom)
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("failed to resolve credentials: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// credentialSources returns every credential source referenced by the config.
+func (c *IntegrationConfig) credentialSources() []*CredentialSource {
+	var sources []*CredentialSource
+	if c.HarborOptions != nil && c.HarborOptions.PasswordFrom != nil {
+		sources = append(sources, c.HarborOptions.PasswordFrom)
+	}
+	if c.DistributionOptions != nil && c.DistributionOptions.PasswordFrom != nil {
+		sources = append(sources, c.DistributionOptions.PasswordFrom)
+	}
+	if c.GitlabOptions != nil {
+		for _, source := range []*CredentialSource{c.GitlabOptions.PasswordFrom, c.GitlabOptions.TokenFrom} {
+			if source != nil {
+				sources = append(sources, source)
+			}
+		}
+	}
+	if c.GiteaOptions != nil {
+		for _, source := range []*CredentialSource{c.GiteaOptions.PasswordFrom, c.GiteaOptions.TokenFrom} {
+			if source != nil {
+				sources = append(sources, source)
+			}
+		}
+	}
+	return sources
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// FilterOptions select the objects of a controller, an object must pass
+// every rule set. Names and namespaces are matched against exact names,
+// globs like *-fat or regular expressions enclosed in slashes like
+// /^team-(a|b)$/. Cluster scoped objects never match namespace rules.
+type FilterOptions struct {
+	// IncludeNamespaces limit the controller to the matching namespaces
+	// +optional
+	IncludeNamespaces []string `json:"include_namespaces" yaml:"IncludeNamespaces"`
+	// ExcludeNamespaces drop the objects of the matching namespaces
+	// +optional
+	ExcludeNamespaces []string `json:"exclude_namespaces" yaml:"ExcludeNamespaces"`
+	// NamespaceSelector matches the labels of the namespace of objects
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespace_selector" yaml:"NamespaceSelector"`
+	// IncludeNames limit the controller to the objects with matching names
+	// +optional
+	IncludeNames []string `json:"include_names" yaml:"IncludeNames"`
+	// ExcludeNames drop the objects with matching names
+	// +optional
+	ExcludeNames []string `json:"exclude_names" yaml:"ExcludeNames"`
+	// Selector matches the labels of objects
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector" yaml:"Selector"`
+}
+
+// GetFilter returns the configured filter of the controller, defaults when
+// none is configured.
+func (c *IntegrationConfig) GetFilter(controller string, defaults *FilterOptions) *FilterOptions {
+	if options := c.GetController(controller); options != nil && options.Filter != nil {
+		return options.Filter
+	}
+	return defaults
+}
+
+// Matches reports whether obj is selected, namespaceLabels returns the
+// labels of a namespace and is only called for a NamespaceSelector.
+func (f *FilterOptions) Matches(obj metav1.Object, namespaceLabels func(namespace string) (map[string]string, error)) bool {
+	namespace, name := obj.GetNamespace(), obj.GetName()
+	if len(f.IncludeNamespaces) != 0 && (len(namespace) == 0 || !MatchName(f.IncludeNamespaces, namespace)) {
+		return false
+	}
+	if len(namespace) != 0 && MatchName(f.ExcludeNamespaces, namespace) {
+		return false
+	}
+	if len(f.IncludeNames) != 0 && !MatchName(f.IncludeNames, name) {
+		return false
+	}
+	if MatchName(f.ExcludeNames, name) {
+		return false
+	}
+	if f.Selector != nil && !matchSelector(f.Selector, obj.GetLabels()) {
+		return false
+	}
+	if f.NamespaceSelector == nil {
+		return true
+	}
+	if len(namespace) == 0 || namespaceLabels == nil {
+		return false
+	}
+	namespaceLabelSet, err := namespaceLabels(namespace)
+	if err != nil {
+		return false
+	}
+	return matchSelector(f.NamespaceSelector, namespaceLabelSet)
+}
+
+// MatchName reports whether name matches one of patterns, exact names, globs
+// or regular expressions enclosed in slashes.
+func MatchName(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		var matched bool
+		switch {
+		case isRegexpPattern(pattern):
+			matched, _ = regexp.MatchString(pattern[1:len(pattern)-1], name)
+		case isGlobPattern(pattern):
+			matched, _ = path.Match(pattern, name)
+		default:
+			matched = pattern == name
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func isRegexpPattern(pattern string) bool {
+	return len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/")
+}
+
+func isGlobPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+func matchSelector(labelSelector *metav1.LabelSelector, set map[string]string) bool {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(set))
+}
+
+func (f *FilterOptions) validate(fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	errs = append(errs, validatePatterns(fldPath.Child("IncludeNamespaces"), f.IncludeNamespaces)...)
+	errs = append(errs, validatePatterns(fldPath.Child("ExcludeNamespaces"), f.ExcludeNamespaces)...)
+	errs = append(errs, validatePatterns(fldPath.Child("IncludeNames"), f.IncludeNames)...)
+	errs = append(errs, validatePatterns(fldPath.Child("ExcludeNames"), f.ExcludeNames)...)
+	errs = append(errs, validateSelector(fldPath.Child("NamespaceSelector"), f.NamespaceSelector)...)
+	errs = append(errs, validateSelector(fldPath.Child("Selector"), f.Selector)...)
+	return errs
+}
+
+func validateSelector(fldPath *field.Path, selector *metav1.LabelSelector) field.ErrorList {
+	if selector == nil {
+		return nil
+	}
+	if _, err := metav1.LabelSelectorAsSelector(selector); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, selector, err.Error())}
+	}
+	return nil
+}
+
+func validatePatterns(fldPath *field.Path, patterns []string) field.ErrorList {
+	var errs field.ErrorList
+	for index, pattern := range patterns {
+		switch {
+		case len(pattern) == 0:
+			errs = append(errs, field.Required(fldPath.Index(index), ""))
+		case isRegexpPattern(pattern):
+			if _, err := regexp.Compile(pattern[1 : len(pattern)-1]); err != nil {
+				errs = append(errs, field.Invalid(fldPath.Index(index), pattern, err.Error()))
+			}
+		case isGlobPattern(pattern):
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = append(errs, field.Invalid(fldPath.Index(index), pattern, err.Error()))
+			}
+		}
+	}
+	return errs
+}
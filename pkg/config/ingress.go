@@ -0,0 +1,261 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"strings"
+	"text/template"
+)
+
+const (
+	// DefaultIngressClass is used for ingresses without class
+	DefaultIngressClass = "nginx"
+
+	defaultVhostAnnotation = "nginx.ingress.kubernetes.io/upstream-vhost"
+	defaultVhostFormat     = "{{.Service}}.{{.Namespace}}.svc.cluster.local"
+)
+
+type IngressOptions struct {
+	// DomainPattern renders the host of rules without host, e.g.
+	// {{.Service}}.{{.Env}}.example.com. Available fields are Name, Service,
+	// Namespace, Workspace and Env
+	// +optional
+	DomainPattern string `json:"domain_pattern" yaml:"DomainPattern"`
+
+	// DefaultClass is the class of ingresses without class, default to nginx
+	// +optional
+	DefaultClass string `json:"default_class" yaml:"DefaultClass"`
+
+	// Classes overrides the upstream vhost annotation per ingress class
+	// +optional
+	Classes []*IngressClassOptions `json:"classes" yaml:"Classes"`
+
+	// Environments rewrites hosts and tls secret names of ingresses copied
+	// into another environment
+	// +optional
+	Environments []*IngressEnvironmentOptions `json:"environments" yaml:"Environments"`
+}
+
+type IngressEnvironmentOptions struct {
+	// Env is the target environment, e.g. fat, uat or sit
+	Env string `json:"env" yaml:"Env"`
+
+	// HostTemplate renders the hosts in Env, available fields are Host,
+	// Subdomain(first label of Host), Domain(the rest), Name, Workspace,
+	// Namespace, SourceEnv and Env. By default the label of the source
+	// environment is replaced, app.fat.example.com becomes app.uat.example.com,
+	// and other hosts are prefixed, app.example.com becomes uat-app.example.com
+	// +optional
+	HostTemplate string `json:"host_template" yaml:"HostTemplate"`
+
+	// TLSSecretTemplate renders the tls secret names in Env, available fields
+	// are SecretName, Name, Workspace, Namespace, SourceEnv and Env. The name
+	// is kept by default
+	// +optional
+	TLSSecretTemplate string `json:"tls_secret_template" yaml:"TLSSecretTemplate"`
+}
+
+type IngressClassOptions struct {
+	Name string `json:"name" yaml:"Name"`
+
+	// VhostAnnotation is the annotation setting the upstream host header,
+	// e.g. haproxy-ingress.github.io/upstream-vhost. Leave it empty for
+	// classes without such an annotation
+	VhostAnnotation string `json:"vhost_annotation" yaml:"VhostAnnotation"`
+
+	// VhostFormat renders the annotation value, available fields are Service
+	// and Namespace, default to {{.Service}}.{{.Namespace}}.svc.cluster.local
+	// +optional
+	VhostFormat string `json:"vhost_format" yaml:"VhostFormat"`
+}
+
+// IngressHost holds the fields available to DomainPattern.
+type IngressHost struct {
+	Name      string
+	Service   string
+	Namespace string
+	Workspace string
+	Env       string
+}
+
+// IngressVhost holds the fields available to VhostFormat.
+type IngressVhost struct {
+	Service   string
+	Namespace string
+}
+
+// IngressCopy holds the fields available to HostTemplate and TLSSecretTemplate.
+type IngressCopy struct {
+	Host       string
+	Subdomain  string
+	Domain     string
+	SecretName string
+	Name       string
+	Workspace  string
+	Namespace  string
+	SourceEnv  string
+	Env        string
+}
+
+// GetEnvironment returns the options of an environment, nil when not configured.
+func (i *IngressOptions) GetEnvironment(env string) *IngressEnvironmentOptions {
+	if i == nil {
+		return nil
+	}
+	for _, environment := range i.Environments {
+		if environment != nil && environment.Env == env {
+			return environment
+		}
+	}
+	return nil
+}
+
+// RewriteHost renders the host of a copied ingress rule or tls entry.
+func (i *IngressEnvironmentOptions) RewriteHost(data IngressCopy) (string, error) {
+	if i != nil && len(i.HostTemplate) != 0 {
+		labels := strings.SplitN(data.Host, ".", 2)
+		data.Subdomain = labels[0]
+		if len(labels) == 2 {
+			data.Domain = labels[1]
+		}
+		return render("HostTemplate", i.HostTemplate, data)
+	}
+	if len(data.Host) == 0 {
+		return "", nil
+	}
+	labels := strings.Split(data.Host, ".")
+	for index, label := range labels {
+		if len(data.SourceEnv) != 0 && label == data.SourceEnv {
+			labels[index] = data.Env
+			return strings.Join(labels, "."), nil
+		}
+	}
+	if strings.HasPrefix(data.Host, "*.") {
+		return "*." + data.Env + "-" + strings.TrimPrefix(data.Host, "*."), nil
+	}
+	return data.Env + "-" + data.Host, nil
+}
+
+// RewriteTLSSecret renders the tls secret name of a copied ingress.
+func (i *IngressEnvironmentOptions) RewriteTLSSecret(data IngressCopy) (string, error) {
+	if i == nil || len(i.TLSSecretTemplate) == 0 || len(data.SecretName) == 0 {
+		return data.SecretName, nil
+	}
+	return render("TLSSecretTemplate", i.TLSSecretTemplate, data)
+}
+
+// GetClass returns the options of an ingress class, unknown classes and
+// classes without overrides use the nginx annotation.
+func (i *IngressOptions) GetClass(name string) *IngressClassOptions {
+	if i != nil {
+		if len(name) == 0 {
+			name = i.DefaultClass
+		}
+		for _, class := range i.Classes {
+			if class != nil && class.Name == name {
+				return class
+			}
+		}
+	}
+	return &IngressClassOptions{
+		Name:            name,
+		VhostAnnotation: defaultVhostAnnotation,
+		VhostFormat:     defaultVhostFormat,
+	}
+}
+
+// Host renders the host of a rule, it's empty when no DomainPattern is set.
+func (i *IngressOptions) Host(host IngressHost) (string, error) {
+	if i == nil || len(i.DomainPattern) == 0 {
+		return "", nil
+	}
+	return render("DomainPattern", i.DomainPattern, host)
+}
+
+// Vhost renders the upstream vhost annotation value of a service.
+func (i *IngressClassOptions) Vhost(vhost IngressVhost) (string, error) {
+	format := i.VhostFormat
+	if len(format) == 0 {
+		format = defaultVhostFormat
+	}
+	return render("VhostFormat", format, vhost)
+}
+
+func (i *IngressOptions) Validate() []error {
+	var errs field.ErrorList
+	fldPath := field.NewPath("IngressOptions")
+
+	if len(i.DomainPattern) != 0 {
+		if host, err := render("DomainPattern", i.DomainPattern, IngressHost{
+			Name: "app", Service: "app", Namespace: "devops-fat", Workspace: "devops", Env: "fat",
+		}); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("DomainPattern"), i.DomainPattern, err.Error()))
+		} else if msgs := validation.IsDNS1123Subdomain(strings.TrimPrefix(host, "*.")); len(msgs) != 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("DomainPattern"), i.DomainPattern, strings.Join(msgs, ", ")))
+		}
+	}
+
+	names := map[string]bool{}
+	for index, class := range i.Classes {
+		classPath := fldPath.Child("Classes").Index(index)
+		if class == nil {
+			errs = append(errs, field.Required(classPath, ""))
+			continue
+		}
+		if len(class.Name) == 0 {
+			errs = append(errs, field.Required(classPath.Child("Name"), ""))
+		} else if names[class.Name] {
+			errs = append(errs, field.Duplicate(classPath.Child("Name"), class.Name))
+		}
+		names[class.Name] = true
+		if len(class.VhostAnnotation) != 0 {
+			for _, msg := range validation.IsQualifiedName(class.VhostAnnotation) {
+				errs = append(errs, field.Invalid(classPath.Child("VhostAnnotation"), class.VhostAnnotation, msg))
+			}
+		}
+		if _, err := class.Vhost(IngressVhost{Service: "app", Namespace: "devops-fat"}); err != nil {
+			errs = append(errs, field.Invalid(classPath.Child("VhostFormat"), class.VhostFormat, err.Error()))
+		}
+	}
+
+	envs := map[string]bool{}
+	for index, environment := range i.Environments {
+		envPath := fldPath.Child("Environments").Index(index)
+		if environment == nil {
+			errs = append(errs, field.Required(envPath, ""))
+			continue
+		}
+		if len(environment.Env) == 0 {
+			errs = append(errs, field.Required(envPath.Child("Env"), ""))
+		} else if envs[environment.Env] {
+			errs = append(errs, field.Duplicate(envPath.Child("Env"), environment.Env))
+		}
+		envs[environment.Env] = true
+		sample := IngressCopy{
+			Host: "app.fat.example.com", SecretName: "app-tls", Name: "app",
+			Workspace: "devops", Namespace: "devops-uat", SourceEnv: "fat", Env: "uat",
+		}
+		if _, err := environment.RewriteHost(sample); err != nil {
+			errs = append(errs, field.Invalid(envPath.Child("HostTemplate"), environment.HostTemplate, err.Error()))
+		}
+		if _, err := environment.RewriteTLSSecret(sample); err != nil {
+			errs = append(errs, field.Invalid(envPath.Child("TLSSecretTemplate"), environment.TLSSecretTemplate, err.Error()))
+		}
+	}
+
+	return toErrors(errs)
+}
+
+func render(name, text string, data interface{}) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s: %v", name, err)
+	}
+	return buf.String(), nil
+}
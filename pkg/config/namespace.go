@@ -0,0 +1,161 @@
+package config
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// DefaultEnvs are the environments of workspaces when none are configured.
+var DefaultEnvs = []string{"fat", "uat", "sit"}
+
+type NamespaceOptions struct {
+	// Envs are the environments of workspaces, a namespace <workspace>-<env>
+	// labeled with its environment is created for each. Defaults to
+	// DefaultEnvs
+	// +optional
+	Envs []string `json:"envs" yaml:"Envs"`
+
+	// Environments are the templates of the ResourceQuota and LimitRange
+	// of the environment namespaces
+	// +optional
+	Environments []*NamespaceEnvironmentOptions `json:"environments" yaml:"Environments"`
+
+	// AllowedNamespaces are the labels of namespaces allowed to reach the
+	// environments of workspaces with network isolation, besides those of
+	// the same workspace, e.g. the namespace of the ingress controller
+	// +optional
+	AllowedNamespaces []map[string]string `json:"allowed_namespaces" yaml:"AllowedNamespaces"`
+}
+
+type NamespaceEnvironmentOptions struct {
+	// Env is the environment, e.g. fat, uat or sit
+	Env string `json:"env" yaml:"Env"`
+
+	// Quota is the hard limits of the ResourceQuota, e.g. requests.cpu: "4"
+	// +optional
+	Quota map[string]string `json:"quota" yaml:"Quota"`
+
+	// LimitRange is the container limits of the LimitRange
+	// +optional
+	LimitRange *LimitRangeOptions `json:"limit_range" yaml:"LimitRange"`
+}
+
+// LimitRangeOptions are the container limits of a LimitRange by resource
+// name, e.g. memory: 512Mi.
+type LimitRangeOptions struct {
+	Default        map[string]string `json:"default" yaml:"Default"`
+	DefaultRequest map[string]string `json:"default_request" yaml:"DefaultRequest"`
+	Max            map[string]string `json:"max" yaml:"Max"`
+	Min            map[string]string `json:"min" yaml:"Min"`
+}
+
+// GetEnvs returns the environments of workspaces.
+func (n *NamespaceOptions) GetEnvs() []string {
+	if n == nil || len(n.Envs) == 0 {
+		return DefaultEnvs
+	}
+	return n.Envs
+}
+
+// GetEnvironment returns the template of env, nil when there's none.
+func (n *NamespaceOptions) GetEnvironment(env string) *NamespaceEnvironmentOptions {
+	if n == nil {
+		return nil
+	}
+	for _, environment := range n.Environments {
+		if environment != nil && environment.Env == env {
+			return environment
+		}
+	}
+	return nil
+}
+
+// ResourceList parses the quantities of resources.
+func ResourceList(resources map[string]string) (corev1.ResourceList, error) {
+	list := corev1.ResourceList{}
+	for name, value := range resources {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, err
+		}
+		list[corev1.ResourceName(name)] = quantity
+	}
+	return list, nil
+}
+
+func (n *NamespaceOptions) Validate() []error {
+	var errs field.ErrorList
+	fldPath := field.NewPath("NamespaceOptions")
+
+	configured := map[string]bool{}
+	for index, env := range n.Envs {
+		envPath := fldPath.Child("Envs").Index(index)
+		for _, msg := range validation.IsDNS1123Label(env) {
+			errs = append(errs, field.Invalid(envPath, env, msg))
+		}
+		if configured[env] {
+			errs = append(errs, field.Duplicate(envPath, env))
+		}
+		configured[env] = true
+	}
+
+	envs := map[string]bool{}
+	for index, environment := range n.Environments {
+		envPath := fldPath.Child("Environments").Index(index)
+		if environment == nil {
+			errs = append(errs, field.Required(envPath, ""))
+			continue
+		}
+		if len(environment.Env) == 0 {
+			errs = append(errs, field.Required(envPath.Child("Env"), ""))
+		} else if envs[environment.Env] {
+			errs = append(errs, field.Duplicate(envPath.Child("Env"), environment.Env))
+		} else if !containsEnv(n.GetEnvs(), environment.Env) {
+			errs = append(errs, field.NotSupported(envPath.Child("Env"), environment.Env, n.GetEnvs()))
+		}
+		envs[environment.Env] = true
+
+		errs = append(errs, validateResources(envPath.Child("Quota"), environment.Quota)...)
+		if limitRange := environment.LimitRange; limitRange != nil {
+			limitPath := envPath.Child("LimitRange")
+			errs = append(errs, validateResources(limitPath.Child("Default"), limitRange.Default)...)
+			errs = append(errs, validateResources(limitPath.Child("DefaultRequest"), limitRange.DefaultRequest)...)
+			errs = append(errs, validateResources(limitPath.Child("Max"), limitRange.Max)...)
+			errs = append(errs, validateResources(limitPath.Child("Min"), limitRange.Min)...)
+		}
+	}
+
+	for index, labels := range n.AllowedNamespaces {
+		for key, value := range labels {
+			labelPath := fldPath.Child("AllowedNamespaces").Index(index).Key(key)
+			for _, msg := range validation.IsQualifiedName(key) {
+				errs = append(errs, field.Invalid(labelPath, key, msg))
+			}
+			for _, msg := range validation.IsValidLabelValue(value) {
+				errs = append(errs, field.Invalid(labelPath, value, msg))
+			}
+		}
+	}
+	return toErrors(errs)
+}
+
+func containsEnv(envs []string, env string) bool {
+	for _, candidate := range envs {
+		if candidate == env {
+			return true
+		}
+	}
+	return false
+}
+
+func validateResources(fldPath *field.Path, resources map[string]string) field.ErrorList {
+	var errs field.ErrorList
+	for name, value := range resources {
+		if _, err := resource.ParseQuantity(value); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Key(name), value, err.Error()))
+		}
+	}
+	return errs
+}
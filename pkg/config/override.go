@@ -0,0 +1,194 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/apis/types/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"strings"
+)
+
+var patchOps = map[string]bool{
+	"add":     true,
+	"remove":  true,
+	"replace": true,
+	"test":    true,
+}
+
+// EnvironmentOverride patches the copies of the resources it selects in an
+// environment, e.g. to lower the replicas of every deployment in uat.
+type EnvironmentOverride struct {
+	// Env is the environment of the copies to patch, fat, uat or sit
+	Env string `json:"env" yaml:"Env"`
+	// Workspaces limits the override to these workspaces
+	// +optional
+	Workspaces []string `json:"workspaces" yaml:"Workspaces"`
+	// Kinds limits the override to these kinds, e.g. deployment, matched
+	// case insensitively
+	// +optional
+	Kinds []string `json:"kinds" yaml:"Kinds"`
+	// Names limits the override to resources with these names
+	// +optional
+	Names []string `json:"names" yaml:"Names"`
+	// Selector limits the override to resources with matching labels
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector" yaml:"Selector"`
+	// JSONPatch is applied to the copy as a json patch (RFC 6902)
+	// +optional
+	JSONPatch []*PatchOperation `json:"json_patch" yaml:"JSONPatch"`
+	// StrategicMergePatch is applied to the copy as a strategic merge patch,
+	// after JSONPatch
+	// +optional
+	StrategicMergePatch map[string]interface{} `json:"strategic_merge_patch" yaml:"StrategicMergePatch"`
+}
+
+// PatchOperation is an add, remove, replace or test operation of a json
+// patch, op defaults to replace.
+type PatchOperation struct {
+	Op    string      `json:"op" yaml:"Op"`
+	Path  string      `json:"path" yaml:"Path"`
+	Value interface{} `json:"value" yaml:"Value"`
+}
+
+// Matches reports whether the override applies to the copy obj of kind in
+// the env environment of workspace.
+func (o *EnvironmentOverride) Matches(kind, workspace, env string, obj metav1.Object) bool {
+	if o.Env != env || !contains(o.Workspaces, workspace) || !containsFold(o.Kinds, kind) || !contains(o.Names, obj.GetName()) {
+		return false
+	}
+	if o.Selector == nil {
+		return true
+	}
+	selector, err := metav1.LabelSelectorAsSelector(o.Selector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+// JSONPatchDocument returns JSONPatch as a json patch document.
+func (o *EnvironmentOverride) JSONPatchDocument() ([]byte, error) {
+	var operations []v1beta1.ClusterOverride
+	for _, operation := range o.JSONPatch {
+		op := operation.Op
+		if len(op) == 0 {
+			op = "replace"
+		}
+		value, err := json.Marshal(normalize(operation.Value))
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, v1beta1.ClusterOverride{
+			Op:    strings.ToLower(op),
+			Path:  operation.Path,
+			Value: runtime.RawExtension{Raw: value},
+		})
+	}
+	return json.Marshal(operations)
+}
+
+// StrategicMergePatchDocument returns StrategicMergePatch as json.
+func (o *EnvironmentOverride) StrategicMergePatchDocument() ([]byte, error) {
+	return json.Marshal(normalize(o.StrategicMergePatch))
+}
+
+func ValidateEnvironmentOverrides(overrides []*EnvironmentOverride) []error {
+	var errs field.ErrorList
+	fldPath := field.NewPath("EnvironmentOverrides")
+
+	for index, override := range overrides {
+		overridePath := fldPath.Index(index)
+		if override == nil {
+			errs = append(errs, field.Required(overridePath, ""))
+			continue
+		}
+		if len(override.Env) == 0 {
+			errs = append(errs, field.Required(overridePath.Child("Env"), ""))
+		}
+		if override.Selector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(override.Selector); err != nil {
+				errs = append(errs, field.Invalid(overridePath.Child("Selector"), override.Selector, err.Error()))
+			}
+		}
+		if len(override.JSONPatch) == 0 && len(override.StrategicMergePatch) == 0 {
+			errs = append(errs, field.Required(overridePath, "one of JSONPatch or StrategicMergePatch is required"))
+		}
+		for opIndex, operation := range override.JSONPatch {
+			opPath := overridePath.Child("JSONPatch").Index(opIndex)
+			if operation == nil {
+				errs = append(errs, field.Required(opPath, ""))
+				continue
+			}
+			if len(operation.Op) != 0 && !patchOps[strings.ToLower(operation.Op)] {
+				errs = append(errs, field.NotSupported(opPath.Child("Op"), operation.Op, []string{"add", "remove", "replace", "test"}))
+			}
+			if !strings.HasPrefix(operation.Path, "/") {
+				errs = append(errs, field.Invalid(opPath.Child("Path"), operation.Path, "must be a json pointer starting with /"))
+			}
+		}
+		if _, err := override.JSONPatchDocument(); err != nil {
+			errs = append(errs, field.Invalid(overridePath.Child("JSONPatch"), "", err.Error()))
+		}
+		if _, err := override.StrategicMergePatchDocument(); err != nil {
+			errs = append(errs, field.Invalid(overridePath.Child("StrategicMergePatch"), "", err.Error()))
+		}
+	}
+	return toErrors(errs)
+}
+
+// contains reports whether value is in values, an empty list contains
+// everything.
+func contains(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold is contains under case folding.
+func containsFold(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize converts the map[interface{}]interface{} values decoded from
+// yaml to map[string]interface{} so they can be encoded as json.
+func normalize(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		result := map[string]interface{}{}
+		for k, v := range value {
+			result[fmt.Sprint(k)] = normalize(v)
+		}
+		return result
+	case map[string]interface{}:
+		result := map[string]interface{}{}
+		for k, v := range value {
+			result[k] = normalize(v)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, v := range value {
+			result[i] = normalize(v)
+		}
+		return result
+	default:
+		return value
+	}
+}
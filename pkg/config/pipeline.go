@@ -0,0 +1,159 @@
+package config
+
+import (
+	"github.com/hchenc/iceberg/pkg/constants"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"regexp"
+	"strings"
+)
+
+// PipelineMatch selects the applications of a pipeline by their type, the
+// app.kubernetes.io/type label, and their labels. Every rule set must match.
+type PipelineMatch struct {
+	// Types are application types, matched case insensitively
+	// +optional
+	Types []string `json:"types" yaml:"Types"`
+	// TypePatterns are regular expressions matched against the application
+	// type, an application matching Types or TypePatterns is selected
+	// +optional
+	TypePatterns []string `json:"type_patterns" yaml:"TypePatterns"`
+	// Selector matches the labels of the application
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector" yaml:"Selector"`
+}
+
+// Matches reports whether an application of appType with labels is selected.
+func (m *PipelineMatch) Matches(appType string, appLabels map[string]string) bool {
+	if len(m.Types) != 0 || len(m.TypePatterns) != 0 {
+		matched := len(m.Types) != 0 && containsFold(m.Types, appType)
+		for _, pattern := range m.TypePatterns {
+			if matched {
+				break
+			}
+			matched, _ = regexp.MatchString(pattern, appType)
+		}
+		if !matched {
+			return false
+		}
+	}
+	if m.Selector == nil {
+		return true
+	}
+	selector, err := metav1.LabelSelectorAsSelector(m.Selector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(appLabels))
+}
+
+// matches reports whether the option's rules select the application. An
+// option without Match keeps the former behaviour and selects the types
+// containing its pipeline name, except for the default pipeline.
+func (i *IntegrateOption) matches(appType string, appLabels map[string]string) bool {
+	if i.Match != nil {
+		return i.Match.Matches(appType, appLabels)
+	}
+	if i.Default || i.Pipeline == constants.DefaultPipeline || len(appType) == 0 {
+		return false
+	}
+	return strings.Contains(strings.ToLower(appType), strings.ToLower(i.Pipeline))
+}
+
+// precedes reports whether i takes precedence over other, workspace
+// overrides come before shared options and higher priorities before lower.
+// Options of equal rank keep their configuration order.
+func (i *IntegrateOption) precedes(other *IntegrateOption) bool {
+	if scoped, otherScoped := len(i.Workspaces) != 0, len(other.Workspaces) != 0; scoped != otherScoped {
+		return scoped
+	}
+	return i.Priority > other.Priority
+}
+
+// SelectIntegrateOption returns the integrate option of an application in
+// workspace with labels, nil if neither a rule nor a default selects it.
+// Options limited to other workspaces are ignored. When no option is marked
+// Default the pipeline named default is the fallback.
+func SelectIntegrateOption(integrateOptions []*IntegrateOption, workspace string, appLabels map[string]string) *IntegrateOption {
+	appType := appLabels[constants.KubesphereAppType]
+
+	var selected, fallback *IntegrateOption
+	explicitDefault := false
+	for _, integrateOption := range integrateOptions {
+		if integrateOption != nil && integrateOption.Default {
+			explicitDefault = true
+		}
+	}
+	for _, integrateOption := range integrateOptions {
+		if integrateOption == nil || !contains(integrateOption.Workspaces, workspace) {
+			continue
+		}
+		if integrateOption.matches(appType, appLabels) {
+			if selected == nil || integrateOption.precedes(selected) {
+				selected = integrateOption
+			}
+			continue
+		}
+		isDefault := integrateOption.Default || !explicitDefault && integrateOption.Pipeline == constants.DefaultPipeline
+		if isDefault && (fallback == nil || integrateOption.precedes(fallback)) {
+			fallback = integrateOption
+		}
+	}
+	if selected != nil {
+		return selected
+	}
+	return fallback
+}
+
+// scopes returns the workspaces of the option, a blank one when it's shared.
+func (i *IntegrateOption) scopes() []string {
+	if len(i.Workspaces) == 0 {
+		return []string{""}
+	}
+	return i.Workspaces
+}
+
+func (m *PipelineMatch) validate(fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if len(m.Types) == 0 && len(m.TypePatterns) == 0 && m.Selector == nil {
+		errs = append(errs, field.Required(fldPath, "at least one of Types, TypePatterns or Selector is required"))
+	}
+	for index, appType := range m.Types {
+		if len(appType) == 0 {
+			errs = append(errs, field.Required(fldPath.Child("Types").Index(index), ""))
+		}
+	}
+	for index, pattern := range m.TypePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("TypePatterns").Index(index), pattern, err.Error()))
+		}
+	}
+	if m.Selector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(m.Selector); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("Selector"), m.Selector, err.Error()))
+		}
+	}
+	return errs
+}
+
+// validateDefaults rejects several defaults for the same workspaces, a
+// workspace may have its own default besides the shared one.
+func validateDefaults(fldPath *field.Path, integrateOptions []*IntegrateOption) field.ErrorList {
+	var errs field.ErrorList
+	scopes := map[string]bool{}
+	for index, integrateOption := range integrateOptions {
+		if integrateOption == nil || !integrateOption.Default {
+			continue
+		}
+		for _, workspace := range integrateOption.scopes() {
+			if scopes[workspace] && len(workspace) == 0 {
+				errs = append(errs, field.Forbidden(fldPath.Index(index).Child("Default"), "another pipeline is the shared default"))
+			} else if scopes[workspace] {
+				errs = append(errs, field.Forbidden(fldPath.Index(index).Child("Default"), "another pipeline is the default of workspace "+workspace))
+			}
+			scopes[workspace] = true
+		}
+	}
+	return errs
+}
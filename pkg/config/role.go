@@ -0,0 +1,225 @@
+package config
+
+import (
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"strings"
+)
+
+const (
+	RoleKindRole        = "Role"
+	RoleKindClusterRole = "ClusterRole"
+
+	// DefaultEnvironmentRole is the Role bound to workspace members in the
+	// environments without any mapping
+	DefaultEnvironmentRole = "operator"
+)
+
+type RoleOptions struct {
+	// Templates are the rules of the Roles created and maintained in every
+	// environment namespace referencing them
+	// +optional
+	Templates []*RoleTemplate `json:"templates" yaml:"Templates"`
+
+	// Environments map the workspace roles of members to the Role or
+	// ClusterRole bound in each environment
+	// +optional
+	Environments []*RoleEnvironmentOptions `json:"environments" yaml:"Environments"`
+}
+
+type RoleTemplate struct {
+	// Name is the name of the Role
+	Name  string        `json:"name" yaml:"Name"`
+	Rules []*PolicyRule `json:"rules" yaml:"Rules"`
+}
+
+// PolicyRule mirrors rbacv1.PolicyRule with keys readable from the
+// configuration file.
+type PolicyRule struct {
+	Verbs           []string `json:"verbs" yaml:"Verbs"`
+	APIGroups       []string `json:"api_groups" yaml:"APIGroups"`
+	Resources       []string `json:"resources" yaml:"Resources"`
+	ResourceNames   []string `json:"resource_names" yaml:"ResourceNames"`
+	NonResourceURLs []string `json:"non_resource_urls" yaml:"NonResourceURLs"`
+}
+
+type RoleEnvironmentOptions struct {
+	// Env is the environment, e.g. fat, uat or sit
+	Env string `json:"env" yaml:"Env"`
+
+	// Roles map workspace roles to the role bound in Env
+	// +optional
+	Roles []*RoleMapping `json:"roles" yaml:"Roles"`
+
+	// Default is bound to the members whose workspace role isn't mapped,
+	// default to the Role operator
+	// +optional
+	Default *RoleRef `json:"default" yaml:"Default"`
+}
+
+type RoleMapping struct {
+	// WorkspaceRole is the workspace role of the member without the
+	// workspace prefix, e.g. admin, regular or viewer
+	WorkspaceRole string `json:"workspace_role" yaml:"WorkspaceRole"`
+
+	// Kind is Role or ClusterRole, default to Role
+	// +optional
+	Kind string `json:"kind" yaml:"Kind"`
+	Name string `json:"name" yaml:"Name"`
+}
+
+type RoleRef struct {
+	// Kind is Role or ClusterRole, default to Role
+	// +optional
+	Kind string `json:"kind" yaml:"Kind"`
+	Name string `json:"name" yaml:"Name"`
+}
+
+// GetKind returns the kind of the referenced role, default to Role.
+func (r RoleRef) GetKind() string {
+	if strings.EqualFold(r.Kind, RoleKindClusterRole) {
+		return RoleKindClusterRole
+	}
+	return RoleKindRole
+}
+
+// GetRoleRef returns the role bound in env to the members with workspaceRole.
+func (r *RoleOptions) GetRoleRef(env, workspaceRole string) RoleRef {
+	defaultRef := RoleRef{Kind: RoleKindRole, Name: DefaultEnvironmentRole}
+	if r == nil {
+		return defaultRef
+	}
+	for _, environment := range r.Environments {
+		if environment == nil || environment.Env != env {
+			continue
+		}
+		for _, mapping := range environment.Roles {
+			if mapping != nil && mapping.WorkspaceRole == workspaceRole {
+				ref := RoleRef{Kind: mapping.Kind, Name: mapping.Name}
+				return RoleRef{Kind: ref.GetKind(), Name: ref.Name}
+			}
+		}
+		if environment.Default != nil {
+			return RoleRef{Kind: environment.Default.GetKind(), Name: environment.Default.Name}
+		}
+	}
+	return defaultRef
+}
+
+// GetTemplate returns the template of the Role name, nil when there's none.
+func (r *RoleOptions) GetTemplate(name string) *RoleTemplate {
+	if r == nil {
+		return nil
+	}
+	for _, template := range r.Templates {
+		if template != nil && template.Name == name {
+			return template
+		}
+	}
+	return nil
+}
+
+// PolicyRules converts the rules of the template.
+func (t *RoleTemplate) PolicyRules() []rbacv1.PolicyRule {
+	rules := make([]rbacv1.PolicyRule, 0, len(t.Rules))
+	for _, rule := range t.Rules {
+		if rule == nil {
+			continue
+		}
+		rules = append(rules, rbacv1.PolicyRule{
+			Verbs:           rule.Verbs,
+			APIGroups:       rule.APIGroups,
+			Resources:       rule.Resources,
+			ResourceNames:   rule.ResourceNames,
+			NonResourceURLs: rule.NonResourceURLs,
+		})
+	}
+	return rules
+}
+
+func (r *RoleOptions) Validate() []error {
+	var errs field.ErrorList
+	fldPath := field.NewPath("RoleOptions")
+
+	names := map[string]bool{}
+	for index, template := range r.Templates {
+		templatePath := fldPath.Child("Templates").Index(index)
+		if template == nil {
+			errs = append(errs, field.Required(templatePath, ""))
+			continue
+		}
+		errs = append(errs, validateRoleName(templatePath.Child("Name"), template.Name)...)
+		if names[template.Name] {
+			errs = append(errs, field.Duplicate(templatePath.Child("Name"), template.Name))
+		}
+		names[template.Name] = true
+		for ruleIndex, rule := range template.Rules {
+			rulePath := templatePath.Child("Rules").Index(ruleIndex)
+			if rule == nil || len(rule.Verbs) == 0 {
+				errs = append(errs, field.Required(rulePath.Child("Verbs"), ""))
+				continue
+			}
+			if len(rule.NonResourceURLs) != 0 {
+				errs = append(errs, field.Forbidden(rulePath.Child("NonResourceURLs"), "namespaced roles cannot grant non-resource urls"))
+			}
+			if len(rule.Resources) == 0 {
+				errs = append(errs, field.Required(rulePath.Child("Resources"), ""))
+			}
+		}
+	}
+
+	envs := map[string]bool{}
+	for index, environment := range r.Environments {
+		envPath := fldPath.Child("Environments").Index(index)
+		if environment == nil {
+			errs = append(errs, field.Required(envPath, ""))
+			continue
+		}
+		if len(environment.Env) == 0 {
+			errs = append(errs, field.Required(envPath.Child("Env"), ""))
+		} else if envs[environment.Env] {
+			errs = append(errs, field.Duplicate(envPath.Child("Env"), environment.Env))
+		}
+		envs[environment.Env] = true
+
+		workspaceRoles := map[string]bool{}
+		for roleIndex, mapping := range environment.Roles {
+			rolePath := envPath.Child("Roles").Index(roleIndex)
+			if mapping == nil {
+				errs = append(errs, field.Required(rolePath, ""))
+				continue
+			}
+			if len(mapping.WorkspaceRole) == 0 {
+				errs = append(errs, field.Required(rolePath.Child("WorkspaceRole"), ""))
+			} else if workspaceRoles[mapping.WorkspaceRole] {
+				errs = append(errs, field.Duplicate(rolePath.Child("WorkspaceRole"), mapping.WorkspaceRole))
+			}
+			workspaceRoles[mapping.WorkspaceRole] = true
+			errs = append(errs, validateRoleRef(rolePath, RoleRef{Kind: mapping.Kind, Name: mapping.Name})...)
+		}
+		if environment.Default != nil {
+			errs = append(errs, validateRoleRef(envPath.Child("Default"), *environment.Default)...)
+		}
+	}
+	return toErrors(errs)
+}
+
+func validateRoleRef(fldPath *field.Path, ref RoleRef) field.ErrorList {
+	var errs field.ErrorList
+	if len(ref.Kind) != 0 && !strings.EqualFold(ref.Kind, RoleKindRole) && !strings.EqualFold(ref.Kind, RoleKindClusterRole) {
+		errs = append(errs, field.NotSupported(fldPath.Child("Kind"), ref.Kind, []string{RoleKindRole, RoleKindClusterRole}))
+	}
+	return append(errs, validateRoleName(fldPath.Child("Name"), ref.Name)...)
+}
+
+func validateRoleName(fldPath *field.Path, name string) field.ErrorList {
+	var errs field.ErrorList
+	if len(name) == 0 {
+		return append(errs, field.Required(fldPath, ""))
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(name) {
+		errs = append(errs, field.Invalid(fldPath, name, msg))
+	}
+	return errs
+}
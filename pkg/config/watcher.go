@@ -0,0 +1,143 @@
+package config
+
+import (
+	"context"
+	"github.com/fsnotify/fsnotify"
+	"github.com/hchenc/iceberg/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	v1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"reflect"
+	"time"
+)
+
+const (
+	// credentialFilePollPeriod is the period to re-read mounted credential files,
+	// kubelet swaps mounted secrets through symlinks which inotify can't follow reliably.
+	credentialFilePollPeriod = 30 * time.Second
+)
+
+// Watcher reloads the integration config when the configuration file, a
+// referenced secret or a referenced credential file changes, and hands the
+// new config to the registered handler. A new config failing validation is
+// dropped and the current one kept.
+type Watcher struct {
+	client       kubernetes.Interface
+	current      *IntegrationConfig
+	integrations Integrations
+	onChange     func(*IntegrationConfig)
+	changed      chan struct{}
+	logger       *logrus.Logger
+}
+
+// NewWatcher returns a watcher of current, reloaded configs are validated
+// for integrations like at startup.
+func NewWatcher(client kubernetes.Interface, current *IntegrationConfig, integrations Integrations, onChange func(*IntegrationConfig)) *Watcher {
+	return &Watcher{
+		client:       client,
+		current:      current,
+		integrations: integrations,
+		onChange:     onChange,
+		changed:      make(chan struct{}, 1),
+		logger: utils.GetLogger(logrus.Fields{
+			"component": "config",
+			"resource":  "watcher",
+		}),
+	}
+}
+
+// Start watches configuration changes until ctx is done. Secrets are watched
+// in the namespaces referenced by the config the watcher was created with.
+func (w *Watcher) Start(ctx context.Context) {
+	viper.OnConfigChange(func(in fsnotify.Event) {
+		w.notify()
+	})
+	viper.WatchConfig()
+
+	namespaces := map[string]bool{}
+	hasFile := false
+	for _, source := range w.current.credentialSources() {
+		if source.SecretRef != nil {
+			namespaces[source.SecretRef.GetNamespace()] = true
+		} else if len(source.File) != 0 {
+			hasFile = true
+		}
+	}
+
+	for namespace := range namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(w.client, 0, informers.WithNamespace(namespace))
+		factory.Core().V1().Secrets().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				w.notifySecret(obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				w.notifySecret(newObj)
+			},
+		})
+		factory.Start(ctx.Done())
+	}
+
+	var poll <-chan time.Time
+	if hasFile {
+		ticker := time.NewTicker(credentialFilePollPeriod)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll:
+			w.reload()
+		case <-w.changed:
+			w.reload()
+		}
+	}
+}
+
+func (w *Watcher) notify() {
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (w *Watcher) notifySecret(obj interface{}) {
+	secret, ok := obj.(*v1.Secret)
+	if !ok {
+		return
+	}
+	for _, source := range w.current.credentialSources() {
+		if ref := source.SecretRef; ref != nil && ref.GetNamespace() == secret.Namespace && ref.Name == secret.Name {
+			w.notify()
+			return
+		}
+	}
+}
+
+func (w *Watcher) reload() {
+	conf, err := load()
+	if err == nil {
+		err = conf.ResolveCredentials(w.client)
+	}
+	if err == nil {
+		err = utilerrors.NewAggregate(conf.Validate(w.integrations))
+	}
+	if err != nil {
+		w.logger.WithFields(logrus.Fields{
+			"message": "failed to reload configuration, keep the current one",
+		}).Error(err)
+		return
+	}
+	if reflect.DeepEqual(conf, w.current) {
+		return
+	}
+	w.logger.Info("configuration changed, reload integration clients")
+	w.current = conf
+	w.onChange(conf)
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"k8s.io/client-go/kubernetes/fake"
+	"path/filepath"
+	"testing"
+)
+
+func TestWatcherReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "integrate.yaml")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := viper.ReadInConfig(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	viper.SetConfigFile(file)
+	write("ProjectDeletionPolicy: Archive\n")
+	current, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reloaded *IntegrationConfig
+	watcher := NewWatcher(fake.NewSimpleClientset(), current, Integrations{}, func(conf *IntegrationConfig) {
+		reloaded = conf
+	})
+
+	// an invalid name pattern keeps the current config
+	write(`ProjectDeletionPolicy: Retain
+Controllers:
+  ServiceToEnv:
+    Filter:
+      ExcludeNames: ["/^(kube/"]
+`)
+	watcher.reload()
+	if reloaded != nil || watcher.current != current {
+		t.Fatalf("expected the invalid config to be dropped, got %+v", reloaded)
+	}
+
+	write("ProjectDeletionPolicy: Retain\n")
+	watcher.reload()
+	if reloaded == nil || reloaded.ProjectDeletionPolicy != ProjectDeletionPolicyRetain || watcher.current != reloaded {
+		t.Errorf("expected the valid config to be reloaded, got %+v", reloaded)
+	}
+}
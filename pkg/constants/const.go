@@ -13,6 +13,83 @@ const (
 	KubesphereWorkspace   = "kubesphere.io/workspace"
 	KubesphereCreator     = "kubesphere.io/creator"
 
+	// IcebergRegistryQuota is the workspace annotation limiting the storage of
+	// its registry namespace, e.g. 10Gi
+	IcebergRegistryQuota = "iceberg.io/registry-quota"
+	// RegistryPullSecret is the image pull secret created in every environment
+	RegistryPullSecret = "iceberg-registry-pull-secret"
+	// IcebergAdopt allows a workspace or an application to take over the
+	// existing scm or registry resources sharing its name which iceberg
+	// didn't create, "true" or "false"
+	IcebergAdopt = "iceberg.io/adopt"
+	// IcebergTopics is the comma separated list of topics of the scm project
+	// of an application
+	IcebergTopics = "iceberg.io/topics"
+
+	// IcebergDomain is the domain of iceberg labels and annotations
+	IcebergDomain = "iceberg.io"
+
+	// IcebergEnvironment labels the environment namespaces of a workspace
+	// with their environment, e.g. fat, together with KubesphereWorkspace it
+	// tells where resources are propagated
+	IcebergEnvironment = "iceberg.io/environment"
+
+	// IcebergPropagatedFrom labels copies made by iceberg with the namespace
+	// of their source, copies are never propagated themselves
+	IcebergPropagatedFrom = "iceberg.io/propagated-from"
+	// IcebergPropagate opts a resource in or out of the propagation to the
+	// other environments, "true" or "false". Without it the built-in rule of
+	// the controller applies, e.g. the version=v1 label of deployments
+	IcebergPropagate = "iceberg.io/propagate"
+	// IcebergPropagateTo is the comma separated list of environments a
+	// resource is propagated to, e.g. uat,sit, it implies IcebergPropagate
+	IcebergPropagateTo = "iceberg.io/propagate-to"
+	// IcebergOverrideOf labels a configmap holding the per environment
+	// overrides of the configmap it names, in the namespace of the copy
+	IcebergOverrideOf = "iceberg.io/override-of"
+	// IcebergOverrideDomain is the annotation domain of per environment
+	// overrides, e.g. uat.override.iceberg.io/DB_HOST
+	IcebergOverrideDomain = "override.iceberg.io"
+
+	// IcebergTransformDomain is the annotation domain of per environment
+	// workload transforms, e.g. uat.transform.iceberg.io/replicas
+	IcebergTransformDomain = "transform.iceberg.io"
+
+	// IcebergMemberCluster labels the secrets in DevopsNamespace holding the
+	// kubeconfig of a member cluster under MemberClusterKubeconfig, the secret
+	// is named after the cluster and its labels are matched by placements
+	IcebergMemberCluster    = "iceberg.io/member-cluster"
+	MemberClusterKubeconfig = "kubeconfig"
+	// IcebergFederated labels the resources iceberg created in member clusters
+	// and IcebergFederatedWorkspace their workspace, they are removed by these
+	// labels when the cluster leaves the placement or the workspace is deleted
+	IcebergFederated          = "iceberg.io/federated"
+	IcebergFederatedWorkspace = "iceberg.io/federated-workspace"
+	// IcebergFederatedStatus is the workspace annotation holding the json of
+	// its GenericFederatedStatus, WorkspaceTemplate is a kubesphere type with
+	// no status of its own. The Propagation condition is True when every
+	// cluster is propagated, the clusters list the failed overrides.
+	IcebergFederatedStatus = "iceberg.io/federated-status"
+
+	// IcebergSecretPropagation selects how a secret is propagated to the
+	// other environments, one of skeleton, copy or external
+	IcebergSecretPropagation = "iceberg.io/secret-propagation"
+	// SecretPropagationSkeleton copies the keys of a secret with empty values
+	SecretPropagationSkeleton = "skeleton"
+	// SecretPropagationCopy copies the values of a non-sensitive secret
+	SecretPropagationCopy = "copy"
+	// SecretPropagationExternal creates an ExternalSecret reading the values
+	// from a per environment path of the secret store
+	SecretPropagationExternal = "external"
+	// IcebergSecretStore names the secret store of external secrets
+	IcebergSecretStore = "iceberg.io/secret-store"
+	// IcebergSecretStoreKind is the kind of the secret store, ClusterSecretStore
+	// when not set
+	IcebergSecretStoreKind = "iceberg.io/secret-store-kind"
+	// IcebergSecretPath is the template of the remote path of external
+	// secrets, {{.Workspace}}/{{.Env}}/{{.Name}} when not set
+	IcebergSecretPath = "iceberg.io/secret-path"
+
 	FAT = "功能验收测试环境(Feature Acceptance Test environment)"
 	SIT = "系统集成测试环境(System Integration Test environment)"
 	UAT = "用户验收测试环境(User Acceptance Test environment)"
@@ -3,22 +3,42 @@ package controller
 import (
 	"context"
 	"github.com/go-logr/logr"
+	"github.com/hchenc/iceberg/pkg/clients/clientset"
+	"github.com/hchenc/iceberg/pkg/config"
 	"github.com/hchenc/iceberg/pkg/controllers/filters"
+	"github.com/hchenc/iceberg/pkg/syncer/resource"
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	"time"
 
 	"github.com/hchenc/application/pkg/apis/app/v1beta1"
 )
 
+const (
+	// pipelineConditionType is the condition of applications telling
+	// whether a pipeline selects them
+	pipelineConditionType  v1beta1.ConditionType = "PipelineSelected"
+	pipelineSelectedReason                       = "PipelineSelected"
+	pipelineNotFoundReason                       = "PipelineNotFound"
+)
+
 func init() {
 	RegisterReconciler("AppToProject", SetUpProjectReconcile)
+	RequireIntegrations("AppToProject", config.Integrations{SCM: true})
+	RequireGenerators("AppToProject", &projectGeneratorService, &applicationGeneratorService)
 }
 
 type ApplicationOperatorReconciler struct {
@@ -33,7 +53,23 @@ func (r *ApplicationOperatorReconciler) Reconcile(ctx context.Context, req recon
 	err := r.Get(ctx, req.NamespacedName, application)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			err := projectGeneratorService.Delete(req.Name)
+			if source, err := r.sourceOf(ctx, req); err != nil {
+				log.Logger.WithFields(logrus.Fields{
+					"application": req.Name,
+					"namespace":   req.Namespace,
+					"message":     "failed to look up the source application",
+				}).Error(err)
+				return reconcile.Result{}, err
+			} else if source != nil {
+				// a copy was deleted, the project belongs to the source
+				log.Logger.WithFields(logrus.Fields{
+					"application": req.Name,
+					"namespace":   req.Namespace,
+					"source":      source.Namespace,
+				}).Info("keep the project of the source application")
+				return reconcile.Result{}, nil
+			}
+			err := projectGeneratorService.Delete(req.String())
 			if err != nil {
 				log.Logger.WithFields(logrus.Fields{
 					"application": req.Name,
@@ -54,12 +90,23 @@ func (r *ApplicationOperatorReconciler) Reconcile(ctx context.Context, req recon
 		}).Info("start to action")
 		// create gitlab project
 		project, err := projectGeneratorService.Add(application)
+		if clientset.IsPipelineNotFound(err) {
+			// nothing to retry until the application or the pipelines change
+			log.Logger.WithFields(logrus.Fields{
+				"event":    "create",
+				"resource": "Project",
+				"name":     application.Name,
+				"result":   "failed",
+				"error":    err.Error(),
+			}).Warn("no pipeline selects the application")
+			return reconcile.Result{}, r.setPipelineCondition(ctx, application, corev1.ConditionFalse, pipelineNotFoundReason, err.Error())
+		}
 		if err != nil {
 			if project != nil {
 				log.Logger.WithFields(logrus.Fields{
 					"event":    "create",
 					"resource": "Pager",
-					"name":     "application-" + application.Name,
+					"name":     application.Name,
 					"result":   "failed",
 					"error":    err.Error(),
 				}).Errorf("pager created failed, retry after %d second", RetryPeriod)
@@ -76,28 +123,33 @@ func (r *ApplicationOperatorReconciler) Reconcile(ctx context.Context, req recon
 				RequeueAfter: RetryPeriod * time.Second,
 			}, err
 		}
+		if err := r.setPipelineCondition(ctx, application, corev1.ConditionTrue, pipelineSelectedReason, ""); err != nil {
+			return reconcile.Result{}, err
+		}
+
+		//sync application to all environment(fat|uat|sit) unless opted out
+		if resource.Propagates(application, true) {
+			_, err = applicationGeneratorService.Add(application)
+			if err != nil {
+				log.Logger.WithFields(logrus.Fields{
+					"event":    "create",
+					"resource": "Application",
+					"name":     application.Name,
+					"result":   "failed",
+					"error":    err.Error(),
+				}).Errorf("application created failed, retry after %d second", RetryPeriod)
+				return reconcile.Result{
+					RequeueAfter: RetryPeriod * time.Second,
+				}, err
+			}
 
-		//sync application to all environment(fat|uat|sit)
-		_, err = applicationGeneratorService.Add(application)
-		if err != nil {
 			log.Logger.WithFields(logrus.Fields{
 				"event":    "create",
 				"resource": "Application",
 				"name":     application.Name,
-				"result":   "failed",
-				"error":    err.Error(),
-			}).Errorf("application created failed, retry after %d second", RetryPeriod)
-			return reconcile.Result{
-				RequeueAfter: RetryPeriod * time.Second,
-			}, err
+				"result":   "success",
+			}).Infof("finish to sync application %s", application.Name)
 		}
-
-		log.Logger.WithFields(logrus.Fields{
-			"event":    "create",
-			"resource": "Application",
-			"name":     application.Name,
-			"result":   "success",
-		}).Infof("finish to sync application %s", application.Name)
 	}
 	log.Logger.WithFields(logrus.Fields{
 		"action": "AppToProject",
@@ -105,19 +157,120 @@ func (r *ApplicationOperatorReconciler) Reconcile(ctx context.Context, req recon
 	return reconcile.Result{}, nil
 }
 
+// sourceOf returns the application named like the deleted one which remains
+// in another environment of its workspace and isn't a copy made by iceberg,
+// nil if the deleted application was the source.
+func (r *ApplicationOperatorReconciler) sourceOf(ctx context.Context, req reconcile.Request) (*v1beta1.Application, error) {
+	_, _, siblings, err := namespaceResolver.Siblings(req.Namespace)
+	if resource.IsUnlabeledNamespace(err) || errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for namespace := range siblings {
+		application := &v1beta1.Application{}
+		err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: req.Name}, application)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if !resource.IsPropagated(application) {
+			return application, nil
+		}
+	}
+	return nil, nil
+}
+
+// setPipelineCondition records whether a pipeline selects the application
+// in its status, the status is only written when the condition changes.
+func (r *ApplicationOperatorReconciler) setPipelineCondition(ctx context.Context, application *v1beta1.Application, status corev1.ConditionStatus, reason, message string) error {
+	now := metav1.Now()
+	condition := v1beta1.Condition{
+		Type:               pipelineConditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastUpdateTime:     now,
+		LastTransitionTime: now,
+	}
+	conditions := application.Status.Conditions
+	index := 0
+	for ; index < len(conditions); index++ {
+		if conditions[index].Type == pipelineConditionType {
+			break
+		}
+	}
+	if index == len(conditions) {
+		if status == corev1.ConditionTrue {
+			// applications synced before the condition existed are left as is
+			return nil
+		}
+		application.Status.Conditions = append(conditions, condition)
+	} else {
+		current := conditions[index]
+		if current.Status == status && current.Reason == reason && current.Message == message {
+			return nil
+		}
+		if current.Status == status {
+			condition.LastTransitionTime = current.LastTransitionTime
+		}
+		conditions[index] = condition
+	}
+	err := r.Status().Update(ctx, application)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"application": application.Name,
+			"namespace":   application.Namespace,
+			"message":     "failed to update application status",
+		}).Error(err)
+	}
+	return err
+}
+
 func (r *ApplicationOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	selector := selectorOf("AppToProject", filters.DefaultEnvironmentFilter)
+	// applications are resynced when the pipelines change, e.g. to select
+	// the ones no pipeline selected before
+	reloaded := make(chan event.GenericEvent)
+	onConfigReload(func(old, new *config.IntegrationConfig) {
+		if equality.Semantic.DeepEqual(old.IntegrateOptions, new.IntegrateOptions) {
+			return
+		}
+		go func() {
+			applications := &v1beta1.ApplicationList{}
+			if err := r.List(context.Background(), applications); err != nil {
+				log.Logger.WithFields(logrus.Fields{
+					"message": "failed to list applications",
+				}).Error(err)
+				return
+			}
+			for index := range applications.Items {
+				if !selector.Selected(&applications.Items[index]) {
+					continue
+				}
+				reloaded <- event.GenericEvent{Object: &applications.Items[index]}
+			}
+		}()
+	})
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1beta1.Application{}).
-		WithEventFilter(
+		WithOptions(optionsOf("AppToProject")).
+		For(&v1beta1.Application{}, builder.WithPredicates(
+			selector,
 			predicate.Or(
-				&filters.NamespaceCreatePredicate{
-					IncludeNamespaces: filters.DefaultIncludeNamespaces,
-				},
-				&filters.NamespaceDeletePredicate{
-					ExcludeNamespaces: filters.DefaultExcludeNamespaces,
-				},
-			),
-		).
+				&filters.EventPredicate{OnCreate: true, OnDelete: true},
+				// description, topics, type and workspace of the project
+				// are read from the annotations and labels
+				predicate.And(
+					&filters.EventPredicate{OnUpdate: true},
+					predicate.Or(
+						predicate.AnnotationChangedPredicate{},
+						predicate.LabelChangedPredicate{},
+					),
+				),
+			))).
+		Watches(&source.Channel{Source: reloaded}, &handler.EnqueueRequestForObject{}).
 		Complete(r)
 }
 
@@ -127,6 +280,6 @@ func SetUpProjectReconcile(mgr manager.Manager) {
 		Log:    ctrl.Log.WithName("AppToProject"),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		log.Fatalf("unable to create application controller for ", err)
+		log.Fatalf("unable to create application controller: %v", err)
 	}
 }
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"context"
+	"github.com/hchenc/application/pkg/apis/app/v1beta1"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/syncer/resource"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"testing"
+)
+
+func TestSourceOf(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, env := range []string{"fat", "uat", "sit"} {
+		_ = indexer.Add(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "devops-" + env,
+			Labels: map[string]string{constants.KubesphereWorkspace: "devops", constants.IcebergEnvironment: env},
+		}})
+	}
+	namespaceResolver = resource.NewNamespaceResolver(corelisters.NewNamespaceLister(indexer))
+	defer func() { namespaceResolver = nil }()
+
+	scheme := runtime.NewScheme()
+	if err := v1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	source := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "devops-fat"}}
+	copied := &v1beta1.Application{ObjectMeta: metav1.ObjectMeta{
+		Name:      "web",
+		Namespace: "devops-sit",
+		Labels:    map[string]string{constants.IcebergPropagatedFrom: "devops-fat"},
+	}}
+	r := &ApplicationOperatorReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(source, copied).Build()}
+
+	// the copy in uat was deleted, the source in fat remains
+	found, err := r.sourceOf(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "devops-uat", Name: "web"}})
+	if err != nil || found == nil || found.Namespace != "devops-fat" {
+		t.Errorf("expected the source in devops-fat, got %v, %v", found, err)
+	}
+
+	// the source was deleted, only a copy remains
+	if err := r.Delete(context.Background(), source); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := r.sourceOf(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "devops-fat", Name: "web"}}); err != nil || found != nil {
+		t.Errorf("expected no source, got %v, %v", found, err)
+	}
+}
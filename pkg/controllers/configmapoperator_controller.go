@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/controllers/filters"
+	"github.com/hchenc/iceberg/pkg/syncer/resource"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"time"
+)
+
+var (
+	configMapAction = "ConfigMapToEnv"
+)
+
+func init() {
+	RegisterReconciler(configMapAction, SetUpConfigMapReconcile)
+	RequireGenerators(configMapAction, &configMapGeneratorService)
+}
+
+type ConfigMapOperatorReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+func (c *ConfigMapOperatorReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	configMap := &v1.ConfigMap{}
+
+	err := c.Get(ctx, req.NamespacedName, configMap)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.Log.Info("receive delete event")
+			return ctrl.Result{}, nil
+		} else {
+			log.Logger.WithFields(logrus.Fields{
+				"configmap": req.Name,
+				"namespace": req.Namespace,
+				"message":   "failed to reconcile configmap",
+			}).Error(err)
+			return ctrl.Result{}, err
+		}
+	}
+
+	if configMap.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	source, err := c.source(ctx, configMap)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"configmap": req.Name,
+			"namespace": req.Namespace,
+			"message":   "failed to get source of configmap",
+		}).Error(err)
+		return ctrl.Result{}, err
+	}
+	if source == nil {
+		return ctrl.Result{}, nil
+	}
+
+	log.Logger.WithFields(logrus.Fields{
+		"action": configMapAction,
+	}).Info("start to action")
+
+	{
+		//sync configmap to all environment(fat|uat|sit)
+		_, err = configMapGeneratorService.Add(source)
+		if err != nil {
+			log.Logger.WithFields(logrus.Fields{
+				"event":    "create",
+				"resource": "ConfigMap",
+				"name":     source.Name,
+				"result":   "failed",
+				"error":    err.Error(),
+			}).Errorf("configmap sync to fat|uat|sit env failed, retry after %d second", RetryPeriod)
+			return reconcile.Result{
+				RequeueAfter: RetryPeriod * time.Second,
+			}, err
+		}
+		log.Logger.WithFields(logrus.Fields{
+			"event":    "create",
+			"resource": "ConfigMap",
+			"name":     source.Name,
+			"result":   "success",
+		}).Infof("finish to sync configmap %s", source.Name)
+	}
+	log.Logger.WithFields(logrus.Fields{
+		"action": configMapAction,
+	}).Info("finish to action")
+	return reconcile.Result{}, nil
+}
+
+// source returns the configmap to propagate for an event on configMap. An
+// override configmap resolves to the source of the copy it overrides,
+// copies made by iceberg and sources opted out resolve to nothing.
+func (c *ConfigMapOperatorReconciler) source(ctx context.Context, configMap *v1.ConfigMap) (*v1.ConfigMap, error) {
+	name := configMap.Labels[constants.IcebergOverrideOf]
+	if len(name) == 0 {
+		if resource.IsPropagated(configMap) {
+			return nil, nil
+		}
+		return configMap, nil
+	}
+
+	copied := &v1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: configMap.Namespace, Name: name}, copied); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if !resource.IsPropagated(copied) {
+		return nil, nil
+	}
+
+	source := &v1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: copied.Labels[constants.IcebergPropagatedFrom], Name: name}, source); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if !resource.Propagates(source, true) {
+		return nil, nil
+	}
+	return source, nil
+}
+
+func (c *ConfigMapOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(optionsOf(configMapAction)).
+		For(&v1.ConfigMap{}).
+		WithEventFilter(
+			predicate.And(
+				selectorOf(configMapAction, filters.DefaultEnvironmentFilter),
+				&filters.EventPredicate{OnCreate: true, OnUpdate: true},
+				// override configmaps pass, they aren't copies
+				&filters.PropagationPredicate{},
+			),
+		).
+		Complete(c)
+}
+
+func SetUpConfigMapReconcile(mgr manager.Manager) {
+	if err := (&ConfigMapOperatorReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName(configMapAction),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		log.Fatalf("unable to create configmap controller: %v", err)
+	}
+}
@@ -2,18 +2,30 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"github.com/hchenc/iceberg/pkg/clients/clientset"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/controllers/filters"
 	"github.com/hchenc/iceberg/pkg/syncer"
+	"github.com/hchenc/iceberg/pkg/syncer/distribution"
+	"github.com/hchenc/iceberg/pkg/syncer/federation"
+	"github.com/hchenc/iceberg/pkg/syncer/gitea"
 	"github.com/hchenc/iceberg/pkg/syncer/gitlab"
 	"github.com/hchenc/iceberg/pkg/syncer/harbor"
+	"github.com/hchenc/iceberg/pkg/syncer/registry"
 	"github.com/hchenc/iceberg/pkg/syncer/resource"
+	"github.com/hchenc/iceberg/pkg/syncer/scm"
 	"github.com/hchenc/iceberg/pkg/utils"
 	"github.com/sirupsen/logrus"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	ingress "k8s.io/api/networking/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/runtime"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sort"
+	"strings"
 
 	application "github.com/hchenc/application/pkg/apis/app/v1beta1"
 	iamv1alpha2 "github.com/hchenc/iceberg/pkg/apis/iam/v1alpha2"
@@ -32,19 +44,20 @@ var (
 
 var (
 	reconcilerMap = make(map[string]Reconciler)
+	// integrationMap holds the external systems used by the reconcilers
+	// which use any
+	integrationMap = make(map[string]config.Integrations)
+	// generatorMap holds the generator services used by the reconcilers,
+	// only those of the enabled reconcilers are installed
+	generatorMap = make(map[string][]*syncer.GenerateService)
 
-	projectGenerator     syncer.Generator
-	groupGenerator       syncer.Generator
-	namespaceGenerator   syncer.Generator
-	applicationGenerator syncer.Generator
-	userGenerator        syncer.Generator
-	rolebindingGenerator syncer.Generator
-	memberGenerator      syncer.Generator
-	harborGenerator      syncer.Generator
-	deploymentGenerator  syncer.Generator
-	serviceGenerator     syncer.Generator
-	volumeGenerator      syncer.Generator
-	secretGenerator      syncer.Generator
+	// integrationConfig returns the current integration config
+	integrationConfig = func() *config.IntegrationConfig { return nil }
+	// onConfigReload registers a listener of integration config reloads
+	onConfigReload = func(listener func(old, new *config.IntegrationConfig)) {}
+	// namespaceResolver resolves the workspace and the environment of
+	// namespaces from their labels
+	namespaceResolver *resource.NamespaceResolver
 
 	projectGeneratorService     syncer.GenerateService
 	groupGeneratorService       syncer.GenerateService
@@ -53,11 +66,19 @@ var (
 	userGeneratorService        syncer.GenerateService
 	rolebindingGeneratorService syncer.GenerateService
 	memberGeneratorService      syncer.GenerateService
-	harborGeneratorService      syncer.GenerateService
+	registryGeneratorService    syncer.GenerateService
 	deploymentGeneratorService  syncer.GenerateService
 	serviceGeneratorService     syncer.GenerateService
 	volumeGeneratorService      syncer.GenerateService
 	secretGeneratorService      syncer.GenerateService
+	ingressGeneratorService     syncer.GenerateService
+	configMapGeneratorService   syncer.GenerateService
+	statefulSetGeneratorService syncer.GenerateService
+	daemonSetGeneratorService   syncer.GenerateService
+	jobGeneratorService         syncer.GenerateService
+	cronJobGeneratorService     syncer.GenerateService
+	federationGeneratorService  syncer.GenerateService
+	environmentGeneratorService syncer.GenerateService
 )
 
 type Reconciler interface {
@@ -74,6 +95,73 @@ func RegisterReconciler(name string, f Reconcile) {
 	reconcilerMap[name] = f
 }
 
+// RequireIntegrations declares the external systems the reconciler name
+// talks to, their clients are only built when it's enabled.
+func RequireIntegrations(name string, integrations config.Integrations) {
+	integrationMap[name] = integrationMap[name].Merge(integrations)
+}
+
+// EnabledReconcilers returns the sorted names of the registered reconcilers
+// enabled by controllers, the value of --controllers, and the Controllers
+// config. Unknown names in controllers are an error.
+func EnabledReconcilers(controllers []string, conf *config.IntegrationConfig) ([]string, error) {
+	names := make([]string, 0, len(reconcilerMap))
+	for name := range reconcilerMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, controller := range controllers {
+		if controller == "*" {
+			continue
+		}
+		if !containsFold(names, strings.TrimPrefix(controller, "-")) {
+			errs = append(errs, fmt.Errorf("unknown controller %q, known controllers: %s", controller, strings.Join(names, ", ")))
+		}
+	}
+	for controller := range conf.Controllers {
+		if !containsFold(names, controller) {
+			log.WithField("controller", controller).Warn("options of an unknown controller are ignored")
+		}
+	}
+	if len(errs) != 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+
+	var enabled []string
+	for _, name := range names {
+		if conf.ControllerEnabled(name, controllers) {
+			enabled = append(enabled, name)
+		}
+	}
+	return enabled, nil
+}
+
+// RequireGenerators declares the generator services the reconciler name
+// uses, they're only installed when it's enabled.
+func RequireGenerators(name string, services ...*syncer.GenerateService) {
+	generatorMap[name] = append(generatorMap[name], services...)
+}
+
+// IntegrationsOf returns the external systems used by the reconcilers names.
+func IntegrationsOf(names []string) config.Integrations {
+	var integrations config.Integrations
+	for _, name := range names {
+		integrations = integrations.Merge(integrationMap[name])
+	}
+	return integrations
+}
+
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
 type Controller struct {
 	Clientset *clientset.ClientSet
 
@@ -90,12 +178,21 @@ func (c *Controller) Reconcile(ctx context.Context) error {
 	return nil
 }
 
-func NewControllerOrDie(cs *clientset.ClientSet, mgr manager.Manager) *Controller {
+// NewControllerOrDie sets up the reconcilers named by enabled, see
+// EnabledReconcilers.
+func NewControllerOrDie(cs *clientset.ClientSet, mgr manager.Manager, enabled []string) *Controller {
 	c := &Controller{
-		Clientset: cs,
-		manager:   mgr,
+		Clientset:     cs,
+		ReconcilerMap: map[string]Reconciler{},
+		manager:       mgr,
+	}
+	for _, name := range enabled {
+		reconciler, exists := reconcilerMap[name]
+		if !exists {
+			log.Fatalf("unknown controller %s", name)
+		}
+		c.ReconcilerMap[name] = reconciler
 	}
-	c.ReconcilerMap = reconcilerMap
 
 	runtime.Must(workspace.AddToScheme(mgr.GetScheme()))
 	runtime.Must(application.AddToScheme(mgr.GetScheme()))
@@ -104,43 +201,197 @@ func NewControllerOrDie(cs *clientset.ClientSet, mgr manager.Manager) *Controlle
 	runtime.Must(corev1.AddToScheme(mgr.GetScheme()))
 	runtime.Must(ingress.AddToScheme(mgr.GetScheme()))
 
-	installGenerator(c.Clientset)
-	installGeneratorService()
+	integrationConfig = cs.Config
+	onConfigReload = cs.OnReload
+	installGenerator(c.Clientset, enabled)
 
-	for _, reconciler := range c.ReconcilerMap {
+	for name, reconciler := range c.ReconcilerMap {
+		log.WithField("controller", name).Info("set up controller")
 		reconciler.SetUp(mgr)
 	}
 	return c
 }
 
-func installGenerator(clientset *clientset.ClientSet) {
-	projectGenerator = gitlab.NewGitLabProjectGenerator("", "", clientset.Ctx, clientset.GitlabClient, clientset.PagerClient)
-	groupGenerator = gitlab.NewGroupGenerator("", clientset.Ctx, clientset.GitlabClient, clientset.PagerClient)
-	userGenerator = gitlab.NewUserGenerator(clientset.Ctx, clientset.GitlabClient, clientset.PagerClient)
-	memberGenerator = gitlab.NewMemberGenerator(clientset.Ctx, clientset.GitlabClient, clientset.PagerClient)
-
-	namespaceGenerator = resource.NewNamespaceGenerator(clientset.Ctx, clientset.Kubeclient)
-	applicationGenerator = resource.NewApplicationGenerator(clientset.Ctx, clientset.Kubeclient, clientset.AppClient)
-	rolebindingGenerator = resource.NewRolebindingGenerator(clientset.Ctx, clientset.Kubeclient)
-	deploymentGenerator = resource.NewDeploymentGenerator(clientset.Ctx, clientset.Kubeclient)
-	serviceGenerator = resource.NewServiceGenerator(clientset.Ctx, clientset.Kubeclient)
-	volumeGenerator = resource.NewVolumeGenerator(clientset.Ctx, clientset.Kubeclient)
-	secretGenerator = resource.NewSecretGenerator(clientset.Ctx, clientset.Kubeclient)
-
-	harborGenerator = harbor.NewHarborProjectGenerator("", "", clientset.HarborClient)
-}
-
-func installGeneratorService() {
-	projectGeneratorService = syncer.NewGenerateService(projectGenerator)
-	groupGeneratorService = syncer.NewGenerateService(groupGenerator)
-	namespaceGeneratorService = syncer.NewGenerateService(namespaceGenerator)
-	applicationGeneratorService = syncer.NewGenerateService(applicationGenerator)
-	userGeneratorService = syncer.NewGenerateService(userGenerator)
-	rolebindingGeneratorService = syncer.NewGenerateService(rolebindingGenerator)
-	memberGeneratorService = syncer.NewGenerateService(memberGenerator)
-	harborGeneratorService = syncer.NewGenerateService(harborGenerator)
-	deploymentGeneratorService = syncer.NewGenerateService(deploymentGenerator)
-	serviceGeneratorService = syncer.NewGenerateService(serviceGenerator)
-	volumeGeneratorService = syncer.NewGenerateService(volumeGenerator)
-	secretGeneratorService = syncer.NewGenerateService(secretGenerator)
+// optionsOf returns the options of the controller name, the configured
+// number of concurrent reconciles.
+func optionsOf(name string) crcontroller.Options {
+	var options crcontroller.Options
+	if conf := integrationConfig(); conf != nil {
+		if controllerOptions := conf.GetController(name); controllerOptions != nil {
+			options.MaxConcurrentReconciles = controllerOptions.MaxConcurrentReconciles
+		}
+	}
+	return options
+}
+
+// selectorOf returns the predicate selecting the objects of the controller
+// name by its configured filter, defaults when none is configured.
+func selectorOf(name string, defaults *config.FilterOptions) filters.SelectorPredicate {
+	selector := filters.SelectorPredicate{
+		Options: func() *config.FilterOptions {
+			if conf := integrationConfig(); conf != nil {
+				return conf.GetFilter(name, defaults)
+			}
+			return defaults
+		},
+	}
+	// controllers of cluster scoped objects don't resolve namespaces
+	if namespaceResolver != nil {
+		selector.NamespaceLabels = namespaceResolver.Labels
+	}
+	return selector
+}
+
+// generatorInstaller builds the generator of a generator service,
+// namespaces is set when it resolves namespaces.
+type generatorInstaller struct {
+	namespaces bool
+	install    func() syncer.Generator
+}
+
+// installGenerator installs the generator services used by the enabled
+// reconcilers, the namespace informer is only started when one of them
+// resolves namespaces.
+func installGenerator(clientset *clientset.ClientSet, enabled []string) {
+	services := map[*syncer.GenerateService]bool{}
+	for _, name := range enabled {
+		for _, service := range generatorMap[name] {
+			services[service] = true
+		}
+	}
+	installers := generatorInstallers(clientset)
+	for service := range services {
+		if installers[service].namespaces && namespaceResolver == nil {
+			resolver, err := resource.NewCachedNamespaceResolver(clientset.Ctx, clientset.Kubeclient)
+			runtime.Must(err)
+			namespaceResolver = resolver
+		}
+	}
+	for service := range services {
+		installer, exists := installers[service]
+		if !exists {
+			log.Fatalf("no generator installer for %v", service)
+		}
+		*service = syncer.NewGenerateService(installer.install())
+	}
+}
+
+// generatorInstallers returns the installers of the generator services, the
+// scm and registry ones require their clients which are only built for an
+// enabled controller using them.
+func generatorInstallers(clientset *clientset.ClientSet) map[*syncer.GenerateService]generatorInstaller {
+	var imageRegistry syncer.Registry
+	switch {
+	case clientset.DistributionClient != nil:
+		imageRegistry = distribution.NewDistributionRegistry(clientset.DistributionClient)
+	case clientset.HarborClient != nil:
+		imageRegistry = harbor.NewHarborRegistry(clientset.HarborClient)
+	}
+	// projects are bootstrapped without registry server when no registry is
+	// in use
+	registryServer := func() string { return "" }
+	if imageRegistry != nil {
+		registryServer = imageRegistry.Server
+	}
+
+	var provider syncer.SCM
+	switch {
+	case clientset.GiteaClient != nil:
+		provider = gitea.NewGiteaSCM(clientset.GiteaClient)
+	case clientset.GitlabClient != nil:
+		provider = gitlab.NewGitlabSCM(clientset.GitlabClient)
+	}
+	scmProvider := func() syncer.SCM {
+		if provider == nil {
+			log.Fatal("no scm client, the scm is only integrated for the controllers requiring it")
+		}
+		return provider
+	}
+
+	overrides := func() []*config.EnvironmentOverride {
+		return clientset.Config().EnvironmentOverrides
+	}
+	namespaceOptions := func() *config.NamespaceOptions {
+		return clientset.Config().NamespaceOptions
+	}
+	return map[*syncer.GenerateService]generatorInstaller{
+		&projectGeneratorService: {namespaces: true, install: func() syncer.Generator {
+			return scm.NewProjectGenerator(clientset.Ctx, scmProvider(), func() string {
+				return clientset.Config().GetProjectDeletionPolicy()
+			}, registryServer, namespaceResolver, clientset.IntegrateClient, clientset.PagerClient)
+		}},
+		&groupGeneratorService: {install: func() syncer.Generator {
+			return scm.NewGroupGenerator(clientset.Ctx, scmProvider(), func() *syncer.GroupLayout {
+				gitlabOptions := clientset.Config().GitlabOptions
+				if clientset.SCMProvider == config.SCMProviderGitea || gitlabOptions == nil {
+					return nil
+				}
+				return &syncer.GroupLayout{
+					Parent:    gitlabOptions.ParentGroup,
+					SubGroups: gitlabOptions.SubGroups,
+				}
+			}, clientset.PagerClient)
+		}},
+		&userGeneratorService: {install: func() syncer.Generator {
+			return scm.NewUserGenerator(clientset.Ctx, scmProvider(), clientset.PagerClient)
+		}},
+		&memberGeneratorService: {install: func() syncer.Generator {
+			return scm.NewMemberGenerator(clientset.Ctx, scmProvider(), clientset.PagerClient)
+		}},
+		&registryGeneratorService: {install: func() syncer.Generator {
+			if imageRegistry == nil {
+				log.Fatal("no registry client, the registry is only integrated for the controllers requiring it")
+			}
+			return registry.NewProjectGenerator(clientset.Ctx, imageRegistry, clientset.Kubeclient)
+		}},
+		&federationGeneratorService: {install: func() syncer.Generator {
+			return federation.NewWorkspaceGenerator(clientset.Ctx, clientset.DynamicClient, clientset.MemberClusters)
+		}},
+		&namespaceGeneratorService: {install: func() syncer.Generator {
+			return resource.NewNamespaceGenerator(clientset.Ctx, clientset.Kubeclient, namespaceOptions)
+		}},
+		&environmentGeneratorService: {namespaces: true, install: func() syncer.Generator {
+			return resource.NewEnvironmentGenerator(clientset.Ctx, clientset.Kubeclient, namespaceResolver, namespaceOptions)
+		}},
+		&rolebindingGeneratorService: {namespaces: true, install: func() syncer.Generator {
+			return resource.NewRolebindingGenerator(clientset.Ctx, clientset.Kubeclient, namespaceResolver, func() *config.RoleOptions {
+				return clientset.Config().RoleOptions
+			})
+		}},
+		&applicationGeneratorService: {namespaces: true, install: func() syncer.Generator {
+			return resource.NewApplicationGenerator(clientset.Ctx, clientset.Kubeclient, clientset.AppClient, namespaceResolver)
+		}},
+		&deploymentGeneratorService: {namespaces: true, install: func() syncer.Generator {
+			return resource.NewDeploymentGenerator(clientset.Ctx, clientset.Kubeclient, namespaceResolver, overrides)
+		}},
+		&statefulSetGeneratorService: {namespaces: true, install: func() syncer.Generator {
+			return resource.NewStatefulSetGenerator(clientset.Ctx, clientset.Kubeclient, namespaceResolver, overrides)
+		}},
+		&daemonSetGeneratorService: {namespaces: true, install: func() syncer.Generator {
+			return resource.NewDaemonSetGenerator(clientset.Ctx, clientset.Kubeclient, namespaceResolver, overrides)
+		}},
+		&jobGeneratorService: {namespaces: true, install: func() syncer.Generator {
+			return resource.NewJobGenerator(clientset.Ctx, clientset.Kubeclient, namespaceResolver, overrides)
+		}},
+		&cronJobGeneratorService: {namespaces: true, install: func() syncer.Generator {
+			return resource.NewCronJobGenerator(clientset.Ctx, clientset.Kubeclient, namespaceResolver, overrides)
+		}},
+		&serviceGeneratorService: {namespaces: true, install: func() syncer.Generator {
+			return resource.NewServiceGenerator(clientset.Ctx, clientset.Kubeclient, namespaceResolver, overrides)
+		}},
+		&volumeGeneratorService: {namespaces: true, install: func() syncer.Generator {
+			return resource.NewVolumeGenerator(clientset.Ctx, clientset.Kubeclient, namespaceResolver, overrides)
+		}},
+		&secretGeneratorService: {namespaces: true, install: func() syncer.Generator {
+			return resource.NewSecretGenerator(clientset.Ctx, clientset.Kubeclient, clientset.DynamicClient, namespaceResolver, overrides)
+		}},
+		&ingressGeneratorService: {namespaces: true, install: func() syncer.Generator {
+			return resource.NewIngressGenerator(clientset.Ctx, clientset.Kubeclient, namespaceResolver, func() *config.IngressOptions {
+				return clientset.Config().IngressOptions
+			}, overrides)
+		}},
+		&configMapGeneratorService: {namespaces: true, install: func() syncer.Generator {
+			return resource.NewConfigMapGenerator(clientset.Ctx, clientset.Kubeclient, namespaceResolver, overrides)
+		}},
+	}
 }
@@ -1 +1,53 @@
 package controller
+
+import (
+	"github.com/hchenc/iceberg/pkg/clients/clientset"
+	"github.com/hchenc/iceberg/pkg/config"
+	"testing"
+)
+
+func TestEnabledReconcilers(t *testing.T) {
+	conf := &config.IntegrationConfig{}
+	if _, err := EnabledReconcilers([]string{"*", "-Unknown"}, conf); err == nil {
+		t.Error("expected an unknown controller to fail")
+	}
+
+	enabled, err := EnabledReconcilers([]string{"*", "-" + action, "-" + registryAction, "-" + federationAction, "-AppToProject", "-UserToUser"}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enabled) != len(reconcilerMap)-5 {
+		t.Errorf("unexpected enabled controllers %v", enabled)
+	}
+	if integrations := IntegrationsOf(enabled); integrations != (config.Integrations{SCM: true}) {
+		t.Errorf("expected the scm of RolebindingToMember only, got %+v", integrations)
+	}
+
+	enabled, err = EnabledReconcilers([]string{"ServiceToEnv", "secrettoenv"}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enabled) != 2 || enabled[0] != secretAction || enabled[1] != "ServiceToEnv" {
+		t.Errorf("unexpected enabled controllers %v", enabled)
+	}
+	if integrations := IntegrationsOf(enabled); integrations != (config.Integrations{}) {
+		t.Errorf("expected no integration, got %+v", integrations)
+	}
+	if integrations := IntegrationsOf([]string{action, registryAction}); integrations != (config.Integrations{SCM: true, Registry: true}) {
+		t.Errorf("expected scm and registry, got %+v", integrations)
+	}
+}
+
+func TestGeneratorInstallers(t *testing.T) {
+	installers := generatorInstallers(&clientset.ClientSet{})
+	for name := range reconcilerMap {
+		for _, service := range generatorMap[name] {
+			if _, exists := installers[service]; !exists {
+				t.Errorf("no installer for a generator of %s", name)
+			}
+		}
+	}
+	if len(generatorMap[action]) != 2 {
+		t.Errorf("expected the group and namespace generators of %s, got %d", action, len(generatorMap[action]))
+	}
+}
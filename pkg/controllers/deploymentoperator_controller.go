@@ -19,6 +19,7 @@ import (
 
 func init() {
 	RegisterReconciler("DeploymentToEnv", SetUpDeploymentReconcile)
+	RequireGenerators("DeploymentToEnv", &deploymentGeneratorService)
 }
 
 type DeploymentOperatorReconciler struct {
@@ -74,17 +75,19 @@ func (d *DeploymentOperatorReconciler) Reconcile(ctx context.Context, req reconc
 
 func (d *DeploymentOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(optionsOf("DeploymentToEnv")).
 		For(&v1.Deployment{}).
 		WithEventFilter(
 			predicate.And(
-				&filters.NamespaceCreatePredicate{
-					IncludeNamespaces: filters.DefaultIncludeNamespaces,
+				selectorOf("DeploymentToEnv", filters.DefaultEnvironmentFilter),
+				&filters.EventPredicate{OnCreate: true},
+				&filters.PropagationPredicate{
+					Default: &filters.LabelCreatePredicate{
+						Force: true,
+						IncludeLabels: map[string]string{
+							constants.KubesphereVersion: constants.KubesphereInitVersion,
+						}},
 				},
-				&filters.LabelCreatePredicate{
-					Force: true,
-					IncludeLabels: map[string]string{
-						constants.KubesphereVersion: constants.KubesphereInitVersion,
-					}},
 			),
 		).
 		Complete(d)
@@ -96,6 +99,6 @@ func SetUpDeploymentReconcile(mgr manager.Manager) {
 		Log:    ctrl.Log.WithName("DeploymentToEnv"),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		log.Fatalf("unable to create deployment controller for ", err)
+		log.Fatalf("unable to create deployment controller: %v", err)
 	}
 }
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	"github.com/hchenc/iceberg/pkg/apis/tenant/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"time"
+)
+
+var (
+	environmentAction = "WorkspaceTemplateToEnvironmentPolicy"
+)
+
+func init() {
+	RegisterReconciler(environmentAction, SetUpEnvironmentReconcile)
+	RequireGenerators(environmentAction, &environmentGeneratorService)
+}
+
+type EnvironmentOperatorReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+func (e *EnvironmentOperatorReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	workspaceTemplate := &v1alpha2.WorkspaceTemplate{}
+
+	err := e.Get(ctx, req.NamespacedName, workspaceTemplate)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			e.Log.Info("receive delete event")
+			return ctrl.Result{}, nil
+		} else {
+			log.Logger.WithFields(logrus.Fields{
+				"workspaceTemplate": req.Name,
+				"message":           "failed to reconcile workspaceTemplate",
+			}).Error(err)
+			return ctrl.Result{}, err
+		}
+	}
+
+	if workspaceTemplate.DeletionTimestamp != nil || workspaceTemplate.Name == "system" || workspaceTemplate.Name == "kube" {
+		return ctrl.Result{}, nil
+	}
+
+	log.Logger.WithFields(logrus.Fields{
+		"action": environmentAction,
+	}).Info("start to action")
+
+	{
+		//sync quota, limits and network isolation of all environment(fat|uat|sit)
+		_, err = environmentGeneratorService.Add(workspaceTemplate)
+		if err != nil {
+			log.Logger.WithFields(logrus.Fields{
+				"event":    "create",
+				"resource": "EnvironmentPolicy",
+				"name":     workspaceTemplate.Name,
+				"result":   "failed",
+				"error":    err.Error(),
+			}).Errorf("environment policies sync to fat|uat|sit env failed, retry after %d second", RetryPeriod)
+			return reconcile.Result{
+				RequeueAfter: RetryPeriod * time.Second,
+			}, err
+		}
+		log.Logger.WithFields(logrus.Fields{
+			"event":    "create",
+			"resource": "EnvironmentPolicy",
+			"name":     workspaceTemplate.Name,
+			"result":   "success",
+		}).Infof("finish to sync environment policies of workspace %s", workspaceTemplate.Name)
+	}
+	log.Logger.WithFields(logrus.Fields{
+		"action": environmentAction,
+	}).Info("finish to action")
+	return reconcile.Result{}, nil
+}
+
+func (e *EnvironmentOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// every workspace is resynced when the namespace templates change
+	reloaded := make(chan event.GenericEvent)
+	onConfigReload(func(old, new *config.IntegrationConfig) {
+		if equality.Semantic.DeepEqual(old.NamespaceOptions, new.NamespaceOptions) {
+			return
+		}
+		go func() {
+			workspaceTemplates := &v1alpha2.WorkspaceTemplateList{}
+			if err := e.List(context.Background(), workspaceTemplates); err != nil {
+				log.Logger.WithFields(logrus.Fields{
+					"message": "failed to list workspaceTemplates",
+				}).Error(err)
+				return
+			}
+			for index := range workspaceTemplates.Items {
+				reloaded <- event.GenericEvent{Object: &workspaceTemplates.Items[index]}
+			}
+		}()
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(optionsOf(environmentAction)).
+		For(&v1alpha2.WorkspaceTemplate{}).
+		Watches(&source.Channel{Source: reloaded}, &handler.EnqueueRequestForObject{}).
+		WithEventFilter(
+			predicate.And(
+				selectorOf(environmentAction, nil),
+				predicate.Or(
+					predicate.GenerationChangedPredicate{},
+					predicate.AnnotationChangedPredicate{},
+				),
+			),
+		).
+		Complete(e)
+}
+
+func SetUpEnvironmentReconcile(mgr manager.Manager) {
+	if err := (&EnvironmentOperatorReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName(environmentAction),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		log.Fatalf("unable to create environment controller: %v", err)
+	}
+}
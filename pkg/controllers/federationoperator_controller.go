@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-logr/logr"
+	"github.com/hchenc/iceberg/pkg/apis/tenant/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/apis/types/v1beta1"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"time"
+)
+
+const (
+	// FederationSyncPeriod is how often placed workspaces are propagated to
+	// their member clusters again, resources of member clusters aren't watched
+	FederationSyncPeriod = 5 * time.Minute
+)
+
+var (
+	federationAction = "WorkspaceTemplateToClusters"
+)
+
+func init() {
+	RegisterReconciler(federationAction, SetUpFederationReconcile)
+	RequireIntegrations(federationAction, config.Integrations{Federation: true})
+	RequireGenerators(federationAction, &federationGeneratorService)
+}
+
+type FederationOperatorReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+func (f *FederationOperatorReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	workspaceTemplate := &v1alpha2.WorkspaceTemplate{}
+
+	err := f.Get(ctx, req.NamespacedName, workspaceTemplate)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			f.Log.Info("receive delete event")
+			// the resources of the workspace are removed from member clusters by their labels
+			if err := federationGeneratorService.Delete(req.Name); err != nil {
+				log.Logger.WithFields(logrus.Fields{
+					"workspaceTemplate": req.Name,
+					"message":           "failed to remove workspace from member clusters",
+				}).Error(err)
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		} else {
+			log.Logger.WithFields(logrus.Fields{
+				"workspaceTemplate": req.Name,
+				"message":           "failed to reconcile workspaceTemplate",
+			}).Error(err)
+			return ctrl.Result{}, err
+		}
+	}
+
+	// a workspace which was placed before is synced to remove it from the
+	// clusters it left
+	placement := workspaceTemplate.Spec.Placement
+	_, placed := workspaceTemplate.Annotations[constants.IcebergFederatedStatus]
+	if workspaceTemplate.DeletionTimestamp != nil || (len(placement.Clusters) == 0 && placement.ClusterSelector == nil && !placed) {
+		return ctrl.Result{}, nil
+	}
+
+	log.Logger.WithFields(logrus.Fields{
+		"action": federationAction,
+	}).Info("start to action")
+
+	status, syncErr := federationGeneratorService.Add(workspaceTemplate)
+	if status, ok := status.(*v1beta1.GenericFederatedStatus); ok && status != nil {
+		if err := f.updateStatus(ctx, workspaceTemplate, status); err != nil {
+			log.Logger.WithFields(logrus.Fields{
+				"workspaceTemplate": req.Name,
+				"message":           "failed to update federated status",
+			}).Error(err)
+		}
+	}
+	if syncErr != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"event":    "create",
+			"resource": "FederatedWorkspace",
+			"name":     workspaceTemplate.Name,
+			"result":   "failed",
+			"error":    syncErr.Error(),
+		}).Errorf("workspace propagated to member clusters failed, retry after %d second", RetryPeriod)
+		return reconcile.Result{
+			RequeueAfter: RetryPeriod * time.Second,
+		}, syncErr
+	}
+	log.Logger.WithFields(logrus.Fields{
+		"event":    "create",
+		"resource": "FederatedWorkspace",
+		"name":     workspaceTemplate.Name,
+		"result":   "success",
+	}).Infof("finish to propagate workspace %s to member clusters", workspaceTemplate.Name)
+
+	log.Logger.WithFields(logrus.Fields{
+		"action": federationAction,
+	}).Info("finish to action")
+	return reconcile.Result{
+		RequeueAfter: FederationSyncPeriod,
+	}, nil
+}
+
+// updateStatus records status in the iceberg.io/federated-status annotation,
+// WorkspaceTemplate is a kubesphere type with no status of its own. The
+// annotation holds the json of a v1beta1.GenericFederatedStatus: the
+// Propagation condition and the status of every cluster.
+func (f *FederationOperatorReconciler) updateStatus(ctx context.Context, workspaceTemplate *v1alpha2.WorkspaceTemplate, status *v1beta1.GenericFederatedStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	patch := client.MergeFrom(workspaceTemplate.DeepCopy())
+	if workspaceTemplate.Annotations == nil {
+		workspaceTemplate.Annotations = map[string]string{}
+	}
+	workspaceTemplate.Annotations[constants.IcebergFederatedStatus] = string(data)
+	return f.Patch(ctx, workspaceTemplate, patch)
+}
+
+func (f *FederationOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(optionsOf(federationAction)).
+		For(&v1alpha2.WorkspaceTemplate{}).
+		WithEventFilter(
+			predicate.And(
+				selectorOf(federationAction, nil),
+				predicate.GenerationChangedPredicate{},
+			),
+		).
+		Complete(f)
+}
+
+func SetUpFederationReconcile(mgr manager.Manager) {
+	if err := (&FederationOperatorReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName(federationAction),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		log.Fatalf("unable to create federation controller: %v", err)
+	}
+}
@@ -1,37 +1,40 @@
 package filters
 
 import (
-	"strings"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/constants"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var (
-	DefaultIncludeNamespaces = []string{
-		"fat",
-		"sit",
-		"uat",
+	// DefaultEnvironmentFilter selects the objects of the environment
+	// namespaces, which carry the iceberg.io/environment label
+	DefaultEnvironmentFilter = &config.FilterOptions{
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: constants.IcebergEnvironment, Operator: metav1.LabelSelectorOpExists},
+			},
+		},
 	}
-	DefaultExcludeNamespaces = []string{
-		"system",
-		"kube",
+	// DefaultWorkspaceFilter skips the built-in workspace of kubesphere
+	DefaultWorkspaceFilter = &config.FilterOptions{
+		ExcludeNames: []string{"system-workspace"},
 	}
-	DefaultExcludeNames = []string{
-		"system",
-		"admin",
+	// DefaultUserFilter skips the built-in admin of kubesphere
+	DefaultUserFilter = &config.FilterOptions{
+		ExcludeNames: []string{"admin"},
 	}
-)
-
-func checkIndexKey(array []string, indexKey string) (bool, bool) {
-	exists, verified := false, false
-
-	if len(array) != 0 {
-		verified = true
-		for _, ns := range array {
-			exists = exists || strings.Contains(indexKey, ns)
-		}
-		return exists, verified
+	// DefaultRolebindingFilter skips the bindings of the built-in admin, named
+	// <user>-<workspace role>, and of the built-in workspace
+	DefaultRolebindingFilter = &config.FilterOptions{
+		ExcludeNames: []string{"admin-*"},
+		Selector: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: constants.KubesphereWorkspace, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"system-workspace"}},
+			},
+		},
 	}
-	return exists, verified
-}
+)
 
 func checkLabels(labels map[string]string, target map[string]string, force bool) bool {
 	result := false
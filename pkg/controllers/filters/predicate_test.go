@@ -1,70 +1,95 @@
 package filters
 
 import (
+	"github.com/hchenc/iceberg/pkg/config"
 	"github.com/hchenc/iceberg/pkg/constants"
 	"github.com/magiconair/properties/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"testing"
 )
 
-func TestNamespacesFilter(t *testing.T) {
-	var nonStrings []string
-	var name string
-	var result, exists bool
-
-	name = "sales-fat"
-	result, exists = checkIndexKey(DefaultIncludeNamespaces, name)
-	assert.Equal(t, exists, true)
-	assert.Equal(t, result, true)
-
-	name = "sales-sit"
-	result, exists = checkIndexKey(DefaultIncludeNamespaces, name)
-	assert.Equal(t, exists, true)
-	assert.Equal(t, result, true)
-
-	name = "sales-uat"
-	result, exists = checkIndexKey(DefaultIncludeNamespaces, name)
-	assert.Equal(t, exists, true)
-	assert.Equal(t, result, true)
-
-	name = "devops-system"
-	result, exists = checkIndexKey(DefaultIncludeNamespaces, name)
-	assert.Equal(t, exists, true)
-	assert.Equal(t, result, false)
-
-	name = "kube-system"
-	result, exists = checkIndexKey(DefaultIncludeNamespaces, name)
-	assert.Equal(t, exists, true)
-	assert.Equal(t, result, false)
-
-	name = "sales-fat"
-	result, exists = checkIndexKey(nonStrings, name)
-	assert.Equal(t, exists, false)
-	assert.Equal(t, result, false)
-
-	name = "sales-sit"
-	result, exists = checkIndexKey(nonStrings, name)
-	assert.Equal(t, exists, false)
-	assert.Equal(t, result, false)
-
-	name = "sales-uat"
-	result, exists = checkIndexKey(nonStrings, name)
-	assert.Equal(t, exists, false)
-	assert.Equal(t, result, false)
+func TestSelectorPredicate(t *testing.T) {
+	namespaces := map[string]map[string]string{
+		"sales-fat":     {constants.KubesphereWorkspace: "sales", constants.IcebergEnvironment: "fat"},
+		"sales-uat":     {constants.KubesphereWorkspace: "sales", constants.IcebergEnvironment: "uat"},
+		"fatal-ops":     {constants.KubesphereWorkspace: "ops"},
+		"devops-system": {},
+	}
+	namespaceLabels := func(namespace string) (map[string]string, error) {
+		if namespaceLabels, exists := namespaces[namespace]; exists {
+			return namespaceLabels, nil
+		}
+		return nil, errors.NewNotFound(corev1.Resource("namespaces"), namespace)
+	}
+	newObject := func(namespace, name string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
 
-	name = "devops-system"
-	result, exists = checkIndexKey(nonStrings, name)
-	assert.Equal(t, exists, false)
-	assert.Equal(t, result, false)
+	var options *config.FilterOptions
+	selector := SelectorPredicate{
+		Options:         func() *config.FilterOptions { return options },
+		NamespaceLabels: namespaceLabels,
+	}
+	assert.Equal(t, selector.Selected(newObject("kube-system", "any")), true)
+
+	options = DefaultEnvironmentFilter
+	assert.Equal(t, selector.Create(event.CreateEvent{Object: newObject("sales-fat", "web")}), true)
+	assert.Equal(t, selector.Delete(event.DeleteEvent{Object: newObject("sales-uat", "web")}), true)
+	assert.Equal(t, selector.Selected(newObject("fatal-ops", "web")), false)
+	assert.Equal(t, selector.Selected(newObject("devops-system", "web")), false)
+	assert.Equal(t, selector.Selected(newObject("deleted", "web")), false)
+
+	options = &config.FilterOptions{
+		IncludeNamespaces: []string{"*-fat", "/^sales-(uat|sit)$/"},
+		ExcludeNames:      []string{"kube"},
+	}
+	assert.Equal(t, selector.Selected(newObject("sales-uat", "kubeflow-team")), true)
+	assert.Equal(t, selector.Selected(newObject("sales-fat", "kube")), false)
+	assert.Equal(t, selector.Selected(newObject("fatal-ops", "web")), false)
+	assert.Equal(t, selector.Selected(newObject("", "web")), false)
+
+	options = DefaultRolebindingFilter
+	rolebinding := newObject("", "alice-sales-admin")
+	rolebinding.Labels = map[string]string{constants.KubesphereWorkspace: "sales"}
+	assert.Equal(t, selector.Selected(rolebinding), true)
+	rolebinding.Labels[constants.KubesphereWorkspace] = "system-workspace"
+	assert.Equal(t, selector.Selected(rolebinding), false)
+	assert.Equal(t, selector.Selected(newObject("", "admin-sales-admin")), false)
+}
 
-	name = "kube-system"
-	result, exists = checkIndexKey(nonStrings, name)
-	assert.Equal(t, exists, false)
-	assert.Equal(t, result, false)
+func TestEventPredicate(t *testing.T) {
+	p := EventPredicate{OnCreate: true, OnUpdate: true}
+	old, updated := &metav1.PartialObjectMetadata{}, &metav1.PartialObjectMetadata{}
+	old.ResourceVersion, updated.ResourceVersion = "1", "1"
+	assert.Equal(t, p.Create(event.CreateEvent{Object: old}), true)
+	assert.Equal(t, p.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated}), false)
+	updated.ResourceVersion = "2"
+	assert.Equal(t, p.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated}), true)
+	assert.Equal(t, p.Delete(event.DeleteEvent{Object: old}), false)
+}
 
-	name = "test"
-	exists, verified := checkIndexKey(DefaultExcludeNames, name)
-	assert.Equal(t, verified, true)
-	assert.Equal(t, exists, false)
+func TestPropagationPredicate(t *testing.T) {
+	versioned := LabelCreatePredicate{
+		Force:         true,
+		IncludeLabels: map[string]string{constants.KubesphereVersion: constants.KubesphereInitVersion},
+	}
+	newObject := func(labels, annotations map[string]string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "sales-fat", Labels: labels, Annotations: annotations}}
+	}
+	p := PropagationPredicate{Default: versioned}
+	assert.Equal(t, p.Create(event.CreateEvent{Object: newObject(nil, nil)}), false)
+	assert.Equal(t, p.Create(event.CreateEvent{Object: newObject(map[string]string{"version": "v1"}, nil)}), true)
+	assert.Equal(t, p.Create(event.CreateEvent{Object: newObject(nil, map[string]string{constants.IcebergPropagate: "true"})}), true)
+	assert.Equal(t, p.Create(event.CreateEvent{Object: newObject(map[string]string{"version": "v1"}, map[string]string{constants.IcebergPropagate: "false"})}), false)
+
+	p = PropagationPredicate{}
+	assert.Equal(t, p.Create(event.CreateEvent{Object: newObject(nil, nil)}), true)
+	assert.Equal(t, p.Create(event.CreateEvent{Object: newObject(map[string]string{constants.IcebergPropagatedFrom: "sales-uat"}, nil)}), false)
+	token := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "default-token-x"}, Type: corev1.SecretTypeServiceAccountToken}
+	assert.Equal(t, p.Create(event.CreateEvent{Object: token}), false)
 }
 
 func TestNamesFilter(t *testing.T) {
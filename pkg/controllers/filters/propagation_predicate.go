@@ -0,0 +1,34 @@
+package filters
+
+import (
+	"github.com/hchenc/iceberg/pkg/syncer/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// PropagationPredicate passes the events of the objects propagated to the
+// other environments. The iceberg.io/propagate and iceberg.io/propagate-to
+// annotations decide first, Default is the rule of the controller for the
+// objects without them and passes everything when nil. The built-in
+// exclusions of resource.IsExcluded never pass.
+type PropagationPredicate struct {
+	Default predicate.Predicate
+}
+
+func (p PropagationPredicate) propagates(obj client.Object, byDefault func() bool) bool {
+	return resource.Propagates(obj, p.Default == nil || byDefault())
+}
+
+func (p PropagationPredicate) Create(e event.CreateEvent) bool {
+	return p.propagates(e.Object, func() bool { return p.Default.Create(e) })
+}
+func (p PropagationPredicate) Update(e event.UpdateEvent) bool {
+	return p.propagates(e.ObjectNew, func() bool { return p.Default.Update(e) })
+}
+func (p PropagationPredicate) Delete(e event.DeleteEvent) bool {
+	return p.propagates(e.Object, func() bool { return p.Default.Delete(e) })
+}
+func (p PropagationPredicate) Generic(e event.GenericEvent) bool {
+	return p.propagates(e.Object, func() bool { return p.Default.Generic(e) })
+}
@@ -0,0 +1,62 @@
+package filters
+
+import (
+	"github.com/hchenc/iceberg/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// NamespaceLabels returns the labels of a namespace, read from a cache.
+type NamespaceLabels func(namespace string) (map[string]string, error)
+
+// SelectorPredicate passes the events of the objects selected by the filter
+// options of a controller. Options are read on every event so that reloads
+// apply at once, every object passes without options.
+type SelectorPredicate struct {
+	Options         func() *config.FilterOptions
+	NamespaceLabels NamespaceLabels
+}
+
+// Selected reports whether the controller reconciles obj.
+func (s SelectorPredicate) Selected(obj client.Object) bool {
+	options := s.Options()
+	if options == nil {
+		return true
+	}
+	return options.Matches(obj, s.NamespaceLabels)
+}
+
+func (s SelectorPredicate) Create(e event.CreateEvent) bool {
+	return s.Selected(e.Object)
+}
+func (s SelectorPredicate) Update(e event.UpdateEvent) bool {
+	return s.Selected(e.ObjectNew)
+}
+func (s SelectorPredicate) Delete(e event.DeleteEvent) bool {
+	return s.Selected(e.Object)
+}
+func (s SelectorPredicate) Generic(e event.GenericEvent) bool {
+	return s.Selected(e.Object)
+}
+
+// EventPredicate passes the kinds of events which are set, updates only
+// when the resource version changed so that resyncs are ignored.
+type EventPredicate struct {
+	OnCreate  bool
+	OnUpdate  bool
+	OnDelete  bool
+	OnGeneric bool
+}
+
+func (p EventPredicate) Create(e event.CreateEvent) bool {
+	return p.OnCreate
+}
+func (p EventPredicate) Update(e event.UpdateEvent) bool {
+	return p.OnUpdate && e.ObjectOld.GetResourceVersion() != e.ObjectNew.GetResourceVersion()
+}
+func (p EventPredicate) Delete(e event.DeleteEvent) bool {
+	return p.OnDelete
+}
+func (p EventPredicate) Generic(e event.GenericEvent) bool {
+	return p.OnGeneric
+}
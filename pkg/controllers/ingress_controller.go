@@ -2,28 +2,34 @@ package controller
 
 import (
 	"context"
-	"fmt"
 	"github.com/go-logr/logr"
+	"github.com/hchenc/iceberg/pkg/config"
 	"github.com/hchenc/iceberg/pkg/controllers/filters"
+	"github.com/hchenc/iceberg/pkg/syncer/resource"
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"time"
 )
 
 func init() {
 	RegisterReconciler("PatchIngress", SetUpIngressReconcile)
+	RequireGenerators("PatchIngress", &ingressGeneratorService)
 }
 
 type IngressOperatorReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
 func (i *IngressOperatorReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
@@ -47,19 +53,33 @@ func (i *IngressOperatorReconciler) Reconcile(ctx context.Context, req reconcile
 		return ctrl.Result{}, nil
 	}
 
-	if uv, exists := ingress.Annotations["nginx.ingress.kubernetes.io/upstream-vhost"]; exists && uv != "" {
-		return reconcile.Result{}, nil
+	var ingressOptions *config.IngressOptions
+	if conf := integrationConfig(); conf != nil {
+		ingressOptions = conf.IngressOptions
+	}
+	changed, err := resource.PatchIngress(ingress, ingressOptions, namespaceResolver)
+	if conflict, ok := err.(*resource.IngressConflictError); ok {
+		// the hosts may still have been filled, the conflict is only reported
+		log.Logger.WithFields(logrus.Fields{
+			"ingress":   req.Name,
+			"namespace": req.Namespace,
+			"services":  conflict.Services,
+		}).Warn(conflict.Error())
+		i.Recorder.Event(ingress, corev1.EventTypeWarning, "UpstreamVhostConflict", conflict.Error())
+	} else if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"ingress":   req.Name,
+			"namespace": req.Namespace,
+			"message":   "failed to patch ingress",
+		}).Error(err)
+		return ctrl.Result{}, err
 	}
 
 	log.Logger.WithFields(logrus.Fields{
 		"action": "PatchIngress",
 	}).Info("start to action")
 
-	{
-		serviceName := ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name
-		namespaceName := ingress.Namespace
-		upstreamVhost := fmt.Sprintf("%s.%s.svc.cluster.local", serviceName, namespaceName)
-		ingress.Annotations["nginx.ingress.kubernetes.io/upstream-vhost"] = upstreamVhost
+	if changed {
 		if err := i.Update(ctx, ingress); err != nil {
 			log.Logger.WithFields(logrus.Fields{
 				"action": "PatchIngress",
@@ -68,6 +88,28 @@ func (i *IngressOperatorReconciler) Reconcile(ctx context.Context, req reconcile
 		}
 	}
 
+	if !resource.Propagates(ingress, true) {
+		log.Logger.WithFields(logrus.Fields{
+			"action": "PatchIngress",
+		}).Info("finish to action")
+		return reconcile.Result{}, nil
+	}
+
+	//sync ingress to all environment(fat|uat|sit)
+	_, err = ingressGeneratorService.Add(ingress)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"event":    "create",
+			"resource": "Ingress",
+			"name":     ingress.Name,
+			"result":   "failed",
+			"error":    err.Error(),
+		}).Errorf("ingress sync to fat|uat|sit env failed, retry after %d second", RetryPeriod)
+		return reconcile.Result{
+			RequeueAfter: RetryPeriod * time.Second,
+		}, err
+	}
+
 	log.Logger.WithFields(logrus.Fields{
 		"action": "PatchIngress",
 	}).Info("finish to action")
@@ -76,15 +118,12 @@ func (i *IngressOperatorReconciler) Reconcile(ctx context.Context, req reconcile
 
 func (i *IngressOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(optionsOf("PatchIngress")).
 		For(&v1.Ingress{}).
 		WithEventFilter(
-			predicate.Or(
-				&filters.NamespaceCreatePredicate{
-					IncludeNamespaces: filters.DefaultIncludeNamespaces,
-				},
-				&filters.NamespaceDeletePredicate{
-					IncludeNamespaces: filters.DefaultIncludeNamespaces,
-				},
+			predicate.And(
+				selectorOf("PatchIngress", filters.DefaultEnvironmentFilter),
+				&filters.EventPredicate{OnCreate: true, OnDelete: true},
 			),
 		).
 		Complete(i)
@@ -92,10 +131,11 @@ func (i *IngressOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 func SetUpIngressReconcile(mgr manager.Manager) {
 	if err := (&IngressOperatorReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("PatchIngress"),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("PatchIngress"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("PatchIngress"),
 	}).SetupWithManager(mgr); err != nil {
-		log.Fatalf("unable to create ingress controller for ", err)
+		log.Fatalf("unable to create ingress controller: %v", err)
 	}
 }
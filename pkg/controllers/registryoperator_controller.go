@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	"github.com/hchenc/iceberg/pkg/apis/tenant/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/controllers/filters"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"time"
+)
+
+var (
+	registryAction = "WorkspaceTemplateToRegistry"
+)
+
+func init() {
+	RegisterReconciler(registryAction, SetUpRegistryReconcile)
+	RequireIntegrations(registryAction, config.Integrations{Registry: true})
+	RequireGenerators(registryAction, &registryGeneratorService)
+}
+
+// RegistryOperatorReconciler creates the registry project of workspaces and
+// the pull secrets of their environments, apart from the group so that the
+// registry can be turned off on its own.
+type RegistryOperatorReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+func (r *RegistryOperatorReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	workspaceTemplate := &v1alpha2.WorkspaceTemplate{}
+
+	err := r.Get(ctx, req.NamespacedName, workspaceTemplate)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Logger.WithFields(logrus.Fields{
+				"workspaceTemplate": req.Name,
+				"message":           "failed to reconcile workspaceTemplate",
+			}).Error(err)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	log.Logger.WithFields(logrus.Fields{
+		"action": registryAction,
+	}).Info("start to action")
+	// create registry's project and the pull secrets of every environment,
+	// retried until the environment namespaces are created
+	_, err = registryGeneratorService.Add(workspaceTemplate)
+	if err != nil {
+		log.Logger.WithFields(logrus.Fields{
+			"event":    "create",
+			"resource": "Registry",
+			"name":     workspaceTemplate.Name,
+			"result":   "failed",
+			"error":    err.Error(),
+		}).Errorf("registry project created failed, retry after %d second", RetryPeriod)
+		return reconcile.Result{
+			RequeueAfter: RetryPeriod * time.Second,
+		}, err
+	}
+	log.Logger.WithFields(logrus.Fields{
+		"action": registryAction,
+	}).Info("finish to action")
+	return reconcile.Result{}, nil
+}
+
+func (r *RegistryOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(optionsOf(registryAction)).
+		For(&v1alpha2.WorkspaceTemplate{}).
+		WithEventFilter(
+			predicate.And(
+				selectorOf(registryAction, filters.DefaultWorkspaceFilter),
+				&filters.EventPredicate{OnCreate: true},
+			),
+		).
+		Complete(r)
+}
+
+func SetUpRegistryReconcile(mgr manager.Manager) {
+	if err := (&RegistryOperatorReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName(registryAction),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		log.Fatalf("unable to create registry controller: %v", err)
+	}
+}
@@ -5,20 +5,27 @@ import (
 	"fmt"
 	"github.com/go-logr/logr"
 	iamv1alpha2 "github.com/hchenc/iceberg/pkg/apis/iam/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/config"
 	"github.com/hchenc/iceberg/pkg/controllers/filters"
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
-	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	"time"
 )
 
 func init() {
 	RegisterReconciler("RolebindingToMember", SetUpRolebindingReconcile)
+	RequireIntegrations("RolebindingToMember", config.Integrations{SCM: true})
+	RequireGenerators("RolebindingToMember", &memberGeneratorService, &rolebindingGeneratorService)
 }
 
 type RolebindingOperatorReconciler struct {
@@ -59,7 +66,7 @@ func (r RolebindingOperatorReconciler) Reconcile(ctx context.Context, req reconc
 				log.Logger.WithFields(logrus.Fields{
 					"event":    "create",
 					"resource": "Pager",
-					"name":     "member-" + rolebinding.Subjects[0].Name,
+					"name":     "member-" + rolebinding.Name,
 					"result":   "failed",
 					"message":  fmt.Sprintf("pager created failed, retry after %d second", RetryPeriod),
 				}).Error(err)
@@ -106,15 +113,37 @@ func (r RolebindingOperatorReconciler) Reconcile(ctx context.Context, req reconc
 }
 
 func (r *RolebindingOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	selector := selectorOf("RolebindingToMember", filters.DefaultRolebindingFilter)
+	// every member is rebound when the role mapping or templates change
+	reloaded := make(chan event.GenericEvent)
+	onConfigReload(func(old, new *config.IntegrationConfig) {
+		if equality.Semantic.DeepEqual(old.RoleOptions, new.RoleOptions) {
+			return
+		}
+		go func() {
+			rolebindings := &iamv1alpha2.WorkspaceRoleBindingList{}
+			if err := r.List(context.Background(), rolebindings); err != nil {
+				log.Logger.WithFields(logrus.Fields{
+					"message": "failed to list workspace rolebindings",
+				}).Error(err)
+				return
+			}
+			for index := range rolebindings.Items {
+				if !selector.Selected(&rolebindings.Items[index]) {
+					continue
+				}
+				reloaded <- event.GenericEvent{Object: &rolebindings.Items[index]}
+			}
+		}()
+	})
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&iamv1alpha2.WorkspaceRoleBinding{}).
-		WithEventFilter(
-			predicate.Or(
-				&filters.NameCreatePredicate{
-					ExcludeNames: filters.DefaultExcludeNames,
-				}, &filters.NameDeletePredicate{
-					ExcludeNames: filters.DefaultExcludeNames,
-				})).
+		WithOptions(optionsOf("RolebindingToMember")).
+		For(&iamv1alpha2.WorkspaceRoleBinding{}, builder.WithPredicates(
+			selector,
+			&filters.EventPredicate{OnCreate: true, OnUpdate: true, OnDelete: true},
+		)).
+		Watches(&source.Channel{Source: reloaded}, &handler.EnqueueRequestForObject{}).
 		Complete(r)
 }
 
@@ -124,6 +153,6 @@ func SetUpRolebindingReconcile(mgr manager.Manager) {
 		Log:    ctrl.Log.WithName("RolebindingToMember"),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		log.Fatalf("unable to create rolebinding controller for ", err)
+		log.Fatalf("unable to create rolebinding controller: %v", err)
 	}
 }
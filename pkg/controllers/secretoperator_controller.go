@@ -11,6 +11,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"time"
 )
@@ -21,6 +22,7 @@ var (
 
 func init() {
 	RegisterReconciler(secretAction, SetUpSecretReconcile)
+	RequireGenerators(secretAction, &secretGeneratorService)
 }
 
 type SecretOperatorReconciler struct {
@@ -29,7 +31,7 @@ type SecretOperatorReconciler struct {
 	Scheme *runtime.Scheme
 }
 
-func (s *SecretOperatorReconciler) Reconcile(ctx context.Context,req reconcile.Request) (reconcile.Result, error) {
+func (s *SecretOperatorReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
 	secret := &v1.Secret{}
 
 	err := s.Get(ctx, req.NamespacedName, secret)
@@ -39,7 +41,7 @@ func (s *SecretOperatorReconciler) Reconcile(ctx context.Context,req reconcile.R
 			return ctrl.Result{}, nil
 		} else {
 			log.Logger.WithFields(logrus.Fields{
-				"secret":   req.Name,
+				"secret":    req.Name,
 				"namespace": req.Namespace,
 				"message":   "failed to reconcile secret",
 			}).Error(err)
@@ -56,40 +58,43 @@ func (s *SecretOperatorReconciler) Reconcile(ctx context.Context,req reconcile.R
 	}).Info("start to action")
 
 	{
-		//sync secret to all environment(fat|uat|sit)
+		//sync secret to all environment(fat|uat|sit), keys added later are synced on update
 		_, err = secretGeneratorService.Add(secret)
 		if err != nil {
 			log.Logger.WithFields(logrus.Fields{
 				"event":    "create",
-				"resource": "Service",
+				"resource": "Secret",
 				"name":     secret.Name,
 				"result":   "failed",
 				"error":    err.Error(),
-			}).Errorf("service sync to fat|uat|sit env failed, retry after %d second", RetryPeriod)
+			}).Errorf("secret sync to fat|uat|sit env failed, retry after %d second", RetryPeriod)
 			return reconcile.Result{
 				RequeueAfter: RetryPeriod * time.Second,
 			}, err
 		}
 		log.Logger.WithFields(logrus.Fields{
 			"event":    "create",
-			"resource": "Service",
+			"resource": "Secret",
 			"name":     secret.Name,
 			"result":   "success",
-		}).Infof("finish to sync service %s", secret.Name)
+		}).Infof("finish to sync secret %s", secret.Name)
 	}
 	log.Logger.WithFields(logrus.Fields{
-		"action": "PersistentVolume",
+		"action": secretAction,
 	}).Info("finish to action")
 	return reconcile.Result{}, nil
 }
 
 func (s *SecretOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(optionsOf(secretAction)).
 		For(&v1.Secret{}).
 		WithEventFilter(
-				&filters.NamespaceCreatePredicate{
-					IncludeNamespaces: filters.DefaultIncludeNamespaces,
-				},
+			predicate.And(
+				selectorOf(secretAction, filters.DefaultEnvironmentFilter),
+				&filters.EventPredicate{OnCreate: true, OnUpdate: true},
+				&filters.PropagationPredicate{},
+			),
 		).
 		Complete(s)
 }
@@ -97,9 +102,9 @@ func (s *SecretOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
 func SetUpSecretReconcile(mgr manager.Manager) {
 	if err := (&SecretOperatorReconciler{
 		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("ServiceToEnv"),
+		Log:    ctrl.Log.WithName(secretAction),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		log.Fatalf("unable to create service controller for ", err)
+		log.Fatalf("unable to create service controller: %v", err)
 	}
-}
\ No newline at end of file
+}
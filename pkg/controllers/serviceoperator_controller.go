@@ -11,12 +11,14 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"time"
 )
 
 func init() {
 	RegisterReconciler("ServiceToEnv", SetUpServiceReconcile)
+	RequireGenerators("ServiceToEnv", &serviceGeneratorService)
 }
 
 type ServiceOperatorReconciler struct {
@@ -72,8 +74,15 @@ func (s *ServiceOperatorReconciler) Reconcile(ctx context.Context, req reconcile
 
 func (s *ServiceOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(optionsOf("ServiceToEnv")).
 		For(&v1.Service{}).
-		WithEventFilter(&filters.NamespaceCreatePredicate{IncludeNamespaces: filters.DefaultIncludeNamespaces}).
+		WithEventFilter(
+			predicate.And(
+				selectorOf("ServiceToEnv", filters.DefaultEnvironmentFilter),
+				&filters.EventPredicate{OnCreate: true},
+				&filters.PropagationPredicate{},
+			),
+		).
 		Complete(s)
 }
 
@@ -83,6 +92,6 @@ func SetUpServiceReconcile(mgr manager.Manager) {
 		Log:    ctrl.Log.WithName("ServiceToEnv"),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		log.Fatalf("unable to create service controller for ", err)
+		log.Fatalf("unable to create service controller: %v", err)
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/go-logr/logr"
 	iamv1alpha2 "github.com/hchenc/iceberg/pkg/apis/iam/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/config"
 	"github.com/hchenc/iceberg/pkg/controllers/filters"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -11,12 +12,15 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"time"
 )
 
 func init() {
 	RegisterReconciler("UserToUser", SetUpUserReconcile)
+	RequireIntegrations("UserToUser", config.Integrations{SCM: true})
+	RequireGenerators("UserToUser", &userGeneratorService)
 }
 
 type UserOperatorReconciler struct {
@@ -89,10 +93,14 @@ func (u *UserOperatorReconciler) Reconcile(ctx context.Context, req reconcile.Re
 
 func (u *UserOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(optionsOf("UserToUser")).
 		For(&iamv1alpha2.User{}).
-		WithEventFilter(&filters.NameCreatePredicate{
-			ExcludeNames: filters.DefaultExcludeNames,
-		}).
+		WithEventFilter(
+			predicate.And(
+				selectorOf("UserToUser", filters.DefaultUserFilter),
+				&filters.EventPredicate{OnCreate: true},
+			),
+		).
 		Complete(u)
 }
 
@@ -102,6 +110,6 @@ func SetUpUserReconcile(mgr manager.Manager) {
 		Log:    ctrl.Log.WithName("UserToUser"),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		log.Fatalf("unable to create user controller for", err)
+		log.Fatalf("unable to create user controller: %v", err)
 	}
 }
@@ -19,6 +19,7 @@ import (
 
 func init() {
 	RegisterReconciler("PersistentVolume", SetUpVolumeReconcile)
+	RequireGenerators("PersistentVolume", &volumeGeneratorService)
 }
 
 type VolumeOperatorReconciler struct {
@@ -74,17 +75,19 @@ func (v VolumeOperatorReconciler) Reconcile(ctx context.Context, req reconcile.R
 
 func (v *VolumeOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(optionsOf("PersistentVolume")).
 		For(&v1.PersistentVolumeClaim{}).
 		WithEventFilter(
 			predicate.And(
-				&filters.NamespaceCreatePredicate{
-					IncludeNamespaces: filters.DefaultIncludeNamespaces,
+				selectorOf("PersistentVolume", filters.DefaultEnvironmentFilter),
+				&filters.EventPredicate{OnCreate: true},
+				&filters.PropagationPredicate{
+					Default: &filters.LabelCreatePredicate{
+						Force: false,
+						IncludeLabels: map[string]string{
+							constants.KubesphereAppName: "anything",
+						}},
 				},
-				&filters.LabelCreatePredicate{
-					Force: false,
-					IncludeLabels: map[string]string{
-						constants.KubesphereAppName: "anything",
-					}},
 			),
 		).
 		Complete(v)
@@ -96,6 +99,6 @@ func SetUpVolumeReconcile(mgr manager.Manager) {
 		Log:    ctrl.Log.WithName("PersistentVolume"),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		log.Fatalf("unable to create volume controller for", err)
+		log.Fatalf("unable to create volume controller: %v", err)
 	}
 }
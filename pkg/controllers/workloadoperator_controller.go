@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	"github.com/hchenc/iceberg/pkg/controllers/filters"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"time"
+)
+
+func init() {
+	RegisterReconciler("StatefulSetToEnv", newWorkloadReconcile("StatefulSetToEnv", "StatefulSet", func() client.Object {
+		return &appsv1.StatefulSet{}
+	}, func() syncer.GenerateService {
+		return statefulSetGeneratorService
+	}))
+	RegisterReconciler("DaemonSetToEnv", newWorkloadReconcile("DaemonSetToEnv", "DaemonSet", func() client.Object {
+		return &appsv1.DaemonSet{}
+	}, func() syncer.GenerateService {
+		return daemonSetGeneratorService
+	}))
+	RegisterReconciler("JobToEnv", newWorkloadReconcile("JobToEnv", "Job", func() client.Object {
+		return &batchv1.Job{}
+	}, func() syncer.GenerateService {
+		return jobGeneratorService
+	}))
+	RegisterReconciler("CronJobToEnv", newWorkloadReconcile("CronJobToEnv", "CronJob", func() client.Object {
+		return &batchv1.CronJob{}
+	}, func() syncer.GenerateService {
+		return cronJobGeneratorService
+	}))
+	RequireGenerators("StatefulSetToEnv", &statefulSetGeneratorService)
+	RequireGenerators("DaemonSetToEnv", &daemonSetGeneratorService)
+	RequireGenerators("JobToEnv", &jobGeneratorService)
+	RequireGenerators("CronJobToEnv", &cronJobGeneratorService)
+}
+
+// WorkloadOperatorReconciler syncs the workloads other than deployments to
+// all environments, one reconciler is set up per kind.
+type WorkloadOperatorReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	action    string
+	kind      string
+	newObject func() client.Object
+	service   func() syncer.GenerateService
+}
+
+func (w *WorkloadOperatorReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	obj := w.newObject()
+
+	err := w.Get(ctx, req.NamespacedName, obj)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			w.Log.Info("receive delete event")
+			return ctrl.Result{}, nil
+		} else {
+			log.Logger.WithFields(logrus.Fields{
+				"kind":      w.kind,
+				"name":      req.Name,
+				"namespace": req.Namespace,
+				"message":   "failed to reconcile workload",
+			}).Error(err)
+			return ctrl.Result{}, err
+		}
+	}
+
+	if obj.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+
+	log.Logger.WithFields(logrus.Fields{
+		"action": w.action,
+	}).Info("start to action")
+
+	{
+		//sync workload to all environment(fat|uat|sit)
+		_, err = w.service().Add(obj)
+		if err != nil {
+			log.Logger.WithFields(logrus.Fields{
+				"event":    "create",
+				"resource": w.kind,
+				"name":     obj.GetName(),
+				"result":   "failed",
+				"error":    err.Error(),
+			}).Errorf("%s sync to fat|uat|sit env failed, retry after %d second", w.kind, RetryPeriod)
+			return reconcile.Result{
+				RequeueAfter: RetryPeriod * time.Second,
+			}, err
+		}
+		log.Logger.WithFields(logrus.Fields{
+			"event":    "create",
+			"resource": w.kind,
+			"name":     obj.GetName(),
+			"result":   "success",
+		}).Infof("finish to sync %s %s", w.kind, obj.GetName())
+	}
+	log.Logger.WithFields(logrus.Fields{
+		"action": w.action,
+	}).Info("finish to action")
+	return reconcile.Result{}, nil
+}
+
+func (w *WorkloadOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(optionsOf(w.action)).
+		For(w.newObject()).
+		WithEventFilter(
+			predicate.And(
+				selectorOf(w.action, filters.DefaultEnvironmentFilter),
+				&filters.EventPredicate{OnCreate: true},
+				&filters.PropagationPredicate{},
+			),
+		).
+		Complete(w)
+}
+
+func newWorkloadReconcile(action, kind string, newObject func() client.Object, service func() syncer.GenerateService) Reconcile {
+	return func(mgr manager.Manager) {
+		if err := (&WorkloadOperatorReconciler{
+			Client:    mgr.GetClient(),
+			Log:       ctrl.Log.WithName(action),
+			Scheme:    mgr.GetScheme(),
+			action:    action,
+			kind:      kind,
+			newObject: newObject,
+			service:   service,
+		}).SetupWithManager(mgr); err != nil {
+			log.Fatalf("unable to create %s controller: %v", kind, err)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/go-logr/logr"
 	"github.com/hchenc/iceberg/pkg/apis/tenant/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/config"
 	"github.com/hchenc/iceberg/pkg/controllers/filters"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -22,6 +23,8 @@ var (
 
 func init() {
 	RegisterReconciler(action, SetUpGroupReconcile)
+	RequireIntegrations(action, config.Integrations{SCM: true})
+	RequireGenerators(action, &groupGeneratorService, &namespaceGeneratorService)
 }
 
 type WorkspaceOperatorReconciler struct {
@@ -93,20 +96,6 @@ func (g *WorkspaceOperatorReconciler) Reconcile(ctx context.Context, req reconci
 			}, err
 		}
 
-		// create harbor's project
-		_, err = harborGeneratorService.Add(workspaceTemplate)
-		if err != nil {
-			log.Logger.WithFields(logrus.Fields{
-				"event":    "create",
-				"resource": "Harbor",
-				"name":     workspaceTemplate.Name,
-				"result":   "failed",
-				"error":    err.Error(),
-			}).Errorf("harbor project created failed, retry after %d second", RetryPeriod)
-			return reconcile.Result{
-				RequeueAfter: RetryPeriod * time.Second,
-			}, err
-		}
 		log.Logger.WithFields(logrus.Fields{
 			"event":    "create",
 			"resource": "Workspace",
@@ -122,19 +111,14 @@ func (g *WorkspaceOperatorReconciler) Reconcile(ctx context.Context, req reconci
 
 func (g *WorkspaceOperatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(optionsOf(action)).
 		For(&v1alpha2.WorkspaceTemplate{}).
 		WithEventFilter(
-			predicate.Or(&filters.NameCreatePredicate{
-				ExcludeNames: []string{
-					"system",
-					"kube",
-				},
-			}, &filters.NameDeletePredicate{
-				ExcludeNames: []string{
-					"system",
-					"kube",
-				},
-			})).
+			predicate.And(
+				selectorOf(action, filters.DefaultWorkspaceFilter),
+				&filters.EventPredicate{OnCreate: true, OnDelete: true},
+			),
+		).
 		Complete(g)
 }
 
@@ -144,6 +128,6 @@ func SetUpGroupReconcile(mgr manager.Manager) {
 		Log:    ctrl.Log.WithName(action),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		log.Fatalf("unable to create workspace controller for", err)
+		log.Fatalf("unable to create workspace controller: %v", err)
 	}
 }
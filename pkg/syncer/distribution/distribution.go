@@ -0,0 +1,72 @@
+package distribution
+
+import (
+	"github.com/hchenc/iceberg/pkg/clients/clientset"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	quotaResource  = schema.GroupResource{Group: config.RegistryProviderDistribution, Resource: "quotas"}
+	memberResource = schema.GroupResource{Group: config.RegistryProviderDistribution, Resource: "members"}
+)
+
+// distributionRegistry syncs workspaces into a registry implementing the
+// docker distribution api, e.g. the reference registry or zot. Repositories
+// are created on push, so namespaces only exist as repository prefixes and
+// every namespace is pulled with the configured account.
+type distributionRegistry struct {
+	distributionClient *clientset.DistributionClient
+}
+
+func (d distributionRegistry) Provider() string {
+	return config.RegistryProviderDistribution
+}
+
+func (d distributionRegistry) Server() string {
+	return d.distributionClient.Server()
+}
+
+func (d distributionRegistry) CreateNamespace(namespace *syncer.RegistryNamespace) (*syncer.RegistryNamespace, error) {
+	return d.GetNamespace(namespace.Name)
+}
+
+// GetNamespace returns the repository prefix, which exists implicitly and
+// can't be owned by anyone else.
+func (d distributionRegistry) GetNamespace(name string) (*syncer.RegistryNamespace, error) {
+	return &syncer.RegistryNamespace{
+		Name:    name,
+		Managed: true,
+	}, nil
+}
+
+func (d distributionRegistry) MarkNamespace(name string) error {
+	return nil
+}
+
+// CreateRobot returns the configured account, the distribution api has no
+// per namespace credentials.
+func (d distributionRegistry) CreateRobot(namespace, name string) (*syncer.RegistryCredential, error) {
+	username, password := d.distributionClient.Credential()
+	return &syncer.RegistryCredential{
+		Server:   d.Server(),
+		Username: username,
+		Password: password,
+	}, nil
+}
+
+func (d distributionRegistry) SetQuota(namespace string, storageLimit int64) error {
+	return errors.NewMethodNotSupported(quotaResource, "update")
+}
+
+func (d distributionRegistry) AddMember(namespace, username string, level syncer.AccessLevel) error {
+	return errors.NewMethodNotSupported(memberResource, "create")
+}
+
+func NewDistributionRegistry(distributionClient *clientset.DistributionClient) syncer.Registry {
+	return &distributionRegistry{
+		distributionClient: distributionClient,
+	}
+}
@@ -0,0 +1,511 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	baseErr "errors"
+	"fmt"
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/hchenc/iceberg/pkg/apis/tenant/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/apis/types/v1beta1"
+	"github.com/hchenc/iceberg/pkg/clients/clientset"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"github.com/hchenc/iceberg/pkg/syncer/resource"
+	"github.com/hchenc/iceberg/pkg/utils"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ClusterPropagationOK     v1beta1.PropagationStatus = "ClusterPropagationOK"
+	ClusterPropagationFailed v1beta1.PropagationStatus = "ClusterPropagationFailed"
+	ClusterNotFound          v1beta1.PropagationStatus = "ClusterNotFound"
+	// ClusterOverrideFailed clusters are propagated without the overrides
+	// listed in their FailedOverrides
+	ClusterOverrideFailed v1beta1.PropagationStatus = "ClusterOverrideFailed"
+	// ClusterRemovalFailed clusters left the placement but still hold
+	// resources of the workspace, the removal is retried
+	ClusterRemovalFailed v1beta1.PropagationStatus = "ClusterRemovalFailed"
+
+	PropagationCondition v1beta1.ConditionType = "Propagation"
+
+	AggregateSuccess      v1beta1.AggregateReason = ""
+	CheckClusters         v1beta1.AggregateReason = "CheckClusters"
+	ClusterRetrievalError v1beta1.AggregateReason = "ClusterRetrievalError"
+)
+
+var namespaceResource = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+// namespacedResources are propagated from the environment namespaces of the
+// host, in order
+var namespacedResources = []schema.GroupVersionResource{
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"},
+	{Version: "v1", Resource: "configmaps"},
+	{Version: "v1", Resource: "secrets"},
+	{Version: "v1", Resource: "services"},
+	{Group: "apps", Version: "v1", Resource: "deployments"},
+	{Group: "apps", Version: "v1", Resource: "statefulsets"},
+	{Group: "apps", Version: "v1", Resource: "daemonsets"},
+	{Group: "batch", Version: "v1", Resource: "cronjobs"},
+	{Group: "batch", Version: "v1", Resource: "jobs"},
+	{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
+}
+
+// strippedFields are set by the host cluster and must not be propagated
+var strippedFields = [][]string{
+	{"metadata", "uid"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "managedFields"},
+	{"metadata", "ownerReferences"},
+	{"metadata", "selfLink"},
+	{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"},
+	{"metadata", "annotations", "deployment.kubernetes.io/revision"},
+	{"status"},
+	{"spec", "clusterIP"},
+	{"spec", "clusterIPs"},
+	{"spec", "healthCheckNodePort"},
+}
+
+// ClusterSelector selects the member clusters of a placement.
+type ClusterSelector interface {
+	Select(ctx context.Context, placement v1beta1.GenericPlacementFields) ([]*clientset.MemberCluster, []string, error)
+}
+
+type workspaceInfo struct {
+	hostClient dynamic.Interface
+	clusters   ClusterSelector
+	logger     *logrus.Logger
+	ctx        context.Context
+}
+
+// Create places the environments of the workspace in the member clusters of
+// its placement: the namespaces and the resources of the host environment
+// namespaces are created or updated in every cluster, with the overrides of
+// the cluster applied. Resources in member clusters not created by iceberg
+// are left untouched, the resources of the clusters which left the
+// placement since the last status are removed. The returned
+// *v1beta1.GenericFederatedStatus reports every cluster, it's returned along
+// with the error when a cluster fails.
+func (w workspaceInfo) Create(obj interface{}) (interface{}, error) {
+	workspace := obj.(*v1alpha2.WorkspaceTemplate)
+	wsLogInfo := logrus.Fields{
+		"workspace": workspace.Name,
+	}
+	status := &v1beta1.GenericFederatedStatus{
+		ObservedGeneration: workspace.Generation,
+	}
+
+	clusters, missing, err := w.clusters.Select(w.ctx, workspace.Spec.Placement)
+	if err != nil {
+		w.logger.WithFields(wsLogInfo).WithFields(logrus.Fields{
+			"message": "failed to select member clusters",
+		}).Error(err)
+		status.Conditions = condition(workspace, v1.ConditionFalse, ClusterRetrievalError, err.Error())
+		return status, err
+	}
+	placed := map[string]bool{}
+	for _, name := range missing {
+		w.logger.WithFields(wsLogInfo).WithFields(logrus.Fields{
+			"cluster": name,
+		}).Warn("kubeconfig secret of member cluster not found")
+		placed[name] = true
+		status.Clusters = append(status.Clusters, v1beta1.GenericClusterStatus{Name: name, Status: ClusterNotFound})
+	}
+
+	overrides := map[string][]v1beta1.ClusterOverride{}
+	for _, override := range workspace.Spec.Overrides {
+		overrides[override.ClusterName] = append(overrides[override.ClusterName], override.ClusterOverrides...)
+	}
+
+	var errs []error
+	for _, cluster := range clusters {
+		placed[cluster.Name] = true
+		failed, err := w.sync(workspace, cluster, overrides[cluster.Name])
+		switch {
+		case err != nil:
+			w.logger.WithFields(wsLogInfo).WithFields(logrus.Fields{
+				"cluster": cluster.Name,
+				"message": "failed to propagate workspace to member cluster",
+			}).Error(err)
+			errs = append(errs, err)
+			status.Clusters = append(status.Clusters, v1beta1.GenericClusterStatus{Name: cluster.Name, Status: ClusterPropagationFailed})
+		case len(failed) != 0:
+			w.logger.WithFields(wsLogInfo).WithFields(logrus.Fields{
+				"cluster":   cluster.Name,
+				"overrides": failed,
+			}).Warn("propagate workspace to member cluster without the overrides failed to apply")
+			status.Clusters = append(status.Clusters, v1beta1.GenericClusterStatus{Name: cluster.Name, Status: ClusterOverrideFailed, FailedOverrides: failed})
+		default:
+			w.logger.WithFields(wsLogInfo).WithFields(logrus.Fields{
+				"cluster": cluster.Name,
+			}).Info("finish to propagate workspace to member cluster")
+			status.Clusters = append(status.Clusters, v1beta1.GenericClusterStatus{Name: cluster.Name, Status: ClusterPropagationOK})
+		}
+	}
+
+	var departed []v1beta1.GenericClusterReference
+	for _, cluster := range lastStatus(workspace).Clusters {
+		if !placed[cluster.Name] {
+			departed = append(departed, v1beta1.GenericClusterReference{Name: cluster.Name})
+		}
+	}
+	if len(departed) != 0 {
+		failed, err := w.remove(workspace.Name, v1beta1.GenericPlacementFields{Clusters: departed})
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, name := range failed {
+			status.Clusters = append(status.Clusters, v1beta1.GenericClusterStatus{Name: name, Status: ClusterRemovalFailed})
+		}
+	}
+
+	var unhealthy []string
+	for _, cluster := range status.Clusters {
+		if cluster.Status != ClusterPropagationOK {
+			unhealthy = append(unhealthy, cluster.Name+": "+string(cluster.Status))
+		}
+	}
+	if len(unhealthy) != 0 {
+		status.Conditions = condition(workspace, v1.ConditionFalse, CheckClusters, strings.Join(unhealthy, ", "))
+	} else {
+		status.Conditions = condition(workspace, v1.ConditionTrue, AggregateSuccess, "")
+	}
+	if len(errs) != 0 {
+		return status, baseErr.New("failed to propagate workspace to member clusters")
+	}
+	w.logger.WithFields(wsLogInfo).Info("finish to sync federated workspace")
+	return status, nil
+}
+
+// sync propagates the environments of workspace to cluster, it returns the
+// overrides which failed to apply.
+func (w workspaceInfo) sync(workspace *v1alpha2.WorkspaceTemplate, cluster *clientset.MemberCluster, overrides []v1beta1.ClusterOverride) ([]string, error) {
+	// the environment namespaces of the workspace, whatever their names
+	namespaces, err := w.hostClient.Resource(namespaceResource).List(w.ctx, metav1.ListOptions{
+		LabelSelector: constants.KubesphereWorkspace + "=" + workspace.Name + "," + constants.IcebergEnvironment,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var failed []string
+	for index := range namespaces.Items {
+		namespace := &namespaces.Items[index]
+		namespaceFailed, err := w.apply(cluster, workspace.Name, namespaceResource, namespace, overrides)
+		if err != nil {
+			return nil, err
+		}
+		failed = append(failed, namespaceFailed...)
+
+		for _, resource := range namespacedResources {
+			list, err := w.hostClient.Resource(resource).Namespace(namespace.GetName()).List(w.ctx, metav1.ListOptions{})
+			if err != nil {
+				return nil, err
+			}
+			for index := range list.Items {
+				if skip(&list.Items[index]) {
+					continue
+				}
+				resourceFailed, err := w.apply(cluster, workspace.Name, resource, &list.Items[index], overrides)
+				if err != nil {
+					return nil, err
+				}
+				failed = append(failed, resourceFailed...)
+			}
+		}
+	}
+	return failed, nil
+}
+
+// apply creates or updates the copy of obj in cluster, it returns the
+// overrides which failed to apply.
+func (w workspaceInfo) apply(cluster *clientset.MemberCluster, workspace string, resource schema.GroupVersionResource, obj *unstructured.Unstructured, overrides []v1beta1.ClusterOverride) ([]string, error) {
+	copied, failed := prepare(workspace, obj, overrides)
+	client := cluster.Client.Resource(resource).Namespace(copied.GetNamespace())
+	_, err := client.Create(w.ctx, copied, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return failed, err
+	}
+
+	existing, err := client.Get(w.ctx, copied.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if existing.GetLabels()[constants.IcebergFederated] != "true" {
+		w.logger.WithFields(logrus.Fields{
+			"cluster":   cluster.Name,
+			"resource":  resource.Resource,
+			"name":      copied.GetName(),
+			"namespace": copied.GetNamespace(),
+		}).Info("resource not made by iceberg already exists in member cluster, skip it")
+		return nil, nil
+	}
+	copied.SetResourceVersion(existing.GetResourceVersion())
+	_, err = client.Update(w.ctx, copied, metav1.UpdateOptions{})
+	return failed, err
+}
+
+// remove deletes the resources of workspace from the clusters of placement,
+// it returns the clusters the removal failed in.
+func (w workspaceInfo) remove(workspace string, placement v1beta1.GenericPlacementFields) ([]string, error) {
+	clusters, missing, err := w.clusters.Select(w.ctx, placement)
+	if err != nil {
+		var failed []string
+		for _, cluster := range placement.Clusters {
+			failed = append(failed, cluster.Name)
+		}
+		return failed, err
+	}
+	for _, name := range missing {
+		w.logger.WithFields(logrus.Fields{
+			"workspace": workspace,
+			"cluster":   name,
+		}).Warn("kubeconfig secret of member cluster not found, its resources are left")
+	}
+	var failed []string
+	for _, cluster := range clusters {
+		if err := w.clean(cluster, workspace); err != nil {
+			w.logger.WithFields(logrus.Fields{
+				"workspace": workspace,
+				"cluster":   cluster.Name,
+				"message":   "failed to remove workspace from member cluster",
+			}).Error(err)
+			failed = append(failed, cluster.Name)
+			continue
+		}
+		w.logger.WithFields(logrus.Fields{
+			"workspace": workspace,
+			"cluster":   cluster.Name,
+		}).Info("finish to remove workspace from member cluster")
+	}
+	sort.Strings(failed)
+	if len(failed) != 0 {
+		return failed, fmt.Errorf("failed to remove workspace %s from member clusters %s", workspace, strings.Join(failed, ", "))
+	}
+	return nil, nil
+}
+
+// clean deletes the resources iceberg created for workspace in cluster by
+// their labels, namespaced resources go first as their namespace may not be
+// made by iceberg.
+func (w workspaceInfo) clean(cluster *clientset.MemberCluster, workspace string) error {
+	options := metav1.ListOptions{
+		LabelSelector: constants.IcebergFederated + "=true," + constants.IcebergFederatedWorkspace + "=" + workspace,
+	}
+	propagation := metav1.DeletePropagationBackground
+	resources := make([]schema.GroupVersionResource, 0, len(namespacedResources)+1)
+	for index := len(namespacedResources) - 1; index >= 0; index-- {
+		resources = append(resources, namespacedResources[index])
+	}
+	resources = append(resources, namespaceResource)
+	for _, resource := range resources {
+		list, err := cluster.Client.Resource(resource).List(w.ctx, options)
+		if err != nil {
+			return err
+		}
+		for index := range list.Items {
+			item := &list.Items[index]
+			err := cluster.Client.Resource(resource).Namespace(item.GetNamespace()).Delete(w.ctx, item.GetName(), metav1.DeleteOptions{
+				PropagationPolicy: &propagation,
+			})
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skip reports whether obj is managed by the host cluster itself, it shares
+// the exclusion list of the propagation between environments.
+func skip(obj *unstructured.Unstructured) bool {
+	if obj.GetKind() == "Secret" {
+		secret := &v1.Secret{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, secret); err == nil {
+			return resource.IsClusterManaged(secret)
+		}
+	}
+	return resource.IsClusterManaged(obj)
+}
+
+// prepare returns the copy of obj for a member cluster along with the
+// overrides which failed to apply to it. Overrides are applied to the
+// resources matching their kind and selector, an override which doesn't
+// apply to a matched resource, e.g. replacing /spec/replicas of a configmap,
+// is reported and the copy is kept without it.
+func prepare(workspace string, obj *unstructured.Unstructured, overrides []v1beta1.ClusterOverride) (*unstructured.Unstructured, []string) {
+	copied := obj.DeepCopy()
+	for _, field := range strippedFields {
+		unstructured.RemoveNestedField(copied.Object, field...)
+	}
+	if copied.GetKind() == "Service" {
+		if ports, found, _ := unstructured.NestedSlice(copied.Object, "spec", "ports"); found {
+			for _, port := range ports {
+				delete(port.(map[string]interface{}), "nodePort")
+			}
+			unstructured.SetNestedSlice(copied.Object, ports, "spec", "ports")
+		}
+	}
+	if copied.GetKind() == "Job" {
+		unstructured.RemoveNestedField(copied.Object, "spec", "selector")
+		for _, label := range []string{"controller-uid", "job-name", "batch.kubernetes.io/controller-uid", "batch.kubernetes.io/job-name"} {
+			unstructured.RemoveNestedField(copied.Object, "spec", "template", "metadata", "labels", label)
+		}
+	}
+	objLabels := copied.GetLabels()
+	if objLabels == nil {
+		objLabels = map[string]string{}
+	}
+	objLabels[constants.IcebergFederated] = "true"
+	objLabels[constants.IcebergFederatedWorkspace] = workspace
+	copied.SetLabels(objLabels)
+
+	var failed []string
+	for _, override := range overrides {
+		if len(override.Op) == 0 {
+			override.Op = "replace"
+		}
+		matched, err := matches(override, obj)
+		if err == nil && matched {
+			var patched *unstructured.Unstructured
+			if patched, err = patch(copied, override); err == nil {
+				copied = patched
+			}
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s %s: %v", reference(obj), override.Op, override.Path, err))
+		}
+	}
+	return copied, failed
+}
+
+// matches reports whether override is applied to obj.
+func matches(override v1beta1.ClusterOverride, obj *unstructured.Unstructured) (bool, error) {
+	if len(override.Kind) != 0 && override.Kind != obj.GetKind() {
+		return false, nil
+	}
+	if override.Selector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(override.Selector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(obj.GetLabels())), nil
+}
+
+func patch(obj *unstructured.Unstructured, override v1beta1.ClusterOverride) (*unstructured.Unstructured, error) {
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	document, err := json.Marshal([]map[string]interface{}{{
+		"op":    override.Op,
+		"path":  override.Path,
+		"value": override.Value,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	operations, err := jsonpatch.DecodePatch(document)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = operations.Apply(data); err != nil {
+		return nil, err
+	}
+	patched := &unstructured.Unstructured{}
+	if err := patched.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return patched, nil
+}
+
+// reference names obj in the failed overrides.
+func reference(obj *unstructured.Unstructured) string {
+	if len(obj.GetNamespace()) == 0 {
+		return obj.GetKind() + " " + obj.GetName()
+	}
+	return obj.GetKind() + " " + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// lastStatus returns the status recorded in the iceberg.io/federated-status
+// annotation of workspace, it's empty when the annotation is missing or
+// invalid.
+func lastStatus(workspace *v1alpha2.WorkspaceTemplate) *v1beta1.GenericFederatedStatus {
+	last := &v1beta1.GenericFederatedStatus{}
+	if err := json.Unmarshal([]byte(workspace.Annotations[constants.IcebergFederatedStatus]), last); err != nil {
+		return &v1beta1.GenericFederatedStatus{}
+	}
+	return last
+}
+
+// condition returns the propagation condition, the transition time is kept
+// from the status reported last when the condition status doesn't change.
+func condition(workspace *v1alpha2.WorkspaceTemplate, status v1.ConditionStatus, reason v1beta1.AggregateReason, message string) []*v1beta1.GenericCondition {
+	now := time.Now().UTC().Format(time.RFC3339)
+	condition := &v1beta1.GenericCondition{
+		Type:               PropagationCondition,
+		Status:             status,
+		LastUpdateTime:     now,
+		LastTransitionTime: now,
+		Reason:             reason,
+		Message:            message,
+	}
+	for _, lastCondition := range lastStatus(workspace).Conditions {
+		if lastCondition != nil && lastCondition.Type == PropagationCondition && lastCondition.Status == status {
+			condition.LastTransitionTime = lastCondition.LastTransitionTime
+		}
+	}
+	return []*v1beta1.GenericCondition{condition}
+}
+
+func (w workspaceInfo) Update(objOld interface{}, objNew interface{}) error {
+	_, err := w.Create(objNew)
+	return err
+}
+
+// Delete removes the resources of the workspace from every member cluster,
+// the clusters it was placed in are unknown once it's deleted.
+func (w workspaceInfo) Delete(name string) error {
+	_, err := w.remove(name, v1beta1.GenericPlacementFields{ClusterSelector: &metav1.LabelSelector{}})
+	return err
+}
+
+func (w workspaceInfo) GetByName(name string) (interface{}, error) {
+	panic("implement me")
+}
+
+func (w workspaceInfo) GetByID(id int) (interface{}, error) {
+	panic("implement me")
+}
+
+func (w workspaceInfo) List(key string) (interface{}, error) {
+	panic("implement me")
+}
+
+func NewWorkspaceGenerator(ctx context.Context, hostClient dynamic.Interface, clusters ClusterSelector) syncer.Generator {
+	logger := utils.GetLogger(logrus.Fields{
+		"component": "federation",
+		"resource":  "workspace",
+	})
+	return &workspaceInfo{
+		hostClient: hostClient,
+		clusters:   clusters,
+		logger:     logger,
+		ctx:        ctx,
+	}
+}
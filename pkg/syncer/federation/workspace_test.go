@@ -0,0 +1,251 @@
+package federation
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/apis/tenant/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/apis/types/v1beta1"
+	"github.com/hchenc/iceberg/pkg/clients/clientset"
+	"github.com/hchenc/iceberg/pkg/constants"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"strings"
+	"testing"
+)
+
+type fakeClusters struct {
+	clusters []*clientset.MemberCluster
+}
+
+func (f fakeClusters) Select(ctx context.Context, placement v1beta1.GenericPlacementFields) ([]*clientset.MemberCluster, []string, error) {
+	if placement.ClusterSelector != nil {
+		return f.clusters, nil, nil
+	}
+	var clusters []*clientset.MemberCluster
+	var missing []string
+	for _, reference := range placement.Clusters {
+		found := false
+		for _, cluster := range f.clusters {
+			if cluster.Name == reference.Name {
+				clusters = append(clusters, cluster)
+				found = true
+			}
+		}
+		if !found {
+			missing = append(missing, reference.Name)
+		}
+	}
+	return clusters, missing, nil
+}
+
+func newObject(apiVersion, kind, namespace, name string, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":            name,
+			"namespace":       namespace,
+			"uid":             "host-uid",
+			"resourceVersion": "7",
+		},
+	}}
+	for key, value := range fields {
+		obj.Object[key] = value
+	}
+	return obj
+}
+
+func newDynamicClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	listKinds := map[schema.GroupVersionResource]string{
+		namespaceResource: "NamespaceList",
+	}
+	for _, resource := range namespacedResources {
+		listKinds[resource] = "List"
+	}
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+}
+
+func TestWorkspaceGenerator(t *testing.T) {
+	ctx := context.Background()
+	namespace := newObject("v1", "Namespace", "", "devops-fat", nil)
+	namespace.SetLabels(map[string]string{constants.KubesphereWorkspace: "devops", constants.IcebergEnvironment: "fat"})
+	hostClient := newDynamicClient(
+		namespace,
+		newObject("apps/v1", "Deployment", "devops-fat", "web", map[string]interface{}{
+			"spec":   map[string]interface{}{"replicas": int64(3)},
+			"status": map[string]interface{}{"replicas": int64(3)},
+		}),
+		newObject("v1", "ConfigMap", "devops-fat", "kube-root-ca.crt", nil),
+		newObject("v1", "Secret", "devops-fat", "sh.helm.release.v1.web.v1", map[string]interface{}{
+			"type": "helm.sh/release.v1",
+		}),
+		newObject("v1", "ConfigMap", "devops-fat", "app", map[string]interface{}{
+			"data": map[string]interface{}{"LOG_LEVEL": "debug"},
+		}),
+		newObject("v1", "Service", "devops-fat", "web", map[string]interface{}{
+			"spec": map[string]interface{}{
+				"clusterIP": "10.0.0.1",
+				"ports":     []interface{}{map[string]interface{}{"port": int64(80), "nodePort": int64(30080)}},
+			},
+		}),
+	)
+	memberClient := newDynamicClient(newObject("v1", "ConfigMap", "devops-fat", "app", nil))
+	generator := NewWorkspaceGenerator(ctx, hostClient, fakeClusters{
+		clusters: []*clientset.MemberCluster{{Name: "member", Client: memberClient}},
+	})
+
+	workspace := &v1alpha2.WorkspaceTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "devops", Generation: 2},
+		Spec: v1beta1.FederatedWorkspaceSpec{
+			Placement: v1beta1.GenericPlacementFields{
+				Clusters: []v1beta1.GenericClusterReference{{Name: "member"}, {Name: "absent"}},
+			},
+			Overrides: []v1beta1.GenericOverrideItem{{
+				ClusterName: "member",
+				ClusterOverrides: []v1beta1.ClusterOverride{
+					{Path: "/spec/replicas", Value: runtime.RawExtension{Raw: []byte("1")}, Kind: "Deployment"},
+					{Path: "/spec/replicas", Value: runtime.RawExtension{Raw: []byte("2")}, Kind: "Deployment", Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"app": "api"},
+					}},
+				},
+			}},
+		},
+	}
+	obj, err := generator.Create(workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := obj.(*v1beta1.GenericFederatedStatus)
+	if status.ObservedGeneration != 2 || len(status.Clusters) != 2 || status.Conditions[0].Reason != CheckClusters {
+		t.Errorf("unexpected status %v", status)
+	}
+	for _, cluster := range status.Clusters {
+		if (cluster.Name == "member" && cluster.Status != ClusterPropagationOK) || (cluster.Name == "absent" && cluster.Status != ClusterNotFound) {
+			t.Errorf("unexpected cluster status %v", cluster)
+		}
+	}
+
+	deployment, err := memberClient.Resource(namespacedResources[5]).Namespace("devops-fat").Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicas, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas"); replicas != 1 {
+		t.Errorf("expected the cluster override to be applied, got %d replicas", replicas)
+	}
+	if _, found := deployment.Object["status"]; found || deployment.GetUID() == "host-uid" || deployment.GetLabels()[constants.IcebergFederated] != "true" || deployment.GetLabels()[constants.IcebergFederatedWorkspace] != "devops" {
+		t.Errorf("unexpected member deployment %v", deployment.Object)
+	}
+
+	service, err := memberClient.Resource(namespacedResources[4]).Namespace("devops-fat").Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ports, _, _ := unstructured.NestedSlice(service.Object, "spec", "ports")
+	if _, found, _ := unstructured.NestedString(service.Object, "spec", "clusterIP"); found || ports[0].(map[string]interface{})["nodePort"] != nil {
+		t.Errorf("expected cluster specific fields to be stripped, got %v", service.Object)
+	}
+
+	configMap, _ := memberClient.Resource(namespacedResources[2]).Namespace("devops-fat").Get(ctx, "app", metav1.GetOptions{})
+	if _, found := configMap.Object["data"]; found {
+		t.Errorf("expected the configmap of the member cluster to be kept, got %v", configMap.Object)
+	}
+	if _, err := memberClient.Resource(namespacedResources[2]).Namespace("devops-fat").Get(ctx, "kube-root-ca.crt", metav1.GetOptions{}); err == nil {
+		t.Error("expected kube-root-ca.crt not to be propagated")
+	}
+	if _, err := memberClient.Resource(namespacedResources[3]).Namespace("devops-fat").Get(ctx, "sh.helm.release.v1.web.v1", metav1.GetOptions{}); err == nil {
+		t.Error("expected the helm release secret not to be propagated")
+	}
+}
+
+func TestPrepareOverrides(t *testing.T) {
+	deployment := newObject("apps/v1", "Deployment", "devops-fat", "web", map[string]interface{}{
+		"spec": map[string]interface{}{"replicas": int64(3)},
+	})
+	configMap := newObject("v1", "ConfigMap", "devops-fat", "app", nil)
+	overrides := []v1beta1.ClusterOverride{
+		{Path: "/spec/replicas", Value: runtime.RawExtension{Raw: []byte("1")}},
+		{Op: "remove", Path: "/spec/paused", Kind: "Deployment"},
+	}
+
+	copied, failed := prepare("devops", deployment, overrides)
+	if replicas, _, _ := unstructured.NestedInt64(copied.Object, "spec", "replicas"); replicas != 1 {
+		t.Errorf("expected the override to be applied, got %d replicas", replicas)
+	}
+	if len(failed) != 1 || !strings.HasPrefix(failed[0], "Deployment devops-fat/web: remove /spec/paused: ") {
+		t.Errorf("expected the failed override to be reported, got %v", failed)
+	}
+
+	// the unscoped override is applied to every kind
+	if _, failed := prepare("devops", configMap, overrides); len(failed) != 1 || !strings.HasPrefix(failed[0], "ConfigMap devops-fat/app: replace /spec/replicas: ") {
+		t.Errorf("expected the failed override to be reported, got %v", failed)
+	}
+}
+
+func TestWorkspaceGeneratorRemove(t *testing.T) {
+	ctx := context.Background()
+	federated := func(obj *unstructured.Unstructured, workspace string) *unstructured.Unstructured {
+		obj.SetLabels(map[string]string{constants.IcebergFederated: "true", constants.IcebergFederatedWorkspace: workspace})
+		return obj
+	}
+	newMemberClient := func() *dynamicfake.FakeDynamicClient {
+		return newDynamicClient(
+			federated(newObject("v1", "Namespace", "", "devops-fat", nil), "devops"),
+			federated(newObject("v1", "ConfigMap", "devops-fat", "app", nil), "devops"),
+			federated(newObject("v1", "ConfigMap", "default", "app", nil), "devops"),
+			federated(newObject("v1", "ConfigMap", "default", "other", nil), "other"),
+			newObject("v1", "ConfigMap", "default", "local", nil),
+		)
+	}
+	assertRemoved := func(client *dynamicfake.FakeDynamicClient) {
+		t.Helper()
+		if _, err := client.Resource(namespaceResource).Get(ctx, "devops-fat", metav1.GetOptions{}); err == nil {
+			t.Error("expected the federated namespace to be removed")
+		}
+		for namespace, name := range map[string]string{"devops-fat": "app", "default": "app"} {
+			if _, err := client.Resource(namespacedResources[2]).Namespace(namespace).Get(ctx, name, metav1.GetOptions{}); err == nil {
+				t.Errorf("expected configmap %s/%s to be removed", namespace, name)
+			}
+		}
+		for _, name := range []string{"other", "local"} {
+			if _, err := client.Resource(namespacedResources[2]).Namespace("default").Get(ctx, name, metav1.GetOptions{}); err != nil {
+				t.Errorf("expected configmap %s to be kept, got %v", name, err)
+			}
+		}
+	}
+
+	left := newMemberClient()
+	kept := newDynamicClient()
+	generator := NewWorkspaceGenerator(ctx, newDynamicClient(), fakeClusters{
+		clusters: []*clientset.MemberCluster{{Name: "left", Client: left}, {Name: "kept", Client: kept}},
+	})
+	workspace := &v1alpha2.WorkspaceTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "devops", Annotations: map[string]string{
+			constants.IcebergFederatedStatus: `{"clusters":[{"name":"left","status":"ClusterPropagationOK"},{"name":"kept","status":"ClusterPropagationOK"}]}`,
+		}},
+		Spec: v1beta1.FederatedWorkspaceSpec{
+			Placement: v1beta1.GenericPlacementFields{
+				Clusters: []v1beta1.GenericClusterReference{{Name: "kept"}},
+			},
+		},
+	}
+	obj, err := generator.Create(workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := obj.(*v1beta1.GenericFederatedStatus)
+	if len(status.Clusters) != 1 || status.Clusters[0].Name != "kept" || status.Conditions[0].Status != "True" {
+		t.Errorf("unexpected status %v", status)
+	}
+	assertRemoved(left)
+
+	deleted := newMemberClient()
+	generator = NewWorkspaceGenerator(ctx, newDynamicClient(), fakeClusters{
+		clusters: []*clientset.MemberCluster{{Name: "deleted", Client: deleted}},
+	})
+	if err := generator.Delete("devops"); err != nil {
+		t.Fatal(err)
+	}
+	assertRemoved(deleted)
+}
@@ -0,0 +1,436 @@
+package gitea
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/clients/clientset"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	organizationResource = schema.GroupResource{Group: config.SCMProviderGitea, Resource: "organizations"}
+	repositoryResource   = schema.GroupResource{Group: config.SCMProviderGitea, Resource: "repositories"}
+	userResource         = schema.GroupResource{Group: config.SCMProviderGitea, Resource: "users"}
+	teamResource         = schema.GroupResource{Group: config.SCMProviderGitea, Resource: "teams"}
+	memberResource       = schema.GroupResource{Group: config.SCMProviderGitea, Resource: "members"}
+	fileResource         = schema.GroupResource{Group: config.SCMProviderGitea, Resource: "files"}
+)
+
+// gitea has no per member access level on organizations, group members are
+// added to an organization team granting the matching repository permission.
+var teams = map[syncer.AccessLevel]team{
+	syncer.DeveloperAccess:  {Name: "developers", Permission: "write"},
+	syncer.MaintainerAccess: {Name: "maintainers", Permission: "admin"},
+}
+
+var collaboratorPermissions = map[syncer.AccessLevel]string{
+	syncer.DeveloperAccess:  "write",
+	syncer.MaintainerAccess: "admin",
+}
+
+var teamUnits = []string{"repo.code", "repo.issues", "repo.pulls", "repo.releases", "repo.wiki", "repo.projects"}
+
+type organization struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	FullName    string `json:"full_name"`
+	Description string `json:"description"`
+}
+
+type repository struct {
+	ID            int      `json:"id"`
+	Name          string   `json:"name"`
+	FullName      string   `json:"full_name"`
+	Description   string   `json:"description"`
+	Owner         *user    `json:"owner"`
+	Topics        []string `json:"topics"`
+	Archived      bool     `json:"archived"`
+	DefaultBranch string   `json:"default_branch"`
+}
+
+type tree struct {
+	Entries []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	} `json:"tree"`
+	Truncated bool `json:"truncated"`
+}
+
+type contents struct {
+	Type    string `json:"type"`
+	SHA     string `json:"sha"`
+	Content string `json:"content"`
+}
+
+type user struct {
+	ID       int    `json:"id"`
+	Login    string `json:"login"`
+	FullName string `json:"full_name"`
+	Email    string `json:"email"`
+}
+
+type team struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Permission string `json:"permission"`
+}
+
+type giteaSCM struct {
+	giteaClient *clientset.GiteaClient
+}
+
+func (g giteaSCM) Provider() string {
+	return config.SCMProviderGitea
+}
+
+func (g giteaSCM) CreateGroup(group *syncer.SCMGroup) (*syncer.SCMGroup, error) {
+	if group.ParentID != 0 {
+		return nil, fmt.Errorf("gitea organizations can't be nested in %d", group.ParentID)
+	}
+	var created organization
+	err := g.giteaClient.Do(http.MethodPost, "/orgs", map[string]interface{}{
+		"username":    group.FullPath,
+		"full_name":   group.Name,
+		"description": group.Description,
+		"visibility":  "private",
+	}, &created)
+	if err != nil {
+		return nil, convertError(err, organizationResource, group.FullPath)
+	}
+	return toSCMGroup(&created), nil
+}
+
+func (g giteaSCM) GetGroup(fullPath string) (*syncer.SCMGroup, error) {
+	var org organization
+	if err := g.giteaClient.Do(http.MethodGet, "/orgs/"+escape(fullPath), nil, &org); err != nil {
+		return nil, convertError(err, organizationResource, fullPath)
+	}
+	return toSCMGroup(&org), nil
+}
+
+func (g giteaSCM) UpdateGroup(group *syncer.SCMGroup) error {
+	err := g.giteaClient.Do(http.MethodPatch, "/orgs/"+escape(group.FullPath), map[string]interface{}{
+		"description": group.Description,
+	}, nil)
+	return convertError(err, organizationResource, group.FullPath)
+}
+
+// CreateRepository creates the repository in the group's organization. When a
+// template is given as owner/repository the new repository is generated from
+// that gitea template repository. Gitea has no per repository pipeline
+// setting, so CIConfigPath is ignored.
+func (g giteaSCM) CreateRepository(repo *syncer.SCMRepository) (*syncer.SCMRepository, error) {
+	var created repository
+	var err error
+	if strings.Contains(repo.Template, "/") {
+		err = g.giteaClient.Do(http.MethodPost, "/repos/"+escape(repo.Template)+"/generate", map[string]interface{}{
+			"owner":       repo.GroupPath,
+			"name":        repo.Name,
+			"description": repo.Description,
+			"private":     true,
+			"git_content": true,
+		}, &created)
+	} else {
+		err = g.giteaClient.Do(http.MethodPost, "/orgs/"+escape(repo.GroupPath)+"/repos", map[string]interface{}{
+			"name":        repo.Name,
+			"description": repo.Description,
+			"private":     true,
+			"auto_init":   true,
+		}, &created)
+	}
+	if err != nil {
+		return nil, convertError(err, repositoryResource, repo.GroupPath+"/"+repo.Name)
+	}
+	if len(repo.Topics) != 0 {
+		err = g.giteaClient.Do(http.MethodPut, "/repos/"+escape(created.FullName)+"/topics", map[string]interface{}{
+			"topics": repo.Topics,
+		}, nil)
+		if err != nil {
+			return nil, convertError(err, repositoryResource, created.FullName)
+		}
+		created.Topics = repo.Topics
+	}
+	return toSCMRepository(&created), nil
+}
+
+// GetRepository looks the repository up by its full path, or by its ID when a
+// number is given.
+func (g giteaSCM) GetRepository(fullPath string) (*syncer.SCMRepository, error) {
+	var repo repository
+	path := "/repos/" + escape(fullPath)
+	if _, err := strconv.Atoi(fullPath); err == nil {
+		path = "/repositories/" + fullPath
+	}
+	if err := g.giteaClient.Do(http.MethodGet, path, nil, &repo); err != nil {
+		return nil, convertError(err, repositoryResource, fullPath)
+	}
+	return toSCMRepository(&repo), nil
+}
+
+func (g giteaSCM) UpdateRepository(repo *syncer.SCMRepository) error {
+	err := g.giteaClient.Do(http.MethodPatch, "/repos/"+escape(repo.FullPath), map[string]interface{}{
+		"description": repo.Description,
+	}, nil)
+	if err == nil && repo.Topics != nil {
+		err = g.giteaClient.Do(http.MethodPut, "/repos/"+escape(repo.FullPath)+"/topics", map[string]interface{}{
+			"topics": repo.Topics,
+		}, nil)
+	}
+	return convertError(err, repositoryResource, repo.FullPath)
+}
+
+func (g giteaSCM) TransferRepository(repo *syncer.SCMRepository, group *syncer.SCMGroup) (*syncer.SCMRepository, error) {
+	var transferred repository
+	err := g.giteaClient.Do(http.MethodPost, "/repos/"+escape(repo.FullPath)+"/transfer", map[string]interface{}{
+		"new_owner": group.FullPath,
+	}, &transferred)
+	if err != nil {
+		return nil, convertError(err, repositoryResource, repo.FullPath)
+	}
+	return toSCMRepository(&transferred), nil
+}
+
+func (g giteaSCM) SetArchived(repo *syncer.SCMRepository, archived bool) error {
+	err := g.giteaClient.Do(http.MethodPatch, "/repos/"+escape(repo.FullPath), map[string]interface{}{
+		"archived": archived,
+	}, nil)
+	return convertError(err, repositoryResource, repo.FullPath)
+}
+
+// SetCIConfigPath is a no-op, pipelines of gitea repositories are defined by
+// the repository content or the external ci system.
+func (g giteaSCM) SetCIConfigPath(repository *syncer.SCMRepository, ciConfigPath string) error {
+	return nil
+}
+
+func (g giteaSCM) ListFiles(repo *syncer.SCMRepository, ref string) ([]string, error) {
+	if len(ref) == 0 {
+		ref = repo.DefaultBranch
+	}
+	var files []string
+	for page := 1; ; page++ {
+		var entries tree
+		path := fmt.Sprintf("/repos/%s/git/trees/%s?recursive=true&page=%d", escape(repo.FullPath), url.PathEscape(ref), page)
+		if err := g.giteaClient.Do(http.MethodGet, path, nil, &entries); err != nil {
+			return nil, convertError(err, repositoryResource, repo.FullPath)
+		}
+		for _, entry := range entries.Entries {
+			if entry.Type == "blob" {
+				files = append(files, entry.Path)
+			}
+		}
+		if !entries.Truncated || len(entries.Entries) == 0 {
+			return files, nil
+		}
+	}
+}
+
+func (g giteaSCM) GetFile(repo *syncer.SCMRepository, filePath, ref string) ([]byte, error) {
+	file, err := g.getContents(repo, filePath, ref)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(file.Content)
+}
+
+// CommitFiles commits the files one by one, gitea's contents api changes a
+// single file per commit.
+func (g giteaSCM) CommitFiles(repo *syncer.SCMRepository, message string, files []*syncer.SCMFile) error {
+	for _, file := range files {
+		body := map[string]interface{}{
+			"branch":  repo.DefaultBranch,
+			"message": message,
+			"content": base64.StdEncoding.EncodeToString(file.Content),
+		}
+		method := http.MethodPost
+		existing, err := g.getContents(repo, file.Path, repo.DefaultBranch)
+		if err == nil {
+			method = http.MethodPut
+			body["sha"] = existing.SHA
+		} else if !errors.IsNotFound(err) {
+			return err
+		}
+		err = g.giteaClient.Do(method, "/repos/"+escape(repo.FullPath)+"/contents/"+escape(file.Path), body, nil)
+		if err != nil {
+			return convertError(err, fileResource, repo.FullPath+"/"+file.Path)
+		}
+	}
+	return nil
+}
+
+func (g giteaSCM) getContents(repo *syncer.SCMRepository, filePath, ref string) (*contents, error) {
+	path := "/repos/" + escape(repo.FullPath) + "/contents/" + escape(filePath)
+	if len(ref) != 0 {
+		path += "?ref=" + url.QueryEscape(ref)
+	}
+	var file contents
+	if err := g.giteaClient.Do(http.MethodGet, path, nil, &file); err != nil {
+		return nil, convertError(err, fileResource, repo.FullPath+"/"+filePath)
+	}
+	if file.Type != "file" {
+		return nil, errors.NewNotFound(fileResource, repo.FullPath+"/"+filePath)
+	}
+	return &file, nil
+}
+
+// CreateUser creates the user with a random password which must be changed on
+// first login, the user is expected to reset it by mail.
+func (g giteaSCM) CreateUser(u *syncer.SCMUser) (*syncer.SCMUser, error) {
+	password, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+	var created user
+	err = g.giteaClient.Do(http.MethodPost, "/admin/users", map[string]interface{}{
+		"username":             u.Username,
+		"full_name":            u.Name,
+		"email":                u.Email,
+		"password":             password,
+		"must_change_password": true,
+		"send_notify":          true,
+	}, &created)
+	if err != nil {
+		return nil, convertError(err, userResource, u.Username)
+	}
+	return toSCMUser(&created), nil
+}
+
+func (g giteaSCM) GetUser(username string) (*syncer.SCMUser, error) {
+	var u user
+	if err := g.giteaClient.Do(http.MethodGet, "/users/"+escape(username), nil, &u); err != nil {
+		return nil, convertError(err, userResource, username)
+	}
+	return toSCMUser(&u), nil
+}
+
+func (g giteaSCM) AddGroupMember(group *syncer.SCMGroup, u *syncer.SCMUser, level syncer.AccessLevel) error {
+	t, err := g.ensureTeam(group.FullPath, teams[level])
+	if err != nil {
+		return err
+	}
+	err = g.giteaClient.Do(http.MethodPut, fmt.Sprintf("/teams/%d/members/%s", t.ID, escape(u.Username)), nil, nil)
+	return convertError(err, memberResource, u.Username)
+}
+
+func (g giteaSCM) AddRepositoryMember(repo *syncer.SCMRepository, u *syncer.SCMUser, level syncer.AccessLevel) error {
+	err := g.giteaClient.Do(http.MethodPut, "/repos/"+escape(repo.FullPath)+"/collaborators/"+escape(u.Username), map[string]interface{}{
+		"permission": collaboratorPermissions[level],
+	}, nil)
+	return convertError(err, memberResource, u.Username)
+}
+
+// ensureTeam returns the organization's team with the expected name, the team
+// is created with access to all repositories when missing.
+func (g giteaSCM) ensureTeam(org string, expected team) (*team, error) {
+	var existing []team
+	if err := g.giteaClient.Do(http.MethodGet, "/orgs/"+escape(org)+"/teams", nil, &existing); err != nil {
+		return nil, convertError(err, teamResource, org)
+	}
+	for i := range existing {
+		if existing[i].Name == expected.Name {
+			return &existing[i], nil
+		}
+	}
+	var created team
+	err := g.giteaClient.Do(http.MethodPost, "/orgs/"+escape(org)+"/teams", map[string]interface{}{
+		"name":                      expected.Name,
+		"permission":                expected.Permission,
+		"includes_all_repositories": true,
+		"can_create_org_repo":       false,
+		"units":                     teamUnits,
+	}, &created)
+	if err != nil {
+		return nil, convertError(err, teamResource, org+"/"+expected.Name)
+	}
+	return &created, nil
+}
+
+// convertError turns gitea's error responses into kubernetes status errors,
+// gitea reports taken names with either 409 or 422 depending on the resource.
+func convertError(err error, resource schema.GroupResource, name string) error {
+	if err == nil {
+		return nil
+	}
+	apiErr, ok := err.(*clientset.APIError)
+	if !ok {
+		return err
+	}
+	switch apiErr.StatusCode {
+	case http.StatusNotFound:
+		return errors.NewNotFound(resource, name)
+	case http.StatusConflict, http.StatusUnprocessableEntity:
+		return errors.NewAlreadyExists(resource, name)
+	}
+	return err
+}
+
+// escape escapes every segment of a slash separated path.
+func escape(path string) string {
+	segments := strings.Split(path, "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	return strings.Join(segments, "/")
+}
+
+func randomPassword() (string, error) {
+	data := make([]byte, 24)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+func toSCMGroup(org *organization) *syncer.SCMGroup {
+	name := org.FullName
+	if len(name) == 0 {
+		name = org.Username
+	}
+	return &syncer.SCMGroup{
+		ID:          org.ID,
+		Name:        name,
+		FullPath:    org.Username,
+		Description: org.Description,
+	}
+}
+
+func toSCMRepository(repo *repository) *syncer.SCMRepository {
+	scmRepository := &syncer.SCMRepository{
+		ID:            repo.ID,
+		Name:          repo.Name,
+		FullPath:      repo.FullName,
+		Description:   repo.Description,
+		Topics:        repo.Topics,
+		Archived:      repo.Archived,
+		DefaultBranch: repo.DefaultBranch,
+	}
+	if repo.Owner != nil {
+		scmRepository.GroupID = repo.Owner.ID
+		scmRepository.GroupPath = repo.Owner.Login
+	}
+	return scmRepository
+}
+
+func toSCMUser(u *user) *syncer.SCMUser {
+	return &syncer.SCMUser{
+		ID:       u.ID,
+		Username: u.Login,
+		Name:     u.FullName,
+		Email:    u.Email,
+	}
+}
+
+func NewGiteaSCM(giteaClient *clientset.GiteaClient) syncer.SCM {
+	return &giteaSCM{
+		giteaClient: giteaClient,
+	}
+}
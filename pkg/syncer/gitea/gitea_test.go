@@ -0,0 +1,183 @@
+package gitea
+
+import (
+	"encoding/json"
+	"github.com/hchenc/iceberg/pkg/clients/clientset"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// fakeGitea serves the subset of gitea's api used by giteaSCM from memory.
+func fakeGitea(t *testing.T) (*httptest.Server, map[string][]string) {
+	orgs := map[string]organization{}
+	teamMembers := map[string][]string{}
+	var orgTeams []team
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/orgs", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		name := body["username"].(string)
+		if _, exists := orgs[name]; exists {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"message":"user already exists"}`))
+			return
+		}
+		orgs[name] = organization{ID: len(orgs) + 1, Username: name, FullName: body["full_name"].(string)}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(orgs[name])
+	})
+	mux.HandleFunc("/api/v1/orgs/devops", func(w http.ResponseWriter, r *http.Request) {
+		org, exists := orgs["devops"]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(org)
+	})
+	mux.HandleFunc("/api/v1/orgs/devops/teams", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var created team
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			created.ID = len(orgTeams) + 10
+			orgTeams = append(orgTeams, created)
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(created)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(orgTeams)
+	})
+	mux.HandleFunc("/api/v1/teams/10/members/", func(w http.ResponseWriter, r *http.Request) {
+		teamMembers["10"] = append(teamMembers["10"], r.URL.Path[len("/api/v1/teams/10/members/"):])
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, teamMembers
+}
+
+func newTestSCM(t *testing.T, server *httptest.Server) syncer.SCM {
+	u, _ := url.Parse(server.URL)
+	client, err := clientset.NewGiteaClient(&config.GiteaOptions{
+		Host:  u.Hostname(),
+		Port:  u.Port(),
+		Token: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewGiteaSCM(client)
+}
+
+func TestGiteaGroup(t *testing.T) {
+	server, teamMembers := fakeGitea(t)
+	scm := newTestSCM(t, server)
+
+	if _, err := scm.GetGroup("devops"); !errors.IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	group, err := scm.CreateGroup(&syncer.SCMGroup{Name: "devops", FullPath: "devops"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.ID != 1 || group.FullPath != "devops" {
+		t.Fatalf("unexpected group %+v", group)
+	}
+	if _, err := scm.CreateGroup(&syncer.SCMGroup{Name: "devops", FullPath: "devops"}); !errors.IsAlreadyExists(err) {
+		t.Fatalf("expected already exists, got %v", err)
+	}
+	if _, err := scm.GetGroup("devops"); err != nil {
+		t.Fatal(err)
+	}
+
+	user := &syncer.SCMUser{ID: 3, Username: "alice"}
+	for i := 0; i < 2; i++ {
+		if err := scm.AddGroupMember(group, user, syncer.DeveloperAccess); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if members := teamMembers["10"]; len(members) != 2 || members[0] != "alice" {
+		t.Fatalf("unexpected team members %v", members)
+	}
+}
+
+func TestGiteaRepository(t *testing.T) {
+	repos := map[string]*repository{
+		"devops/web": {ID: 5, Name: "web", FullName: "devops/web", Owner: &user{ID: 1, Login: "devops"}},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repositories/5", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(repos["devops/web"])
+	})
+	mux.HandleFunc("/api/v1/repos/devops/web", func(w http.ResponseWriter, r *http.Request) {
+		repo := repos["devops/web"]
+		if r.Method == http.MethodPatch {
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if description, ok := body["description"].(string); ok {
+				repo.Description = description
+			}
+			if archived, ok := body["archived"].(bool); ok {
+				repo.Archived = archived
+			}
+		}
+		_ = json.NewEncoder(w).Encode(repo)
+	})
+	mux.HandleFunc("/api/v1/repos/devops/web/topics", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Topics []string `json:"topics"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		repos["devops/web"].Topics = body.Topics
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/api/v1/repos/devops/web/transfer", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		repo := repos["devops/web"]
+		repo.Owner = &user{ID: 2, Login: body["new_owner"]}
+		repo.FullName = body["new_owner"] + "/web"
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(repo)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	scm := newTestSCM(t, server)
+
+	repo, err := scm.GetRepository("5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.FullPath != "devops/web" || repo.GroupID != 1 {
+		t.Fatalf("unexpected repository %+v", repo)
+	}
+
+	repo.Description = "web [managed by iceberg]"
+	repo.Topics = []string{"go", "web"}
+	if err := scm.UpdateRepository(repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := scm.SetArchived(repo, true); err != nil {
+		t.Fatal(err)
+	}
+	if updated := repos["devops/web"]; updated.Description != repo.Description || len(updated.Topics) != 2 || !updated.Archived {
+		t.Fatalf("unexpected updated repository %+v", updated)
+	}
+
+	transferred, err := scm.TransferRepository(repo, &syncer.SCMGroup{ID: 2, FullPath: "platform"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transferred.FullPath != "platform/web" || transferred.GroupID != 2 {
+		t.Fatalf("unexpected transferred repository %+v", transferred)
+	}
+}
@@ -0,0 +1,376 @@
+package gitlab
+
+import (
+	"encoding/base64"
+	"github.com/hchenc/iceberg/pkg/clients/clientset"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	git "github.com/xanzy/go-gitlab"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"net/http"
+	"path"
+	"strings"
+)
+
+var (
+	groupResource   = schema.GroupResource{Group: config.SCMProviderGitlab, Resource: "groups"}
+	projectResource = schema.GroupResource{Group: config.SCMProviderGitlab, Resource: "projects"}
+	userResource    = schema.GroupResource{Group: config.SCMProviderGitlab, Resource: "users"}
+	memberResource  = schema.GroupResource{Group: config.SCMProviderGitlab, Resource: "members"}
+	fileResource    = schema.GroupResource{Group: config.SCMProviderGitlab, Resource: "files"}
+)
+
+var accessLevels = map[syncer.AccessLevel]git.AccessLevelValue{
+	syncer.DeveloperAccess:  git.DeveloperPermissions,
+	syncer.MaintainerAccess: git.MaintainerPermissions,
+}
+
+type gitlabSCM struct {
+	gitlabClient *clientset.GitlabClient
+	lookup       *lookup
+}
+
+func (g gitlabSCM) Provider() string {
+	return config.SCMProviderGitlab
+}
+
+// CreateGroup creates the group, as a subgroup of ParentID if it's set. The
+// path of the group is the last segment of its full path.
+func (g gitlabSCM) CreateGroup(group *syncer.SCMGroup) (*syncer.SCMGroup, error) {
+	options := &git.CreateGroupOptions{
+		Name:                  git.String(group.Name),
+		Path:                  git.String(path.Base(group.FullPath)),
+		Description:           git.String(group.Description),
+		MembershipLock:        git.Bool(false),
+		Visibility:            git.Visibility(git.PrivateVisibility),
+		ShareWithGroupLock:    git.Bool(false),
+		RequireTwoFactorAuth:  git.Bool(false),
+		ProjectCreationLevel:  git.ProjectCreationLevel(git.DeveloperProjectCreation),
+		AutoDevopsEnabled:     git.Bool(false),
+		SubGroupCreationLevel: git.SubGroupCreationLevel(git.MaintainerSubGroupCreationLevelValue),
+		EmailsDisabled:        git.Bool(false),
+		MentionsDisabled:      git.Bool(false),
+	}
+	if group.ParentID != 0 {
+		options.ParentID = git.Int(group.ParentID)
+	}
+	created, _, err := g.gitlabClient.Client().Groups.CreateGroup(options)
+	if err != nil {
+		return nil, convertError(err, groupResource, group.FullPath)
+	}
+	g.lookup.storeGroup(created)
+	return toSCMGroup(created), nil
+}
+
+// GetGroup looks the group up by its full path, or by its ID when a number is
+// given.
+func (g gitlabSCM) GetGroup(fullPath string) (*syncer.SCMGroup, error) {
+	group, err := g.lookup.Group(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	return toSCMGroup(group), nil
+}
+
+func (g gitlabSCM) UpdateGroup(group *syncer.SCMGroup) error {
+	updated, _, err := g.gitlabClient.Client().Groups.UpdateGroup(group.ID, &git.UpdateGroupOptions{
+		Description: git.String(group.Description),
+	})
+	if err != nil {
+		return convertError(err, groupResource, group.FullPath)
+	}
+	g.lookup.storeGroup(updated)
+	return nil
+}
+
+func (g gitlabSCM) CreateRepository(repository *syncer.SCMRepository) (*syncer.SCMRepository, error) {
+	options := &git.CreateProjectOptions{
+		Name:                             git.String(repository.Name),
+		Path:                             git.String(repository.Name),
+		NamespaceID:                      git.Int(repository.GroupID),
+		Description:                      git.String(repository.Description),
+		SharedRunnersEnabled:             git.Bool(true),
+		Visibility:                       git.Visibility(git.PrivateVisibility),
+		OnlyAllowMergeIfPipelineSucceeds: git.Bool(true),
+		RemoveSourceBranchAfterMerge:     git.Bool(false),
+		RequestAccessEnabled:             git.Bool(true),
+		CIConfigPath:                     git.String(repository.CIConfigPath),
+		AutoDevopsEnabled:                git.Bool(false),
+		InitializeWithReadme:             git.Bool(true),
+		IssuesEnabled:                    git.Bool(true),
+		MergeRequestsEnabled:             git.Bool(true),
+	}
+	if repository.Topics != nil {
+		options.TagList = &repository.Topics
+	}
+	if len(repository.Template) != 0 {
+		options.TemplateName = git.String(repository.Template)
+		options.UseCustomTemplate = git.Bool(true)
+	}
+	project, _, err := g.gitlabClient.Client().Projects.CreateProject(options)
+	if err != nil {
+		return nil, convertError(err, projectResource, repository.Name)
+	}
+	g.lookup.storeProject(project)
+	return toSCMRepository(project), nil
+}
+
+// GetRepository looks the project up by its full path, or by its ID when a
+// number is given.
+func (g gitlabSCM) GetRepository(fullPath string) (*syncer.SCMRepository, error) {
+	project, err := g.lookup.Project(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	return toSCMRepository(project), nil
+}
+
+func (g gitlabSCM) UpdateRepository(repository *syncer.SCMRepository) error {
+	options := &git.EditProjectOptions{
+		Description: git.String(repository.Description),
+	}
+	if repository.Topics != nil {
+		options.TagList = &repository.Topics
+	}
+	return g.editProject(repository, options)
+}
+
+func (g gitlabSCM) TransferRepository(repository *syncer.SCMRepository, group *syncer.SCMGroup) (*syncer.SCMRepository, error) {
+	project, _, err := g.gitlabClient.Client().Projects.TransferProject(repository.ID, &git.TransferProjectOptions{
+		Namespace: group.ID,
+	})
+	if err != nil {
+		return nil, g.projectError(err, repository)
+	}
+	g.lookup.forgetProject(repository.ID, repository.FullPath)
+	g.lookup.storeProject(project)
+	return toSCMRepository(project), nil
+}
+
+func (g gitlabSCM) SetArchived(repository *syncer.SCMRepository, archived bool) error {
+	archive := g.gitlabClient.Client().Projects.UnarchiveProject
+	if archived {
+		archive = g.gitlabClient.Client().Projects.ArchiveProject
+	}
+	project, _, err := archive(repository.ID)
+	if err != nil {
+		return g.projectError(err, repository)
+	}
+	g.lookup.forgetProject(repository.ID, repository.FullPath)
+	g.lookup.storeProject(project)
+	return nil
+}
+
+func (g gitlabSCM) SetCIConfigPath(repository *syncer.SCMRepository, ciConfigPath string) error {
+	return g.editProject(repository, &git.EditProjectOptions{
+		CIConfigPath: git.String(ciConfigPath),
+	})
+}
+
+func (g gitlabSCM) editProject(repository *syncer.SCMRepository, options *git.EditProjectOptions) error {
+	project, _, err := g.gitlabClient.Client().Projects.EditProject(repository.ID, options)
+	if err != nil {
+		return g.projectError(err, repository)
+	}
+	g.lookup.forgetProject(repository.ID, repository.FullPath)
+	g.lookup.storeProject(project)
+	return nil
+}
+
+// projectError converts err and forgets the cached project when gitlab no
+// longer knows it, a deleted project would be served from the cache otherwise.
+func (g gitlabSCM) projectError(err error, repository *syncer.SCMRepository) error {
+	err = convertError(err, projectResource, repository.FullPath)
+	if errors.IsNotFound(err) {
+		g.lookup.forgetProject(repository.ID, repository.FullPath)
+	}
+	return err
+}
+
+func (g gitlabSCM) ListFiles(repository *syncer.SCMRepository, ref string) ([]string, error) {
+	options := &git.ListTreeOptions{
+		ListOptions: git.ListOptions{PerPage: lookupPageSize, Page: 1},
+		Recursive:   git.Bool(true),
+	}
+	if len(ref) != 0 {
+		options.Ref = git.String(ref)
+	}
+	var files []string
+	for {
+		nodes, resp, err := g.gitlabClient.Client().Repositories.ListTree(repository.ID, options)
+		if err != nil {
+			return nil, convertError(err, projectResource, repository.FullPath)
+		}
+		for _, node := range nodes {
+			if node.Type == "blob" {
+				files = append(files, node.Path)
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			return files, nil
+		}
+		options.Page = resp.NextPage
+	}
+}
+
+func (g gitlabSCM) GetFile(repository *syncer.SCMRepository, filePath, ref string) ([]byte, error) {
+	if len(ref) == 0 {
+		ref = repository.DefaultBranch
+	}
+	content, _, err := g.gitlabClient.Client().RepositoryFiles.GetRawFile(repository.ID, filePath, &git.GetRawFileOptions{
+		Ref: git.String(ref),
+	})
+	if err != nil {
+		return nil, convertError(err, fileResource, repository.FullPath+"/"+filePath)
+	}
+	return content, nil
+}
+
+// CommitFiles commits all files at once, files already on the branch are
+// updated and the others created.
+func (g gitlabSCM) CommitFiles(repository *syncer.SCMRepository, message string, files []*syncer.SCMFile) error {
+	existing := map[string]bool{}
+	paths, err := g.ListFiles(repository, repository.DefaultBranch)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	for _, filePath := range paths {
+		existing[filePath] = true
+	}
+
+	actions := make([]*git.CommitActionOptions, 0, len(files))
+	for _, file := range files {
+		action := git.FileCreate
+		if existing[file.Path] {
+			action = git.FileUpdate
+		}
+		actions = append(actions, &git.CommitActionOptions{
+			Action:   git.FileAction(action),
+			FilePath: git.String(file.Path),
+			Content:  git.String(base64.StdEncoding.EncodeToString(file.Content)),
+			Encoding: git.String("base64"),
+		})
+	}
+	_, _, err = g.gitlabClient.Client().Commits.CreateCommit(repository.ID, &git.CreateCommitOptions{
+		Branch:        git.String(repository.DefaultBranch),
+		CommitMessage: git.String(message),
+		Actions:       actions,
+	})
+	return convertError(err, projectResource, repository.FullPath)
+}
+
+func (g gitlabSCM) CreateUser(user *syncer.SCMUser) (*syncer.SCMUser, error) {
+	created, _, err := g.gitlabClient.Client().Users.CreateUser(&git.CreateUserOptions{
+		Email:          git.String(user.Email),
+		ResetPassword:  git.Bool(true),
+		Username:       git.String(user.Username),
+		Name:           git.String(user.Name),
+		CanCreateGroup: git.Bool(false),
+	})
+	if err != nil {
+		return nil, convertError(err, userResource, user.Username)
+	}
+	g.lookup.storeUser(created)
+	return toSCMUser(created), nil
+}
+
+// GetUser looks the user up by its username, an AmbiguousError is returned
+// when the username matches several users.
+func (g gitlabSCM) GetUser(username string) (*syncer.SCMUser, error) {
+	user, err := g.lookup.User(username)
+	if err != nil {
+		return nil, err
+	}
+	return toSCMUser(user), nil
+}
+
+func (g gitlabSCM) AddGroupMember(group *syncer.SCMGroup, user *syncer.SCMUser, level syncer.AccessLevel) error {
+	_, _, err := g.gitlabClient.Client().GroupMembers.AddGroupMember(group.ID, &git.AddGroupMemberOptions{
+		UserID:      git.Int(user.ID),
+		AccessLevel: git.AccessLevel(accessLevels[level]),
+	})
+	if err = convertError(err, memberResource, user.Username); errors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+func (g gitlabSCM) AddRepositoryMember(repository *syncer.SCMRepository, user *syncer.SCMUser, level syncer.AccessLevel) error {
+	_, _, err := g.gitlabClient.Client().ProjectMembers.AddProjectMember(repository.ID, &git.AddProjectMemberOptions{
+		UserID:      user.ID,
+		AccessLevel: git.AccessLevel(accessLevels[level]),
+	})
+	if err = convertError(err, memberResource, user.Username); errors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// convertError turns gitlab's error responses into kubernetes status errors,
+// gitlab reports taken names with either 409 or 400 depending on the resource.
+func convertError(err error, resource schema.GroupResource, name string) error {
+	if err == nil {
+		return nil
+	}
+	errResp, ok := err.(*git.ErrorResponse)
+	if !ok || errResp.Response == nil {
+		return err
+	}
+	switch code := errResp.Response.StatusCode; {
+	case code == http.StatusNotFound:
+		return errors.NewNotFound(resource, name)
+	case code == http.StatusConflict,
+		code == http.StatusBadRequest && strings.Contains(errResp.Message, "has already been taken"):
+		return errors.NewAlreadyExists(resource, name)
+	}
+	return err
+}
+
+func toSCMGroup(group *git.Group) *syncer.SCMGroup {
+	fullPath := group.FullPath
+	if len(fullPath) == 0 {
+		fullPath = group.Path
+	}
+	return &syncer.SCMGroup{
+		ID:          group.ID,
+		Name:        group.Name,
+		FullPath:    fullPath,
+		Description: group.Description,
+		ParentID:    group.ParentID,
+	}
+}
+
+func toSCMRepository(project *git.Project) *syncer.SCMRepository {
+	repository := &syncer.SCMRepository{
+		ID:            project.ID,
+		Name:          project.Name,
+		FullPath:      project.PathWithNamespace,
+		Description:   project.Description,
+		CIConfigPath:  project.CIConfigPath,
+		Topics:        project.TagList,
+		Archived:      project.Archived,
+		DefaultBranch: project.DefaultBranch,
+	}
+	if project.Namespace != nil {
+		repository.GroupID = project.Namespace.ID
+		repository.GroupPath = project.Namespace.FullPath
+	}
+	return repository
+}
+
+func toSCMUser(user *git.User) *syncer.SCMUser {
+	return &syncer.SCMUser{
+		ID:       user.ID,
+		Username: user.Username,
+		Name:     user.Name,
+		Email:    user.Email,
+	}
+}
+
+func NewGitlabSCM(gitlabClient *clientset.GitlabClient) syncer.SCM {
+	return &gitlabSCM{
+		gitlabClient: gitlabClient,
+		lookup:       newLookup(gitlabClient),
+	}
+}
@@ -0,0 +1,193 @@
+package gitlab
+
+import (
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/clients/clientset"
+	git "github.com/xanzy/go-gitlab"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// lookupTTL is how long resolved groups, projects and users are cached,
+	// long enough to absorb the lookups of one reconcile burst
+	lookupTTL = 30 * time.Second
+
+	lookupPageSize = 100
+)
+
+// AmbiguousError is returned when a name resolves to more than one resource.
+type AmbiguousError struct {
+	Resource schema.GroupResource
+	Name     string
+	Matches  []string
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("%s %q is ambiguous, it matches %s", e.Resource.String(), e.Name, strings.Join(e.Matches, ", "))
+}
+
+// IsAmbiguous returns true if err is an AmbiguousError.
+func IsAmbiguous(err error) bool {
+	_, ok := err.(*AmbiguousError)
+	return ok
+}
+
+type lookupEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// lookup resolves gitlab groups and projects by full path or ID and users by
+// username. Results are exact: missing resources are reported as kubernetes
+// NotFound errors and a name matching several resources as AmbiguousError,
+// list endpoints are read page by page until the last one.
+type lookup struct {
+	gitlabClient *clientset.GitlabClient
+	ttl          time.Duration
+	now          func() time.Time
+
+	lock    sync.Mutex
+	entries map[string]lookupEntry
+}
+
+func newLookup(gitlabClient *clientset.GitlabClient) *lookup {
+	return &lookup{
+		gitlabClient: gitlabClient,
+		ttl:          lookupTTL,
+		now:          time.Now,
+		entries:      map[string]lookupEntry{},
+	}
+}
+
+// Group resolves a group by its full path or ID.
+func (l *lookup) Group(ref string) (*git.Group, error) {
+	if value, ok := l.get(groupResource, ref); ok {
+		return value.(*git.Group), nil
+	}
+	group, _, err := l.gitlabClient.Client().Groups.GetGroup(pathOrID(ref))
+	if err != nil {
+		return nil, convertError(err, groupResource, ref)
+	}
+	l.storeGroup(group)
+	return group, nil
+}
+
+// Project resolves a project by its full path or ID.
+func (l *lookup) Project(ref string) (*git.Project, error) {
+	if value, ok := l.get(projectResource, ref); ok {
+		return value.(*git.Project), nil
+	}
+	project, _, err := l.gitlabClient.Client().Projects.GetProject(pathOrID(ref), &git.GetProjectOptions{})
+	if err != nil {
+		return nil, convertError(err, projectResource, ref)
+	}
+	l.storeProject(project)
+	return project, nil
+}
+
+// User resolves a user by username, gitlab compares usernames ignoring case.
+func (l *lookup) User(username string) (*git.User, error) {
+	if value, ok := l.get(userResource, strings.ToLower(username)); ok {
+		return value.(*git.User), nil
+	}
+
+	var matches []*git.User
+	options := &git.ListUsersOptions{
+		ListOptions: git.ListOptions{PerPage: lookupPageSize, Page: 1},
+		Username:    git.String(username),
+	}
+	for {
+		users, resp, err := l.gitlabClient.Client().Users.ListUsers(options)
+		if err != nil {
+			return nil, convertError(err, userResource, username)
+		}
+		for _, user := range users {
+			if strings.EqualFold(user.Username, username) {
+				matches = append(matches, user)
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		options.Page = resp.NextPage
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, errors.NewNotFound(userResource, username)
+	case 1:
+		l.storeUser(matches[0])
+		return matches[0], nil
+	}
+	names := make([]string, 0, len(matches))
+	for _, user := range matches {
+		names = append(names, fmt.Sprintf("%s(%d)", user.Username, user.ID))
+	}
+	return nil, &AmbiguousError{Resource: userResource, Name: username, Matches: names}
+}
+
+func (l *lookup) storeGroup(group *git.Group) {
+	l.set(group, groupResource, strconv.Itoa(group.ID), group.FullPath)
+}
+
+func (l *lookup) storeProject(project *git.Project) {
+	l.set(project, projectResource, strconv.Itoa(project.ID), project.PathWithNamespace)
+}
+
+// forgetProject drops the cached entries of a project under its ID and the
+// full path it had before a transfer, rename or deletion, so they are not
+// served until the TTL runs out.
+func (l *lookup) forgetProject(id int, fullPath string) {
+	l.forget(projectResource, strconv.Itoa(id), fullPath)
+}
+
+func (l *lookup) storeUser(user *git.User) {
+	l.set(user, userResource, strings.ToLower(user.Username))
+}
+
+func (l *lookup) get(resource schema.GroupResource, key string) (interface{}, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	entry, ok := l.entries[resource.String()+"/"+key]
+	if !ok {
+		return nil, false
+	}
+	if l.now().After(entry.expires) {
+		delete(l.entries, resource.String()+"/"+key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (l *lookup) set(value interface{}, resource schema.GroupResource, keys ...string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	expires := l.now().Add(l.ttl)
+	for _, key := range keys {
+		if len(key) != 0 {
+			l.entries[resource.String()+"/"+key] = lookupEntry{value: value, expires: expires}
+		}
+	}
+}
+
+func (l *lookup) forget(resource schema.GroupResource, keys ...string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for _, key := range keys {
+		delete(l.entries, resource.String()+"/"+key)
+	}
+}
+
+// pathOrID passes numeric references to gitlab as IDs and anything else as
+// full paths.
+func pathOrID(ref string) interface{} {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return id
+	}
+	return ref
+}
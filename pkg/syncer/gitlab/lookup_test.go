@@ -0,0 +1,171 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"github.com/hchenc/iceberg/pkg/clients/clientset"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	git "github.com/xanzy/go-gitlab"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// fakeGitlab serves groups, projects and paginated users from memory and counts
+// the requests it receives. Transferring a project moves it to the ops group.
+func fakeGitlab() (*httptest.Server, map[string]int) {
+	requests := map[string]int{}
+	devops := &git.Group{ID: 7, Path: "devops", FullPath: "platform/devops"}
+	groups := map[string]*git.Group{"platform%2Fdevops": devops, "7": devops}
+	app := &git.Project{ID: 9, Path: "app", PathWithNamespace: "platform/devops/app"}
+	projects := map[string]*git.Project{"platform%2Fdevops%2Fapp": app, "9": app}
+	users := [][]*git.User{
+		{{ID: 1, Username: "tommy"}, {ID: 2, Username: "jerry"}},
+		{{ID: 3, Username: "Tom"}, {ID: 4, Username: "Jerry"}},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/groups/", func(w http.ResponseWriter, r *http.Request) {
+		requests["groups"]++
+		key := r.URL.EscapedPath()[len("/api/v4/groups/"):]
+		group, exists := groups[key]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"404 Group Not Found"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(group)
+	})
+	mux.HandleFunc("/api/v4/projects/", func(w http.ResponseWriter, r *http.Request) {
+		requests["projects"]++
+		key := r.URL.EscapedPath()[len("/api/v4/projects/"):]
+		if r.Method == http.MethodPut && key == "9/transfer" {
+			moved := &git.Project{ID: 9, Path: "app", PathWithNamespace: "platform/ops/app"}
+			projects = map[string]*git.Project{"platform%2Fops%2Fapp": moved, "9": moved}
+			_ = json.NewEncoder(w).Encode(moved)
+			return
+		}
+		project, exists := projects[key]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"404 Project Not Found"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(project)
+	})
+	mux.HandleFunc("/api/v4/users", func(w http.ResponseWriter, r *http.Request) {
+		requests["users"]++
+		page := r.URL.Query().Get("page")
+		index := 0
+		if page == "2" {
+			index = 1
+		} else {
+			w.Header().Set("X-Next-Page", "2")
+		}
+		// gitlab filters usernames ignoring case, the fake returns every
+		// page as is to exercise the exact matching
+		_ = json.NewEncoder(w).Encode(users[index])
+	})
+	return httptest.NewServer(mux), requests
+}
+
+func TestLookup(t *testing.T) {
+	server, requests := fakeGitlab()
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	host, port, _ := net.SplitHostPort(serverURL.Host)
+	gitlabClient, err := clientset.NewGitlabClient(&config.GitlabOptions{
+		Host:  host,
+		Port:  port,
+		Token: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookup := newLookup(gitlabClient)
+	now := time.Now()
+	lookup.now = func() time.Time { return now }
+
+	group, err := lookup.Group("platform/devops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.ID != 7 {
+		t.Errorf("expected group 7, got %d", group.ID)
+	}
+	if group, err = lookup.Group("7"); err != nil || group.FullPath != "platform/devops" {
+		t.Errorf("expected group 7 to be resolved by id, got %v, %v", group, err)
+	}
+	if requests["groups"] != 1 {
+		t.Errorf("expected the group to be cached, got %d requests", requests["groups"])
+	}
+	if _, err := lookup.Group("devops"); !errors.IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	user, err := lookup.User("tom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 3 {
+		t.Errorf("expected user 3 from the second page, got %d", user.ID)
+	}
+	if _, err := lookup.User("jerry"); !IsAmbiguous(err) {
+		t.Errorf("expected jerry to be ambiguous, got %v", err)
+	}
+	if _, err := lookup.User("spike"); !errors.IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	requests["users"] = 0
+	if _, err := lookup.User("TOM"); err != nil || requests["users"] != 0 {
+		t.Errorf("expected the user to be cached, got %v after %d requests", err, requests["users"])
+	}
+	now = now.Add(lookupTTL + time.Second)
+	if _, err := lookup.User("tom"); err != nil || requests["users"] != 2 {
+		t.Errorf("expected the expired user to be listed again, got %v after %d requests", err, requests["users"])
+	}
+}
+
+func TestLookupTransferredProject(t *testing.T) {
+	server, requests := fakeGitlab()
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	host, port, _ := net.SplitHostPort(serverURL.Host)
+	gitlabClient, err := clientset.NewGitlabClient(&config.GitlabOptions{
+		Host:  host,
+		Port:  port,
+		Token: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scm := gitlabSCM{gitlabClient: gitlabClient, lookup: newLookup(gitlabClient)}
+
+	repository, err := scm.GetRepository("platform/devops/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scm.TransferRepository(repository, &syncer.SCMGroup{ID: 8, FullPath: "platform/ops"}); err != nil {
+		t.Fatal(err)
+	}
+
+	requests["projects"] = 0
+	if _, err := scm.GetRepository("platform/devops/app"); !errors.IsNotFound(err) {
+		t.Errorf("expected the old path to be forgotten, got %v", err)
+	}
+	if requests["projects"] != 1 {
+		t.Errorf("expected the old path to be looked up again, got %d requests", requests["projects"])
+	}
+	moved, err := scm.GetRepository("9")
+	if err != nil || moved.FullPath != "platform/ops/app" {
+		t.Errorf("expected the id to resolve to the new path, got %v, %v", moved, err)
+	}
+	if _, err := scm.GetRepository("platform/ops/app"); err != nil || requests["projects"] != 1 {
+		t.Errorf("expected the new path to be cached, got %v after %d requests", err, requests["projects"])
+	}
+}
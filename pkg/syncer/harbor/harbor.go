@@ -0,0 +1,276 @@
+package harbor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/antihax/optional"
+	harbor2 "github.com/hchenc/go-harbor"
+	"github.com/hchenc/iceberg/pkg/clients/clientset"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"net/http"
+	"strconv"
+)
+
+var (
+	projectResource = schema.GroupResource{Group: config.RegistryProviderHarbor, Resource: "projects"}
+	robotResource   = schema.GroupResource{Group: config.RegistryProviderHarbor, Resource: "robots"}
+	quotaResource   = schema.GroupResource{Group: config.RegistryProviderHarbor, Resource: "quotas"}
+	memberResource  = schema.GroupResource{Group: config.RegistryProviderHarbor, Resource: "members"}
+	labelResource   = schema.GroupResource{Group: config.RegistryProviderHarbor, Resource: "labels"}
+)
+
+// managedLabel is the project label marking the projects created by iceberg
+const managedLabel = "managed-by-iceberg"
+
+// harbor's built in project roles
+var roles = map[syncer.AccessLevel]int32{
+	syncer.DeveloperAccess:  2,
+	syncer.MaintainerAccess: 4,
+}
+
+type harborRegistry struct {
+	harborClient *clientset.HarborClient
+}
+
+func (h harborRegistry) Provider() string {
+	return config.RegistryProviderHarbor
+}
+
+func (h harborRegistry) Server() string {
+	return h.harborClient.Server()
+}
+
+func (h harborRegistry) CreateNamespace(namespace *syncer.RegistryNamespace) (*syncer.RegistryNamespace, error) {
+	resp, err := h.harborClient.Client().ProjectApi.CreateProject(harbor2.ProjectReq{
+		ProjectName: namespace.Name,
+		Metadata: &harbor2.ProjectMetadata{
+			Public: strconv.FormatBool(namespace.Public),
+		},
+	}, &harbor2.ProjectApiCreateProjectOpts{})
+	closeBody(resp)
+	if err != nil {
+		return nil, convertError(err, projectResource, namespace.Name)
+	}
+	created, err := h.GetNamespace(namespace.Name)
+	if err != nil {
+		return nil, err
+	}
+	// an unlabelled project couldn't be told apart from a foreign one later,
+	// so the project is removed again if it can't be labelled
+	if err := h.createManagedLabel(created); err != nil {
+		resp, deleteErr := h.harborClient.Client().ProjectApi.DeleteProject(namespace.Name, &harbor2.ProjectApiDeleteProjectOpts{
+			XIsResourceName: optional.NewBool(true),
+		})
+		closeBody(resp)
+		if deleteErr != nil {
+			return nil, fmt.Errorf("failed to label project %s: %v, and to delete it: %v", namespace.Name, err, deleteErr)
+		}
+		return nil, err
+	}
+	created.Managed = true
+	return created, nil
+}
+
+func (h harborRegistry) GetNamespace(name string) (*syncer.RegistryNamespace, error) {
+	project, resp, err := h.harborClient.Client().ProjectApi.GetProject(name, &harbor2.ProjectApiGetProjectOpts{
+		XIsResourceName: optional.NewBool(true),
+	})
+	closeBody(resp)
+	if err != nil {
+		return nil, convertError(err, projectResource, name)
+	}
+	namespace := &syncer.RegistryNamespace{
+		ID:   int(project.ProjectId),
+		Name: project.Name,
+	}
+	if project.Metadata != nil {
+		namespace.Public, _ = strconv.ParseBool(project.Metadata.Public)
+	}
+	labels, resp, err := h.harborClient.Client().LabelApi.ListLabels(&harbor2.LabelApiListLabelsOpts{
+		Scope:     optional.NewString("p"),
+		ProjectId: optional.NewInt64(int64(namespace.ID)),
+		Name:      optional.NewString(managedLabel),
+	})
+	closeBody(resp)
+	if err != nil {
+		return nil, convertError(err, labelResource, managedLabel)
+	}
+	for _, label := range labels {
+		namespace.Managed = namespace.Managed || label.Name == managedLabel
+	}
+	return namespace, nil
+}
+
+func (h harborRegistry) MarkNamespace(name string) error {
+	namespace, err := h.GetNamespace(name)
+	if err != nil || namespace.Managed {
+		return err
+	}
+	return h.createManagedLabel(namespace)
+}
+
+func (h harborRegistry) createManagedLabel(namespace *syncer.RegistryNamespace) error {
+	resp, err := h.harborClient.Client().LabelApi.CreateLabel(harbor2.Label{
+		Name:        managedLabel,
+		Description: "created by iceberg for workspace " + namespace.Name,
+		Scope:       "p",
+		ProjectId:   int64(namespace.ID),
+	}, &harbor2.LabelApiCreateLabelOpts{})
+	closeBody(resp)
+	if err = convertError(err, labelResource, managedLabel); errors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// CreateRobot creates a project robot allowed to pull, harbor only returns
+// the secret of a robot on creation so an existing robot's secret is refreshed.
+func (h harborRegistry) CreateRobot(namespace, name string) (*syncer.RegistryCredential, error) {
+	project, err := h.GetNamespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+	created, resp, err := h.harborClient.Client().Robotv1Api.CreateRobotV1(namespace, harbor2.RobotCreateV1{
+		Name:        name,
+		Description: "image pull robot managed by iceberg",
+		ExpiresAt:   -1,
+		Access: []harbor2.Access{
+			{Resource: fmt.Sprintf("/project/%d/repository", project.ID), Action: "pull"},
+		},
+	}, &harbor2.Robotv1ApiCreateRobotV1Opts{
+		XIsResourceName: optional.NewBool(true),
+	})
+	closeBody(resp)
+	if err == nil {
+		return &syncer.RegistryCredential{
+			Server:   h.Server(),
+			Username: created.Name,
+			Password: created.Secret,
+		}, nil
+	}
+	if err = convertError(err, robotResource, name); !errors.IsAlreadyExists(err) {
+		return nil, err
+	}
+
+	robots, resp, err := h.harborClient.Client().Robotv1Api.ListRobotV1(namespace, &harbor2.Robotv1ApiListRobotV1Opts{
+		XIsResourceName: optional.NewBool(true),
+	})
+	closeBody(resp)
+	if err != nil {
+		return nil, convertError(err, robotResource, name)
+	}
+	for _, robot := range robots {
+		// robot names are prefixed by robot$ and, since harbor 2.2, the project
+		if robot.Name != "robot$"+name && robot.Name != "robot$"+namespace+"+"+name {
+			continue
+		}
+		secret, err := randomSecret()
+		if err != nil {
+			return nil, err
+		}
+		refreshed, resp, err := h.harborClient.Client().RobotApi.RefreshSec(int32(robot.Id), harbor2.RobotSec{Secret: secret}, &harbor2.RobotApiRefreshSecOpts{})
+		closeBody(resp)
+		if err != nil {
+			return nil, convertError(err, robotResource, name)
+		}
+		if len(refreshed.Secret) != 0 {
+			secret = refreshed.Secret
+		}
+		return &syncer.RegistryCredential{
+			Server:   h.Server(),
+			Username: robot.Name,
+			Password: secret,
+		}, nil
+	}
+	return nil, errors.NewNotFound(robotResource, name)
+}
+
+func (h harborRegistry) SetQuota(namespace string, storageLimit int64) error {
+	project, err := h.GetNamespace(namespace)
+	if err != nil {
+		return err
+	}
+	quotas, resp, err := h.harborClient.Client().QuotaApi.ListQuotas(&harbor2.QuotaApiListQuotasOpts{
+		Reference:   optional.NewString("project"),
+		ReferenceId: optional.NewString(strconv.Itoa(project.ID)),
+	})
+	closeBody(resp)
+	if err != nil {
+		return convertError(err, quotaResource, namespace)
+	}
+	if len(quotas) == 0 {
+		return errors.NewNotFound(quotaResource, namespace)
+	}
+	// the generated client has no typed resource list, so the update is sent raw
+	err = h.harborClient.Do(http.MethodPut, fmt.Sprintf("/quotas/%d", quotas[0].Id), map[string]interface{}{
+		"hard": map[string]int64{
+			"storage": storageLimit,
+		},
+	}, nil)
+	return convertError(err, quotaResource, namespace)
+}
+
+func (h harborRegistry) AddMember(namespace, username string, level syncer.AccessLevel) error {
+	resp, err := h.harborClient.Client().MemberApi.CreateProjectMember(namespace, &harbor2.MemberApiCreateProjectMemberOpts{
+		XIsResourceName: optional.NewBool(true),
+		ProjectMember: optional.NewInterface(harbor2.ProjectMember{
+			RoleId: roles[level],
+			MemberUser: &harbor2.UserEntity{
+				Username: username,
+			},
+		}),
+	})
+	closeBody(resp)
+	if err = convertError(err, memberResource, username); errors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// convertError turns harbor's error responses into kubernetes status errors.
+func convertError(err error, resource schema.GroupResource, name string) error {
+	if err == nil {
+		return nil
+	}
+	var code int
+	switch t := err.(type) {
+	case harbor2.GenericHarborError:
+		code = t.StatusCode()
+	case *clientset.APIError:
+		code = t.StatusCode
+	default:
+		return err
+	}
+	switch code {
+	case http.StatusNotFound:
+		return errors.NewNotFound(resource, name)
+	case http.StatusConflict:
+		return errors.NewAlreadyExists(resource, name)
+	}
+	return err
+}
+
+func closeBody(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+}
+
+func randomSecret() (string, error) {
+	data := make([]byte, 16)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	// harbor requires upper case, lower case and digits in robot secrets
+	return "Ice" + hex.EncodeToString(data) + "9", nil
+}
+
+func NewHarborRegistry(harborClient *clientset.HarborClient) syncer.Registry {
+	return &harborRegistry{
+		harborClient: harborClient,
+	}
+}
@@ -0,0 +1,30 @@
+package harbor
+
+import (
+	"context"
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/clients/clientset"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"os"
+	"testing"
+)
+
+func TestCreateProject(t *testing.T) {
+	host := os.Getenv("HARBOR_HOST")
+	if len(host) == 0 {
+		t.Skip("HARBOR_HOST not set, skip harbor integration test")
+	}
+	client := clientset.NewHarborClient(&config.HarborOptions{
+		User:     "admin",
+		Password: "Harbor12345",
+		Host:     host,
+	}, context.Background())
+
+	registry := NewHarborRegistry(client)
+	result, err := registry.CreateNamespace(&syncer.RegistryNamespace{
+		Name:   "test2",
+		Public: true,
+	})
+	fmt.Println(result, err)
+}
@@ -0,0 +1,34 @@
+package syncer
+
+import (
+	"github.com/hchenc/iceberg/pkg/constants"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+)
+
+// ManagedMarker ends the description of the scm groups and repositories
+// created by iceberg. Existing resources without it are only taken over when
+// their owner is annotated with constants.IcebergAdopt.
+const ManagedMarker = "[managed by iceberg]"
+
+// MarkDescription appends ManagedMarker to description.
+func MarkDescription(description string) string {
+	if IsMarked(description) {
+		return description
+	}
+	if description = strings.TrimSpace(description); len(description) == 0 {
+		return ManagedMarker
+	}
+	return description + " " + ManagedMarker
+}
+
+// IsMarked returns true if description carries ManagedMarker.
+func IsMarked(description string) bool {
+	return strings.Contains(description, ManagedMarker)
+}
+
+// AdoptionAllowed returns true if owner allows iceberg to take over existing
+// resources it didn't create.
+func AdoptionAllowed(owner metav1.Object) bool {
+	return owner.GetAnnotations()[constants.IcebergAdopt] == "true"
+}
@@ -0,0 +1,48 @@
+package syncer
+
+// RegistryNamespace is a harbor project or the repository prefix of a
+// distribution registry.
+type RegistryNamespace struct {
+	ID     int
+	Name   string
+	Public bool
+	// Managed is true if the namespace carries iceberg's ownership marker
+	Managed bool
+}
+
+// RegistryCredential is the account used to pull images of a namespace.
+type RegistryCredential struct {
+	Server   string
+	Username string
+	Password string
+}
+
+// Registry abstracts the image registry that workspaces are synced into.
+//
+// Like SCM, Create methods return a kubernetes AlreadyExists error when the
+// resource exists and Get methods return a NotFound error when it doesn't.
+// Features a backend lacks, e.g. quotas on a plain distribution registry,
+// return a MethodNotSupported error which generators treat as a no-op.
+type Registry interface {
+	// Provider returns the name of the backend, e.g. harbor or distribution
+	Provider() string
+
+	// Server returns the registry host used in image references and pull secrets
+	Server() string
+
+	// CreateNamespace creates the namespace marked as managed by iceberg
+	CreateNamespace(namespace *RegistryNamespace) (*RegistryNamespace, error)
+	GetNamespace(name string) (*RegistryNamespace, error)
+	// MarkNamespace marks an existing namespace as managed by iceberg
+	MarkNamespace(name string) error
+
+	// CreateRobot returns a pull only credential for the namespace, an
+	// existing robot with the same name gets a new secret
+	CreateRobot(namespace, name string) (*RegistryCredential, error)
+
+	// SetQuota limits the storage of the namespace in bytes, -1 is unlimited
+	SetQuota(namespace string, storageLimit int64) error
+
+	// AddMember adds a user to a namespace, adding an existing member is not an error
+	AddMember(namespace, username string, level AccessLevel) error
+}
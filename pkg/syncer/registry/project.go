@@ -0,0 +1,276 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	baseErr "errors"
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/apis/tenant/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"github.com/hchenc/iceberg/pkg/utils"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const pullRobotName = "iceberg-pull"
+
+type projectInfo struct {
+	registry   syncer.Registry
+	kubeClient kubernetes.Interface
+	logger     *logrus.Logger
+	ctx        context.Context
+}
+
+func (p projectInfo) Create(obj interface{}) (interface{}, error) {
+	workspace := obj.(*v1alpha2.WorkspaceTemplate)
+	workspaceLogInfo := logrus.Fields{
+		"workspace": workspace.Name,
+		"provider":  p.registry.Provider(),
+	}
+
+	namespace, err := p.registry.CreateNamespace(&syncer.RegistryNamespace{
+		Name:   workspace.Name,
+		Public: true,
+	})
+	if errors.IsAlreadyExists(err) {
+		namespace, err = p.registry.GetNamespace(workspace.Name)
+		if err == nil && !namespace.Managed {
+			err = p.adopt(workspace)
+		}
+	}
+	if err != nil {
+		p.logger.WithFields(workspaceLogInfo).WithFields(logrus.Fields{
+			"message": "failed to create registry namespace",
+		}).Error(err)
+		return nil, err
+	}
+
+	if quota, exists := workspace.GetAnnotations()[constants.IcebergRegistryQuota]; exists {
+		if err := p.setQuota(workspace.Name, quota); err != nil {
+			p.logger.WithFields(workspaceLogInfo).WithFields(logrus.Fields{
+				"quota":   quota,
+				"message": "failed to set registry quota",
+			}).Error(err)
+			return namespace, err
+		}
+	}
+
+	// registry accounts are usually provisioned by ldap or oidc, a creator
+	// without one must not block the workspace
+	if creator := workspace.GetAnnotations()[constants.KubesphereCreator]; creator != "" && creator != "admin" {
+		if err := p.registry.AddMember(workspace.Name, creator, syncer.MaintainerAccess); err != nil && !errors.IsMethodNotSupported(err) {
+			p.logger.WithFields(workspaceLogInfo).WithFields(logrus.Fields{
+				"user":    creator,
+				"message": "failed to add registry member",
+			}).Warn(err)
+		}
+	}
+
+	if err := p.syncPullSecrets(workspace.Name); err != nil {
+		return namespace, err
+	}
+	p.logger.WithFields(workspaceLogInfo).Info("finish to sync registry namespace")
+	return namespace, nil
+}
+
+// adopt marks the existing unmarked namespace if the workspace is annotated
+// with constants.IcebergAdopt, or if iceberg already wrote its pull secret to
+// the workspace which is the case for the namespaces created before they were
+// marked. The decision is logged.
+func (p projectInfo) adopt(workspace *v1alpha2.WorkspaceTemplate) error {
+	logger := p.logger.WithFields(logrus.Fields{
+		"workspace": workspace.Name,
+		"provider":  p.registry.Provider(),
+	})
+
+	var reason string
+	if syncer.AdoptionAllowed(workspace) {
+		reason = "annotated with " + constants.IcebergAdopt
+	} else if synced, err := p.pullSecretSynced(workspace.Name); err != nil {
+		return err
+	} else if synced {
+		reason = "pull secret already synced"
+	}
+	if len(reason) == 0 {
+		err := fmt.Errorf("registry namespace %s exists but is not managed by iceberg, annotate %s with %s=true to adopt it", workspace.Name, workspace.Name, constants.IcebergAdopt)
+		logger.WithFields(logrus.Fields{
+			"decision": "refused",
+			"message":  "refuse to adopt unmarked registry namespace",
+		}).Error(err)
+		return err
+	}
+	logger.WithFields(logrus.Fields{
+		"decision": "adopted",
+		"reason":   reason,
+	}).Warn("adopt unmarked registry namespace")
+	return p.registry.MarkNamespace(workspace.Name)
+}
+
+func (p projectInfo) setQuota(namespace, quota string) error {
+	limit, err := resource.ParseQuantity(quota)
+	if err != nil {
+		return err
+	}
+	if err := p.registry.SetQuota(namespace, limit.Value()); err != nil && !errors.IsMethodNotSupported(err) {
+		return err
+	}
+	return nil
+}
+
+// syncPullSecrets writes the registry credential to every environment of the
+// workspace. The credential is only requested when a secret is missing since
+// robots get a new secret each time, then all secrets are rewritten.
+func (p projectInfo) syncPullSecrets(workspaceName string) error {
+	candidates, err := p.environmentNamespaces(workspaceName)
+	if err != nil {
+		return err
+	} else if len(candidates) == 0 {
+		// retried until the namespace generator created them
+		return fmt.Errorf("workspace %s has no environment namespace yet", workspaceName)
+	}
+	var missing bool
+	for _, namespace := range candidates {
+		_, err := p.kubeClient.CoreV1().Secrets(namespace).Get(p.ctx, constants.RegistryPullSecret, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			missing = true
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	if !missing {
+		return nil
+	}
+
+	credential, err := p.registry.CreateRobot(workspaceName, pullRobotName)
+	if err != nil {
+		p.logger.WithFields(logrus.Fields{
+			"workspace": workspaceName,
+			"message":   "failed to create registry robot",
+		}).Error(err)
+		return err
+	}
+	dockerConfig, err := dockerConfigJSON(credential)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, namespace := range candidates {
+		secret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      constants.RegistryPullSecret,
+				Namespace: namespace,
+				Labels: map[string]string{
+					constants.KubesphereWorkspace: workspaceName,
+				},
+			},
+			Type: v1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{
+				v1.DockerConfigJsonKey: dockerConfig,
+			},
+		}
+		_, err := p.kubeClient.CoreV1().Secrets(namespace).Create(p.ctx, secret, metav1.CreateOptions{})
+		if errors.IsAlreadyExists(err) {
+			_, err = p.kubeClient.CoreV1().Secrets(namespace).Update(p.ctx, secret, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			p.logger.WithFields(logrus.Fields{
+				"workspace": workspaceName,
+				"namespace": namespace,
+				"message":   "failed to sync registry pull secret",
+			}).Error(err)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return baseErr.New("failed to sync registry pull secret")
+	}
+	return nil
+}
+
+// pullSecretSynced reports whether an environment of the workspace has the
+// pull secret.
+func (p projectInfo) pullSecretSynced(workspaceName string) (bool, error) {
+	namespaces, err := p.environmentNamespaces(workspaceName)
+	if err != nil {
+		return false, err
+	}
+	for _, namespace := range namespaces {
+		_, err := p.kubeClient.CoreV1().Secrets(namespace).Get(p.ctx, constants.RegistryPullSecret, metav1.GetOptions{})
+		if err == nil {
+			return true, nil
+		} else if !errors.IsNotFound(err) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// environmentNamespaces returns the namespaces labeled as environments of the
+// workspace.
+func (p projectInfo) environmentNamespaces(workspaceName string) ([]string, error) {
+	list, err := p.kubeClient.CoreV1().Namespaces().List(p.ctx, metav1.ListOptions{
+		LabelSelector: constants.KubesphereWorkspace + "=" + workspaceName + "," + constants.IcebergEnvironment,
+	})
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make([]string, 0, len(list.Items))
+	for _, namespace := range list.Items {
+		namespaces = append(namespaces, namespace.Name)
+	}
+	return namespaces, nil
+}
+
+func dockerConfigJSON(credential *syncer.RegistryCredential) ([]byte, error) {
+	auth := base64.StdEncoding.EncodeToString([]byte(credential.Username + ":" + credential.Password))
+	return json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{
+			credential.Server: map[string]string{
+				"username": credential.Username,
+				"password": credential.Password,
+				"auth":     auth,
+			},
+		},
+	})
+}
+
+func (p projectInfo) Update(objOld interface{}, objNew interface{}) error {
+	panic("implement me")
+}
+
+func (p projectInfo) Delete(name string) error {
+	panic("implement me")
+}
+
+func (p projectInfo) GetByName(name string) (interface{}, error) {
+	return p.registry.GetNamespace(name)
+}
+
+func (p projectInfo) GetByID(id int) (interface{}, error) {
+	panic("implement me")
+}
+
+func (p projectInfo) List(key string) (interface{}, error) {
+	panic("implement me")
+}
+
+func NewProjectGenerator(ctx context.Context, registry syncer.Registry, kubeClient kubernetes.Interface) syncer.Generator {
+	logger := utils.GetLogger(logrus.Fields{
+		"component": registry.Provider(),
+		"resource":  "project",
+	})
+	return &projectInfo{
+		registry:   registry,
+		kubeClient: kubeClient,
+		logger:     logger,
+		ctx:        ctx,
+	}
+}
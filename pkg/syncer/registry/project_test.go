@@ -0,0 +1,122 @@
+package registry
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/apis/tenant/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+type fakeRegistry struct {
+	robots   int
+	quota    int64
+	unmarked bool
+	marked   bool
+}
+
+func (f *fakeRegistry) Provider() string { return "fake" }
+
+func (f *fakeRegistry) Server() string { return "registry.hchenc.com" }
+
+func (f *fakeRegistry) CreateNamespace(namespace *syncer.RegistryNamespace) (*syncer.RegistryNamespace, error) {
+	return nil, errors.NewAlreadyExists(schema.GroupResource{Resource: "projects"}, namespace.Name)
+}
+
+func (f *fakeRegistry) GetNamespace(name string) (*syncer.RegistryNamespace, error) {
+	return &syncer.RegistryNamespace{ID: 1, Name: name, Managed: !f.unmarked || f.marked}, nil
+}
+
+func (f *fakeRegistry) MarkNamespace(name string) error {
+	f.marked = true
+	return nil
+}
+
+func (f *fakeRegistry) CreateRobot(namespace, name string) (*syncer.RegistryCredential, error) {
+	f.robots++
+	return &syncer.RegistryCredential{Server: f.Server(), Username: "robot$" + name, Password: "secret"}, nil
+}
+
+func (f *fakeRegistry) SetQuota(namespace string, storageLimit int64) error {
+	f.quota = storageLimit
+	return nil
+}
+
+func (f *fakeRegistry) AddMember(namespace, username string, level syncer.AccessLevel) error {
+	return errors.NewMethodNotSupported(schema.GroupResource{Resource: "members"}, "create")
+}
+
+// environments returns the environment namespaces of the devops workspace,
+// named unlike <workspace>-<env> since they're found by their labels.
+func environments() []runtime.Object {
+	var namespaces []runtime.Object
+	for name, env := range map[string]string{"devops-fat": "fat", "devops-uat": "uat", "devops-production": "prod"} {
+		namespaces = append(namespaces, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{constants.KubesphereWorkspace: "devops", constants.IcebergEnvironment: env},
+		}})
+	}
+	return namespaces
+}
+
+func TestCreate(t *testing.T) {
+	registry := &fakeRegistry{}
+	kubeClient := fake.NewSimpleClientset(environments()...)
+	generator := NewProjectGenerator(context.Background(), registry, kubeClient)
+
+	workspace := &v1alpha2.WorkspaceTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "devops",
+			Annotations: map[string]string{
+				constants.IcebergRegistryQuota: "1Gi",
+				constants.KubesphereCreator:    "alice",
+			},
+		},
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := generator.Create(workspace); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if registry.robots != 1 {
+		t.Errorf("expected the robot to be created once, got %d", registry.robots)
+	}
+	if registry.quota != 1<<30 {
+		t.Errorf("expected quota 1Gi, got %d", registry.quota)
+	}
+	for _, namespace := range []string{"devops-fat", "devops-uat", "devops-production"} {
+		secret, err := kubeClient.CoreV1().Secrets(namespace).Get(context.Background(), constants.RegistryPullSecret, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := `{"auths":{"registry.hchenc.com":{"auth":"cm9ib3QkaWNlYmVyZy1wdWxsOnNlY3JldA==","password":"secret","username":"robot$iceberg-pull"}}}`; string(secret.Data[".dockerconfigjson"]) != expected {
+			t.Errorf("unexpected docker config %s", secret.Data[".dockerconfigjson"])
+		}
+	}
+}
+
+func TestAdopt(t *testing.T) {
+	registry := &fakeRegistry{unmarked: true}
+	generator := NewProjectGenerator(context.Background(), registry, fake.NewSimpleClientset(environments()...))
+
+	workspace := &v1alpha2.WorkspaceTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "devops"},
+	}
+	if _, err := generator.Create(workspace); err == nil || registry.marked {
+		t.Fatalf("expected the unmarked namespace not to be adopted, got %v", err)
+	}
+
+	workspace.Annotations = map[string]string{constants.IcebergAdopt: "true"}
+	if _, err := generator.Create(workspace); err != nil {
+		t.Fatal(err)
+	}
+	if !registry.marked {
+		t.Error("expected the annotated workspace to adopt and mark the namespace")
+	}
+}
@@ -5,18 +5,19 @@ import (
 	baseErr "errors"
 	applicationv1beta1 "github.com/hchenc/application/pkg/apis/app/v1beta1"
 	"github.com/hchenc/application/pkg/client/clientset/versioned"
+	"github.com/hchenc/iceberg/pkg/constants"
 	"github.com/hchenc/iceberg/pkg/syncer"
 	"github.com/hchenc/iceberg/pkg/utils"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"strings"
 )
 
 type applicationInfo struct {
 	appClient  *versioned.Clientset
 	kubeClient *kubernetes.Clientset
+	namespaces *NamespaceResolver
 	logger     *logrus.Logger
 	ctx        context.Context
 }
@@ -27,23 +28,26 @@ func (a applicationInfo) Create(obj interface{}) (interface{}, error) {
 		"application": application.Name,
 	}
 	a.logger.WithFields(appLogInfo).Info("start to create kubesphere application")
-	namespacePrefix := strings.Split(application.Namespace, "-")[0]
-	candidates := map[string]string{
-		namespacePrefix + "-fat": "fat",
-		namespacePrefix + "-uat": "uat",
-		namespacePrefix + "-sit": "sit",
+	_, _, candidates, err := a.namespaces.Siblings(application.Namespace)
+	if err != nil {
+		return nil, skipUnlabeled(a.logger.WithFields(appLogInfo), err)
 	}
-	delete(candidates, application.Namespace)
+	candidates = propagationTargets(application, candidates)
 
 	var errs []error
 
 	for namespace := range candidates {
 		application := assembleResource(application, namespace, func(obj interface{}, namespace string) interface{} {
+			labels := map[string]string{}
+			for key, value := range application.Labels {
+				labels[key] = value
+			}
+			labels[constants.IcebergPropagatedFrom] = application.Namespace
 			return &applicationv1beta1.Application{
 				ObjectMeta: v1.ObjectMeta{
 					Name:        application.Name,
 					Namespace:   namespace,
-					Labels:      application.Labels,
+					Labels:      labels,
 					Annotations: application.Annotations,
 					Finalizers:  application.Finalizers,
 					ClusterName: application.ClusterName,
@@ -92,7 +96,7 @@ func (a applicationInfo) List(key string) (interface{}, error) {
 	panic("implement me")
 }
 
-func NewApplicationGenerator(ctx context.Context, kubeClient *kubernetes.Clientset, appClient *versioned.Clientset) syncer.Generator {
+func NewApplicationGenerator(ctx context.Context, kubeClient *kubernetes.Clientset, appClient *versioned.Clientset, namespaces *NamespaceResolver) syncer.Generator {
 	logger := utils.GetLogger(logrus.Fields{
 		"component": "kubesphere",
 		"resource":  "application",
@@ -100,6 +104,7 @@ func NewApplicationGenerator(ctx context.Context, kubeClient *kubernetes.Clients
 	return applicationInfo{
 		appClient:  appClient,
 		kubeClient: kubeClient,
+		namespaces: namespaces,
 		ctx:        ctx,
 		logger:     logger,
 	}
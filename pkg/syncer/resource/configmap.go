@@ -0,0 +1,191 @@
+package resource
+
+import (
+	"context"
+	baseErr "errors"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"github.com/hchenc/iceberg/pkg/utils"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"strings"
+)
+
+type configMapInfo struct {
+	kubeClient kubernetes.Interface
+	namespaces *NamespaceResolver
+	overrides  OverridesFunc
+	logger     *logrus.Logger
+	ctx        context.Context
+}
+
+// Create copies the configmap into the sibling environments. The keys of a
+// copy are overridden by the <env>.override.iceberg.io/<key> annotations of
+// the source, then by the configmap labelled iceberg.io/override-of in the
+// target namespace. Copies made earlier are updated, configmaps with the
+// same name which weren't made by iceberg are left untouched.
+func (c configMapInfo) Create(obj interface{}) (interface{}, error) {
+	configMap := obj.(*v1.ConfigMap)
+	cmLogInfo := logrus.Fields{
+		"configmap": configMap.Name,
+	}
+	if IsExcluded(configMap) || len(configMap.Labels[constants.IcebergOverrideOf]) != 0 {
+		return nil, nil
+	}
+	workspace, _, candidates, err := c.namespaces.Siblings(configMap.Namespace)
+	if err != nil {
+		return nil, skipUnlabeled(c.logger.WithFields(cmLogInfo), err)
+	}
+	candidates = propagationTargets(configMap, candidates)
+	var errs []error
+
+	for namespace, env := range candidates {
+		err := c.sync(configMap, workspace, namespace, env)
+		if err == nil {
+			c.logger.WithFields(cmLogInfo).WithFields(logrus.Fields{
+				"namespace": namespace,
+			}).Info("finish to create namespaced kubernetes configmap")
+		} else {
+			c.logger.WithFields(cmLogInfo).WithFields(logrus.Fields{
+				"namespace": namespace,
+				"message":   "failed to create namespaced kubernetes configmap",
+			}).Error(err)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return nil, baseErr.New("failed to sync kubernetes configmap")
+	} else {
+		c.logger.WithFields(cmLogInfo).Info("finish to sync kubesphere configmap")
+		return nil, nil
+	}
+}
+
+func (c configMapInfo) sync(source *v1.ConfigMap, workspace, namespace, env string) error {
+	data, err := c.overrideData(source.Name, namespace)
+	if err != nil {
+		return err
+	}
+	configMap := copyConfigMap(source, namespace, env, data)
+	if err := applyOverrides(configMap, "ConfigMap", workspace, env, c.overrides); err != nil {
+		return err
+	}
+
+	_, err = c.kubeClient.CoreV1().ConfigMaps(namespace).Create(c.ctx, configMap, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := c.kubeClient.CoreV1().ConfigMaps(namespace).Get(c.ctx, configMap.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if existing.Labels[constants.IcebergPropagatedFrom] != source.Namespace {
+		c.logger.WithFields(logrus.Fields{
+			"configmap": configMap.Name,
+			"namespace": namespace,
+		}).Info("configmap not made by iceberg already exists, skip it")
+		return nil
+	}
+	configMap.ResourceVersion = existing.ResourceVersion
+	_, err = c.kubeClient.CoreV1().ConfigMaps(namespace).Update(c.ctx, configMap, metav1.UpdateOptions{})
+	return err
+}
+
+// overrideData returns the data of the override configmap of name in namespace.
+func (c configMapInfo) overrideData(name, namespace string) (map[string]string, error) {
+	configMaps, err := c.kubeClient.CoreV1().ConfigMaps(namespace).List(c.ctx, metav1.ListOptions{
+		LabelSelector: constants.IcebergOverrideOf + "=" + name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	overrides := map[string]string{}
+	for _, configMap := range configMaps.Items {
+		for key, value := range configMap.Data {
+			overrides[key] = value
+		}
+	}
+	return overrides, nil
+}
+
+func copyConfigMap(source *v1.ConfigMap, namespace, env string, overrides map[string]string) *v1.ConfigMap {
+	labels := map[string]string{}
+	for key, value := range source.Labels {
+		labels[key] = value
+	}
+	labels[constants.IcebergPropagatedFrom] = source.Namespace
+
+	data := map[string]string{}
+	for key, value := range source.Data {
+		data[key] = value
+	}
+	annotations := map[string]string{}
+	for key, value := range source.Annotations {
+		domain := strings.SplitN(key, "/", 2)
+		if len(domain) == 2 && strings.HasSuffix(domain[0], "."+constants.IcebergOverrideDomain) {
+			if domain[0] == env+"."+constants.IcebergOverrideDomain {
+				data[domain[1]] = value
+			}
+			continue
+		}
+		annotations[key] = value
+	}
+	for key, value := range overrides {
+		data[key] = value
+	}
+
+	return &v1.ConfigMap{
+		TypeMeta: source.TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        source.Name,
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Data:       data,
+		BinaryData: source.BinaryData,
+	}
+}
+
+// IsPropagated reports whether the object is a copy made by iceberg.
+func IsPropagated(obj metav1.Object) bool {
+	return len(obj.GetLabels()[constants.IcebergPropagatedFrom]) != 0
+}
+
+func (c configMapInfo) Update(objOld interface{}, objNew interface{}) error {
+	_, err := c.Create(objNew)
+	return err
+}
+
+func (c configMapInfo) Delete(name string) error {
+	panic("implement me")
+}
+
+func (c configMapInfo) GetByName(name string) (interface{}, error) {
+	panic("implement me")
+}
+
+func (c configMapInfo) GetByID(id int) (interface{}, error) {
+	panic("implement me")
+}
+
+func (c configMapInfo) List(key string) (interface{}, error) {
+	panic("implement me")
+}
+
+func NewConfigMapGenerator(ctx context.Context, kubeClient kubernetes.Interface, namespaces *NamespaceResolver, overrides OverridesFunc) syncer.Generator {
+	logger := utils.GetLogger(logrus.Fields{
+		"component": "kubernetes",
+		"resource":  "configmap",
+	})
+	return &configMapInfo{
+		kubeClient: kubeClient,
+		namespaces: namespaces,
+		overrides:  overrides,
+		logger:     logger,
+		ctx:        ctx,
+	}
+}
@@ -0,0 +1,94 @@
+package resource
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/constants"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestConfigMapGenerator(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "app-overrides",
+				Namespace: "devops-sit",
+				Labels:    map[string]string{constants.IcebergOverrideOf: "app"},
+			},
+			Data: map[string]string{"DB_HOST": "db.sit"},
+		},
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "other",
+				Namespace: "devops-uat",
+			},
+			Data: map[string]string{"LOG_LEVEL": "info"},
+		},
+	)
+	generator := NewConfigMapGenerator(context.Background(), kubeClient, newTestResolver(), nil)
+
+	newConfigMap := func(name string) *v1.ConfigMap {
+		return &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "devops-fat",
+				Annotations: map[string]string{
+					"uat." + constants.IcebergOverrideDomain + "/DB_HOST": "db.uat",
+					"sit." + constants.IcebergOverrideDomain + "/DB_HOST": "db.ignored",
+					"description": "app settings",
+				},
+			},
+			Data: map[string]string{"DB_HOST": "db.fat", "LOG_LEVEL": "debug"},
+		}
+	}
+	if _, err := generator.Create(newConfigMap("app")); err != nil {
+		t.Fatal(err)
+	}
+
+	uat, err := kubeClient.CoreV1().ConfigMaps("devops-uat").Get(context.Background(), "app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uat.Data["DB_HOST"] != "db.uat" || uat.Data["LOG_LEVEL"] != "debug" {
+		t.Errorf("unexpected uat data %v", uat.Data)
+	}
+	if len(uat.Annotations) != 1 || uat.Labels[constants.IcebergPropagatedFrom] != "devops-fat" {
+		t.Errorf("unexpected uat metadata %v %v", uat.Annotations, uat.Labels)
+	}
+
+	sit, err := kubeClient.CoreV1().ConfigMaps("devops-sit").Get(context.Background(), "app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sit.Data["DB_HOST"] != "db.sit" {
+		t.Errorf("expected the override configmap to win, got %v", sit.Data)
+	}
+
+	// copies are updated, configmaps not made by iceberg are kept
+	source := newConfigMap("app")
+	source.Data["LOG_LEVEL"] = "warn"
+	if err := generator.Update(nil, source); err != nil {
+		t.Fatal(err)
+	}
+	uat, _ = kubeClient.CoreV1().ConfigMaps("devops-uat").Get(context.Background(), "app", metav1.GetOptions{})
+	if uat.Data["LOG_LEVEL"] != "warn" {
+		t.Errorf("expected uat copy to be updated, got %v", uat.Data)
+	}
+	if _, err := generator.Create(newConfigMap("other")); err != nil {
+		t.Fatal(err)
+	}
+	other, _ := kubeClient.CoreV1().ConfigMaps("devops-uat").Get(context.Background(), "other", metav1.GetOptions{})
+	if other.Data["LOG_LEVEL"] != "info" {
+		t.Errorf("expected unmanaged configmap to be kept, got %v", other.Data)
+	}
+
+	// copies and override configmaps are never propagated
+	if _, err := generator.Create(uat); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kubeClient.CoreV1().ConfigMaps("devops-fat").Get(context.Background(), "app", metav1.GetOptions{}); err == nil {
+		t.Error("expected a copy not to be propagated")
+	}
+}
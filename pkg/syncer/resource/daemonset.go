@@ -0,0 +1,47 @@
+package resource
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	v1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type daemonSetWorkload struct{}
+
+func (daemonSetWorkload) clone(obj interface{}, namespace string, transform *workloadTransform) interface{} {
+	daemonSet := obj.(*v1.DaemonSet).DeepCopy()
+	daemonSet.ObjectMeta = cloneObjectMeta(daemonSet.ObjectMeta, namespace)
+	daemonSet.Status = v1.DaemonSetStatus{}
+	transform.apply(&daemonSet.Spec.Template)
+	return daemonSet
+}
+
+func (daemonSetWorkload) exists(ctx context.Context, kubeClient kubernetes.Interface, namespace, selector string) (bool, error) {
+	daemonSets, err := kubeClient.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return false, err
+	}
+	return len(daemonSets.Items) != 0, nil
+}
+
+func (daemonSetWorkload) get(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string) (metav1.Object, error) {
+	return kubeClient.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (daemonSetWorkload) create(ctx context.Context, kubeClient kubernetes.Interface, obj interface{}) error {
+	daemonSet := obj.(*v1.DaemonSet)
+	_, err := kubeClient.AppsV1().DaemonSets(daemonSet.Namespace).Create(ctx, daemonSet, metav1.CreateOptions{})
+	return err
+}
+
+func (daemonSetWorkload) update(ctx context.Context, kubeClient kubernetes.Interface, obj interface{}, existing metav1.Object) error {
+	daemonSet := obj.(*v1.DaemonSet)
+	_, err := kubeClient.AppsV1().DaemonSets(daemonSet.Namespace).Update(ctx, daemonSet, metav1.UpdateOptions{})
+	return err
+}
+
+func NewDaemonSetGenerator(ctx context.Context, kubeClient kubernetes.Interface, namespaces *NamespaceResolver, overrides OverridesFunc) syncer.Generator {
+	return newWorkloadGenerator(ctx, kubeClient, namespaces, overrides, "daemonset", daemonSetWorkload{})
+}
@@ -0,0 +1,306 @@
+package resource
+
+import (
+	"context"
+	baseErr "errors"
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/apis/tenant/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"github.com/hchenc/iceberg/pkg/utils"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"strings"
+)
+
+const (
+	EnvironmentQuota            = "iceberg-quota"
+	EnvironmentLimitRange       = "iceberg-limits"
+	EnvironmentNetworkIsolation = "iceberg-workspace-isolation"
+)
+
+var managedLabels = map[string]string{
+	"app.kubernetes.io/managed-by": "iceberg",
+}
+
+type environmentInfo struct {
+	kubeClient kubernetes.Interface
+	options    func() *config.NamespaceOptions
+	namespaces *NamespaceResolver
+	logger     *logrus.Logger
+	ctx        context.Context
+}
+
+// Create hardens the environment namespaces of the workspace with the
+// ResourceQuota and LimitRange of the environment's template, and with a
+// NetworkPolicy denying traffic from other workspaces when the workspace
+// has network isolation. The template is overridden by the workspace
+// annotations <env>.quota.iceberg.io/<resource> and
+// <env>.limitrange.iceberg.io/<default|defaultRequest|max|min>.<resource>.
+// Objects whose template becomes empty are deleted.
+func (e environmentInfo) Create(obj interface{}) (interface{}, error) {
+	workspace := obj.(*v1alpha2.WorkspaceTemplate)
+	envLogInfo := logrus.Fields{
+		"workspace": workspace.Name,
+	}
+	options := e.options()
+	isolation := workspace.Spec.Template.Spec.NetworkIsolation != nil && *workspace.Spec.Template.Spec.NetworkIsolation
+
+	environments, err := e.namespaces.Environments(workspace.Name)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, env := range options.GetEnvs() {
+		if !containsValue(environments, env) {
+			// retried until the namespace generator created it
+			err := fmt.Errorf("environment %s of workspace %s has no namespace yet", env, workspace.Name)
+			e.logger.WithFields(envLogInfo).Warn(err)
+			errs = append(errs, err)
+		}
+	}
+	for namespace, env := range environments {
+		err := e.syncQuota(workspace, namespace, env, options.GetEnvironment(env))
+		if err == nil {
+			err = e.syncLimitRange(workspace, namespace, env, options.GetEnvironment(env))
+		}
+		if err == nil {
+			err = e.syncNetworkPolicy(workspace.Name, namespace, isolation, options)
+		}
+		if err == nil {
+			e.logger.WithFields(envLogInfo).WithFields(logrus.Fields{
+				"namespace": namespace,
+			}).Info("finish to harden environment namespace")
+		} else {
+			e.logger.WithFields(envLogInfo).WithFields(logrus.Fields{
+				"namespace": namespace,
+				"message":   "failed to harden environment namespace",
+			}).Error(err)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return nil, baseErr.New("failed to sync kubernetes environment policies")
+	} else {
+		e.logger.WithFields(envLogInfo).Info("finish to sync kubernetes environment policies")
+		return nil, nil
+	}
+}
+
+func (e environmentInfo) syncQuota(workspace *v1alpha2.WorkspaceTemplate, namespace, env string, environment *config.NamespaceEnvironmentOptions) error {
+	resources := map[string]string{}
+	if environment != nil {
+		for name, value := range environment.Quota {
+			resources[name] = value
+		}
+	}
+	for name, value := range annotationsOf(workspace.Annotations, env+".quota."+constants.IcebergDomain) {
+		resources[name] = value
+	}
+	client := e.kubeClient.CoreV1().ResourceQuotas(namespace)
+	if len(resources) == 0 {
+		return ignoreNotFound(client.Delete(e.ctx, EnvironmentQuota, metav1.DeleteOptions{}))
+	}
+	hard, err := config.ResourceList(resources)
+	if err != nil {
+		return err
+	}
+
+	quota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: EnvironmentQuota, Namespace: namespace, Labels: managedLabels},
+		Spec:       corev1.ResourceQuotaSpec{Hard: hard},
+	}
+	existing, err := client.Get(e.ctx, EnvironmentQuota, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = client.Create(e.ctx, quota, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(existing.Spec, quota.Spec) {
+		return nil
+	}
+	existing.Spec = quota.Spec
+	_, err = client.Update(e.ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+func (e environmentInfo) syncLimitRange(workspace *v1alpha2.WorkspaceTemplate, namespace, env string, environment *config.NamespaceEnvironmentOptions) error {
+	limits := map[string]map[string]string{
+		"default":        {},
+		"defaultRequest": {},
+		"max":            {},
+		"min":            {},
+	}
+	if environment != nil && environment.LimitRange != nil {
+		for kind, resources := range map[string]map[string]string{
+			"default":        environment.LimitRange.Default,
+			"defaultRequest": environment.LimitRange.DefaultRequest,
+			"max":            environment.LimitRange.Max,
+			"min":            environment.LimitRange.Min,
+		} {
+			for name, value := range resources {
+				limits[kind][name] = value
+			}
+		}
+	}
+	for key, value := range annotationsOf(workspace.Annotations, env+".limitrange."+constants.IcebergDomain) {
+		parts := strings.SplitN(key, ".", 2)
+		if len(parts) != 2 || limits[parts[0]] == nil {
+			return baseErr.New("invalid limit range annotation " + key)
+		}
+		limits[parts[0]][parts[1]] = value
+	}
+	client := e.kubeClient.CoreV1().LimitRanges(namespace)
+	empty := true
+	for _, resources := range limits {
+		empty = empty && len(resources) == 0
+	}
+	if empty {
+		return ignoreNotFound(client.Delete(e.ctx, EnvironmentLimitRange, metav1.DeleteOptions{}))
+	}
+
+	item := corev1.LimitRangeItem{Type: corev1.LimitTypeContainer}
+	for kind, target := range map[string]*corev1.ResourceList{
+		"default":        &item.Default,
+		"defaultRequest": &item.DefaultRequest,
+		"max":            &item.Max,
+		"min":            &item.Min,
+	} {
+		if len(limits[kind]) == 0 {
+			continue
+		}
+		list, err := config.ResourceList(limits[kind])
+		if err != nil {
+			return err
+		}
+		*target = list
+	}
+	limitRange := &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: EnvironmentLimitRange, Namespace: namespace, Labels: managedLabels},
+		Spec:       corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{item}},
+	}
+	existing, err := client.Get(e.ctx, EnvironmentLimitRange, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = client.Create(e.ctx, limitRange, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(existing.Spec, limitRange.Spec) {
+		return nil
+	}
+	existing.Spec = limitRange.Spec
+	_, err = client.Update(e.ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+func (e environmentInfo) syncNetworkPolicy(workspaceName, namespace string, isolation bool, options *config.NamespaceOptions) error {
+	client := e.kubeClient.NetworkingV1().NetworkPolicies(namespace)
+	if !isolation {
+		return ignoreNotFound(client.Delete(e.ctx, EnvironmentNetworkIsolation, metav1.DeleteOptions{}))
+	}
+
+	peers := []networkingv1.NetworkPolicyPeer{{
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{constants.KubesphereWorkspace: workspaceName},
+		},
+	}}
+	if options != nil {
+		for _, labels := range options.AllowedNamespaces {
+			peers = append(peers, networkingv1.NetworkPolicyPeer{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: labels},
+			})
+		}
+	}
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: EnvironmentNetworkIsolation, Namespace: namespace, Labels: managedLabels},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: peers}},
+		},
+	}
+	existing, err := client.Get(e.ctx, EnvironmentNetworkIsolation, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = client.Create(e.ctx, policy, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(existing.Spec, policy.Spec) {
+		return nil
+	}
+	existing.Spec = policy.Spec
+	_, err = client.Update(e.ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// annotationsOf returns the annotations in domain keyed by their name.
+func annotationsOf(annotations map[string]string, domain string) map[string]string {
+	result := map[string]string{}
+	for key, value := range annotations {
+		if strings.HasPrefix(key, domain+"/") {
+			result[strings.TrimPrefix(key, domain+"/")] = value
+		}
+	}
+	return result
+}
+
+func ignoreNotFound(err error) error {
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (e environmentInfo) Update(objOld interface{}, objNew interface{}) error {
+	_, err := e.Create(objNew)
+	return err
+}
+
+func (e environmentInfo) Delete(name string) error {
+	panic("implement me")
+}
+
+func (e environmentInfo) GetByName(name string) (interface{}, error) {
+	panic("implement me")
+}
+
+func (e environmentInfo) GetByID(id int) (interface{}, error) {
+	panic("implement me")
+}
+
+func (e environmentInfo) List(key string) (interface{}, error) {
+	panic("implement me")
+}
+
+// containsValue reports whether value is one of the values of values.
+func containsValue(values map[string]string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func NewEnvironmentGenerator(ctx context.Context, kubeClient kubernetes.Interface, namespaces *NamespaceResolver, options func() *config.NamespaceOptions) syncer.Generator {
+	logger := utils.GetLogger(logrus.Fields{
+		"component": "kubernetes",
+		"resource":  "environment",
+	})
+	return &environmentInfo{
+		kubeClient: kubeClient,
+		options:    options,
+		namespaces: namespaces,
+		logger:     logger,
+		ctx:        ctx,
+	}
+}
@@ -0,0 +1,99 @@
+package resource
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/apis/tenant/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestEnvironmentGenerator(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewSimpleClientset()
+	options := &config.NamespaceOptions{
+		Environments: []*config.NamespaceEnvironmentOptions{
+			{
+				Env:        "uat",
+				Quota:      map[string]string{"requests.cpu": "4", "requests.memory": "8Gi"},
+				LimitRange: &config.LimitRangeOptions{Default: map[string]string{"memory": "512Mi"}},
+			},
+		},
+		AllowedNamespaces: []map[string]string{{"kubernetes.io/metadata.name": "ingress-nginx"}},
+	}
+	generator := NewEnvironmentGenerator(ctx, kubeClient, newTestResolver(), func() *config.NamespaceOptions {
+		return options
+	})
+
+	isolation := true
+	workspace := &v1alpha2.WorkspaceTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "devops",
+			Annotations: map[string]string{
+				"uat.quota.iceberg.io/requests.cpu":            "2",
+				"sit.limitrange.iceberg.io/max.memory":         "1Gi",
+				"fat.limitrange.iceberg.io/defaultRequest.cpu": "100m",
+			},
+		},
+	}
+	workspace.Spec.Template.Spec.NetworkIsolation = &isolation
+	if _, err := generator.Create(workspace); err != nil {
+		t.Fatal(err)
+	}
+
+	quota, err := kubeClient.CoreV1().ResourceQuotas("devops-uat").Get(ctx, EnvironmentQuota, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cpu, memory := quota.Spec.Hard["requests.cpu"], quota.Spec.Hard["requests.memory"]; cpu.String() != "2" || memory.String() != "8Gi" {
+		t.Errorf("unexpected uat quota %v", quota.Spec.Hard)
+	}
+	if _, err := kubeClient.CoreV1().ResourceQuotas("devops-fat").Get(ctx, EnvironmentQuota, metav1.GetOptions{}); err == nil {
+		t.Error("expected no quota in fat")
+	}
+	limitRange, err := kubeClient.CoreV1().LimitRanges("devops-sit").Get(ctx, EnvironmentLimitRange, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if max := limitRange.Spec.Limits[0].Max["memory"]; max.String() != "1Gi" {
+		t.Errorf("unexpected sit limit range %v", limitRange.Spec)
+	}
+	policy, err := kubeClient.NetworkingV1().NetworkPolicies("devops-fat").Get(ctx, EnvironmentNetworkIsolation, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peers := policy.Spec.Ingress[0].From; len(peers) != 2 || peers[0].NamespaceSelector.MatchLabels["kubesphere.io/workspace"] != "devops" {
+		t.Errorf("unexpected network policy %v", policy.Spec)
+	}
+
+	// templates removed and isolation turned off
+	options.Environments = nil
+	isolation = false
+	delete(workspace.Annotations, "uat.quota.iceberg.io/requests.cpu")
+	if err := generator.Update(nil, workspace); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kubeClient.CoreV1().ResourceQuotas("devops-uat").Get(ctx, EnvironmentQuota, metav1.GetOptions{}); err == nil {
+		t.Error("expected the uat quota to be deleted")
+	}
+	if _, err := kubeClient.NetworkingV1().NetworkPolicies("devops-fat").Get(ctx, EnvironmentNetworkIsolation, metav1.GetOptions{}); err == nil {
+		t.Error("expected the network policy to be deleted")
+	}
+
+	// an environment without namespace is retried, the others are hardened
+	options.Envs = []string{"fat", "uat", "sit", "dev"}
+	options.Environments = []*config.NamespaceEnvironmentOptions{{Env: "uat", Quota: map[string]string{"pods": "10"}}}
+	if _, err := generator.Create(workspace); err == nil {
+		t.Error("expected the environment without namespace to fail")
+	}
+	if _, err := kubeClient.CoreV1().ResourceQuotas("devops-uat").Get(ctx, EnvironmentQuota, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the uat quota, got %v", err)
+	}
+	options.Envs = nil
+
+	workspace.Annotations["uat.limitrange.iceberg.io/limit.cpu"] = "1"
+	if _, err := generator.Create(workspace); err == nil {
+		t.Error("expected an invalid limit range annotation to fail")
+	}
+}
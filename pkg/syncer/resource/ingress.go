@@ -0,0 +1,319 @@
+package resource
+
+import (
+	"context"
+	baseErr "errors"
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"github.com/hchenc/iceberg/pkg/utils"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sort"
+	"strings"
+)
+
+const ingressClassAnnotation = "kubernetes.io/ingress.class"
+
+// IngressConflictError is returned when the backends of an ingress don't
+// share one service, so no upstream vhost fits all of them.
+type IngressConflictError struct {
+	Services []string
+}
+
+func (e *IngressConflictError) Error() string {
+	return fmt.Sprintf("ingress routes to more than one service(%s), split it to set the upstream vhost", strings.Join(e.Services, ", "))
+}
+
+// IngressClass returns the class of an ingress, read from the spec or the
+// legacy annotation.
+func IngressClass(ingress *v1.Ingress) string {
+	if ingress.Spec.IngressClassName != nil {
+		return *ingress.Spec.IngressClassName
+	}
+	return ingress.Annotations[ingressClassAnnotation]
+}
+
+// IngressServices returns the sorted names of the services every rule, path
+// and the default backend of an ingress route to.
+func IngressServices(ingress *v1.Ingress) []string {
+	services := map[string]bool{}
+	if backend := ingress.Spec.DefaultBackend; backend != nil && backend.Service != nil {
+		services[backend.Service.Name] = true
+	}
+	for _, rule := range ingress.Spec.Rules {
+		for _, service := range ruleServices(rule) {
+			services[service] = true
+		}
+	}
+	var names []string
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func ruleServices(rule v1.IngressRule) []string {
+	var services []string
+	if rule.HTTP == nil {
+		return services
+	}
+	for _, path := range rule.HTTP.Paths {
+		if path.Backend.Service != nil {
+			services = append(services, path.Backend.Service.Name)
+		}
+	}
+	return services
+}
+
+// PatchIngress fills the hosts of rules without host from the domain pattern
+// and sets the upstream vhost annotation of the ingress class. An existing
+// annotation is left untouched, and hosts are left empty outside environment
+// namespaces. It reports whether the ingress changed, and an
+// IngressConflictError when the backends don't share one service.
+func PatchIngress(ingress *v1.Ingress, options *config.IngressOptions, namespaces *NamespaceResolver) (bool, error) {
+	var changed bool
+	workspace, env, err := namespaces.Resolve(ingress.Namespace)
+	if err != nil && !IsUnlabeledNamespace(err) {
+		return changed, err
+	}
+
+	for index := range ingress.Spec.Rules {
+		rule := &ingress.Spec.Rules[index]
+		if len(rule.Host) != 0 || len(env) == 0 {
+			continue
+		}
+		service := ingress.Name
+		if services := ruleServices(*rule); len(services) != 0 {
+			service = services[0]
+		}
+		host, err := options.Host(config.IngressHost{
+			Name:      ingress.Name,
+			Service:   service,
+			Namespace: ingress.Namespace,
+			Workspace: workspace,
+			Env:       env,
+		})
+		if err != nil {
+			return changed, err
+		}
+		if len(host) != 0 {
+			rule.Host = host
+			changed = true
+		}
+	}
+
+	class := options.GetClass(IngressClass(ingress))
+	if len(class.VhostAnnotation) == 0 {
+		return changed, nil
+	}
+	if vhost, exists := ingress.Annotations[class.VhostAnnotation]; exists && len(vhost) != 0 {
+		return changed, nil
+	}
+	services := IngressServices(ingress)
+	switch len(services) {
+	case 0:
+		return changed, nil
+	case 1:
+	default:
+		return changed, &IngressConflictError{Services: services}
+	}
+	vhost, err := class.Vhost(config.IngressVhost{
+		Service:   services[0],
+		Namespace: ingress.Namespace,
+	})
+	if err != nil {
+		return changed, err
+	}
+	if ingress.Annotations == nil {
+		ingress.Annotations = map[string]string{}
+	}
+	ingress.Annotations[class.VhostAnnotation] = vhost
+	return true, nil
+}
+
+type ingressInfo struct {
+	kubeClient kubernetes.Interface
+	namespaces *NamespaceResolver
+	options    func() *config.IngressOptions
+	overrides  OverridesFunc
+	logger     *logrus.Logger
+	ctx        context.Context
+}
+
+// Create copies the ingress into the sibling environments, hosts and tls
+// secret names are rewritten per environment. The upstream vhost annotation
+// is dropped since it names a service of the source namespace, the copies
+// get their own when the ingress controller patches them. Copies made by
+// iceberg are updated, they are labelled with their source namespace and
+// never propagated again.
+func (i ingressInfo) Create(obj interface{}) (interface{}, error) {
+	ingress := obj.(*v1.Ingress)
+	ingLogInfo := logrus.Fields{
+		"ingress": ingress.Name,
+	}
+	if IsExcluded(ingress) {
+		return nil, nil
+	}
+	workspace, sourceEnv, candidates, err := i.namespaces.Siblings(ingress.Namespace)
+	if err != nil {
+		return nil, skipUnlabeled(i.logger.WithFields(ingLogInfo), err)
+	}
+	candidates = propagationTargets(ingress, candidates)
+	var errs []error
+	options := i.options()
+
+	for namespace, env := range candidates {
+		copied, err := copyIngress(ingress, namespace, options, config.IngressCopy{
+			Name:      ingress.Name,
+			Workspace: workspace,
+			Namespace: namespace,
+			SourceEnv: sourceEnv,
+			Env:       env,
+		})
+		if err == nil {
+			err = applyOverrides(copied, "Ingress", workspace, env, i.overrides)
+		}
+		if err == nil {
+			err = i.sync(ingress, copied, options)
+		}
+		if err == nil {
+			i.logger.WithFields(ingLogInfo).WithFields(logrus.Fields{
+				"namespace": namespace,
+			}).Info("finish to create namespaced kubernetes ingress")
+		} else {
+			i.logger.WithFields(ingLogInfo).WithFields(logrus.Fields{
+				"namespace": namespace,
+				"message":   "failed to create namespaced kubernetes ingress",
+			}).Error(err)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return nil, baseErr.New("failed to sync kubernetes ingress")
+	} else {
+		i.logger.WithFields(ingLogInfo).Info("finish to sync kubesphere ingress")
+		return nil, nil
+	}
+}
+
+// sync creates copied or updates the copy made by iceberg, the upstream
+// vhost annotation the ingress controller set on the copy is kept.
+func (i ingressInfo) sync(source, copied *v1.Ingress, options *config.IngressOptions) error {
+	client := i.kubeClient.NetworkingV1().Ingresses(copied.Namespace)
+	_, err := client.Create(i.ctx, copied, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := client.Get(i.ctx, copied.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if existing.Labels[constants.IcebergPropagatedFrom] != source.Namespace {
+		i.logger.WithFields(logrus.Fields{
+			"ingress":   copied.Name,
+			"namespace": copied.Namespace,
+		}).Info("ingress not made by iceberg already exists, skip it")
+		return nil
+	}
+	vhostAnnotation := options.GetClass(IngressClass(copied)).VhostAnnotation
+	if vhost, exists := existing.Annotations[vhostAnnotation]; len(vhostAnnotation) != 0 && exists {
+		if copied.Annotations == nil {
+			copied.Annotations = map[string]string{}
+		}
+		copied.Annotations[vhostAnnotation] = vhost
+	}
+	copied.ResourceVersion = existing.ResourceVersion
+	_, err = client.Update(i.ctx, copied, metav1.UpdateOptions{})
+	return err
+}
+
+func copyIngress(ingress *v1.Ingress, namespace string, options *config.IngressOptions, data config.IngressCopy) (*v1.Ingress, error) {
+	environment := options.GetEnvironment(data.Env)
+	rewriteHost := func(host string) (string, error) {
+		data.Host = host
+		return environment.RewriteHost(data)
+	}
+
+	spec := ingress.Spec.DeepCopy()
+	for index := range spec.Rules {
+		host, err := rewriteHost(spec.Rules[index].Host)
+		if err != nil {
+			return nil, err
+		}
+		spec.Rules[index].Host = host
+	}
+	for index := range spec.TLS {
+		tls := &spec.TLS[index]
+		for hostIndex := range tls.Hosts {
+			host, err := rewriteHost(tls.Hosts[hostIndex])
+			if err != nil {
+				return nil, err
+			}
+			tls.Hosts[hostIndex] = host
+		}
+		data.SecretName = tls.SecretName
+		secretName, err := environment.RewriteTLSSecret(data)
+		if err != nil {
+			return nil, err
+		}
+		tls.SecretName = secretName
+	}
+
+	annotations := map[string]string{}
+	for key, value := range ingress.Annotations {
+		annotations[key] = value
+	}
+	delete(annotations, options.GetClass(IngressClass(ingress)).VhostAnnotation)
+
+	return &v1.Ingress{
+		TypeMeta: ingress.TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ingress.Name,
+			Namespace:   namespace,
+			Labels:      propagatedLabels(ingress),
+			Annotations: annotations,
+		},
+		Spec: *spec,
+	}, nil
+}
+
+func (i ingressInfo) Update(objOld interface{}, objNew interface{}) error {
+	panic("implement me")
+}
+
+func (i ingressInfo) Delete(name string) error {
+	panic("implement me")
+}
+
+func (i ingressInfo) GetByName(name string) (interface{}, error) {
+	panic("implement me")
+}
+
+func (i ingressInfo) GetByID(id int) (interface{}, error) {
+	panic("implement me")
+}
+
+func (i ingressInfo) List(key string) (interface{}, error) {
+	panic("implement me")
+}
+
+func NewIngressGenerator(ctx context.Context, kubeClient kubernetes.Interface, namespaces *NamespaceResolver, options func() *config.IngressOptions, overrides OverridesFunc) syncer.Generator {
+	logger := utils.GetLogger(logrus.Fields{
+		"component": "kubernetes",
+		"resource":  "ingress",
+	})
+	return &ingressInfo{
+		kubeClient: kubeClient,
+		namespaces: namespaces,
+		options:    options,
+		overrides:  overrides,
+		logger:     logger,
+		ctx:        ctx,
+	}
+}
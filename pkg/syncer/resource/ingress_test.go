@@ -0,0 +1,211 @@
+package resource
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/constants"
+	v1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func newIngress(class string, rules ...v1.IngressRule) *v1.Ingress {
+	ingress := &v1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "devops-fat",
+		},
+		Spec: v1.IngressSpec{
+			Rules: rules,
+		},
+	}
+	if len(class) != 0 {
+		ingress.Spec.IngressClassName = &class
+	}
+	return ingress
+}
+
+func newRule(host string, services ...string) v1.IngressRule {
+	rule := v1.IngressRule{Host: host}
+	if len(services) == 0 {
+		return rule
+	}
+	rule.HTTP = &v1.HTTPIngressRuleValue{}
+	for _, service := range services {
+		rule.HTTP.Paths = append(rule.HTTP.Paths, v1.HTTPIngressPath{
+			Path: "/" + service,
+			Backend: v1.IngressBackend{
+				Service: &v1.IngressServiceBackend{Name: service},
+			},
+		})
+	}
+	return rule
+}
+
+func TestPatchIngress(t *testing.T) {
+	options := &config.IngressOptions{
+		DomainPattern: "{{.Service}}.{{.Env}}.example.com",
+		Classes: []*config.IngressClassOptions{
+			{Name: "haproxy", VhostAnnotation: "haproxy-ingress.github.io/upstream-vhost"},
+			{Name: "traefik"},
+		},
+	}
+	resolver := newTestResolver()
+
+	// no rules at all must not panic
+	if changed, err := PatchIngress(newIngress(""), options, resolver); changed || err != nil {
+		t.Errorf("expected no change, got %v %v", changed, err)
+	}
+
+	ingress := newIngress("", newRule(""), newRule("", "api"), newRule("api.example.com", "api", "api"))
+	if changed, err := PatchIngress(ingress, options, resolver); !changed || err != nil {
+		t.Fatalf("expected change, got %v %v", changed, err)
+	}
+	if host := ingress.Spec.Rules[1].Host; host != "api.fat.example.com" {
+		t.Errorf("unexpected host %s", host)
+	}
+	if host := ingress.Spec.Rules[0].Host; host != "web.fat.example.com" {
+		t.Errorf("unexpected host %s", host)
+	}
+	if vhost := ingress.Annotations["nginx.ingress.kubernetes.io/upstream-vhost"]; vhost != "api.devops-fat.svc.cluster.local" {
+		t.Errorf("unexpected vhost %s", vhost)
+	}
+
+	ingress = newIngress("haproxy", newRule("a.example.com", "api"))
+	if _, err := PatchIngress(ingress, options, resolver); err != nil {
+		t.Fatal(err)
+	}
+	if vhost := ingress.Annotations["haproxy-ingress.github.io/upstream-vhost"]; vhost != "api.devops-fat.svc.cluster.local" {
+		t.Errorf("unexpected vhost %s", vhost)
+	}
+
+	ingress = newIngress("traefik", newRule("a.example.com", "api", "web"))
+	if changed, err := PatchIngress(ingress, options, resolver); changed || err != nil {
+		t.Errorf("expected no change for class without annotation, got %v %v", changed, err)
+	}
+
+	ingress = newIngress("", newRule("a.example.com", "api", "web"))
+	if _, err := PatchIngress(ingress, options, resolver); err == nil {
+		t.Error("expected conflict")
+	} else if conflict, ok := err.(*IngressConflictError); !ok || len(conflict.Services) != 2 {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// hosts are left empty outside environment namespaces
+	resolver = newTestResolver(newNamespace("devops-tools", "devops", ""))
+	ingress = newIngress("", newRule("", "api"))
+	ingress.Namespace = "devops-tools"
+	if _, err := PatchIngress(ingress, options, resolver); err != nil || len(ingress.Spec.Rules[0].Host) != 0 {
+		t.Errorf("expected no host, got %q %v", ingress.Spec.Rules[0].Host, err)
+	}
+}
+
+func TestIngressGenerator(t *testing.T) {
+	options := &config.IngressOptions{
+		Environments: []*config.IngressEnvironmentOptions{
+			{Env: "sit", HostTemplate: "{{.Subdomain}}-{{.Env}}.{{.Domain}}", TLSSecretTemplate: "{{.SecretName}}-{{.Env}}"},
+		},
+	}
+	kubeClient := fake.NewSimpleClientset()
+	generator := NewIngressGenerator(context.Background(), kubeClient, newTestResolver(), func() *config.IngressOptions {
+		return options
+	}, nil)
+
+	ingress := newIngress("", newRule("web.fat.example.com", "web"), newRule("api.example.com", "api"))
+	ingress.Annotations = map[string]string{
+		"nginx.ingress.kubernetes.io/upstream-vhost": "web.devops-fat.svc.cluster.local",
+		"nginx.ingress.kubernetes.io/rewrite-target": "/",
+	}
+	ingress.Spec.TLS = []v1.IngressTLS{{Hosts: []string{"web.fat.example.com"}, SecretName: "web-tls"}}
+	if _, err := generator.Create(ingress); err != nil {
+		t.Fatal(err)
+	}
+
+	uat, err := kubeClient.NetworkingV1().Ingresses("devops-uat").Get(context.Background(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hosts := []string{uat.Spec.Rules[0].Host, uat.Spec.Rules[1].Host, uat.Spec.TLS[0].Hosts[0]}; hosts[0] != "web.uat.example.com" || hosts[1] != "uat-api.example.com" || hosts[2] != "web.uat.example.com" {
+		t.Errorf("unexpected uat hosts %v", hosts)
+	}
+	if uat.Spec.TLS[0].SecretName != "web-tls" {
+		t.Errorf("unexpected uat tls secret %s", uat.Spec.TLS[0].SecretName)
+	}
+	if _, exists := uat.Annotations["nginx.ingress.kubernetes.io/upstream-vhost"]; exists || len(uat.Annotations) != 1 {
+		t.Errorf("unexpected uat annotations %v", uat.Annotations)
+	}
+
+	sit, err := kubeClient.NetworkingV1().Ingresses("devops-sit").Get(context.Background(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host := sit.Spec.Rules[0].Host; host != "web-sit.fat.example.com" {
+		t.Errorf("unexpected sit host %s", host)
+	}
+	if sit.Spec.TLS[0].SecretName != "web-tls-sit" {
+		t.Errorf("unexpected sit tls secret %s", sit.Spec.TLS[0].SecretName)
+	}
+	if _, err := kubeClient.NetworkingV1().Ingresses("devops-fat").Get(context.Background(), "web", metav1.GetOptions{}); err == nil {
+		t.Error("expected the source environment to be skipped")
+	}
+
+	// the copies are not propagated again, their hosts would be rewritten twice
+	if uat.Labels[constants.IcebergPropagatedFrom] != "devops-fat" || Propagates(uat, true) {
+		t.Errorf("expected the uat copy to be marked as propagated, got labels %v", uat.Labels)
+	}
+	kubeClient.ClearActions()
+	if _, err := generator.Create(uat); err != nil {
+		t.Fatal(err)
+	}
+	if actions := kubeClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected reconciling a copy to be a no-op, got %v", actions)
+	}
+}
+
+func TestIngressGeneratorUpdate(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewSimpleClientset()
+	overrides := []*config.EnvironmentOverride{
+		{Env: "uat", JSONPatch: []*config.PatchOperation{{Op: "add", Path: "/metadata/annotations/timeout", Value: "30"}}},
+	}
+	generator := NewIngressGenerator(ctx, kubeClient, newTestResolver(), func() *config.IngressOptions {
+		return &config.IngressOptions{}
+	}, func() []*config.EnvironmentOverride {
+		return overrides
+	})
+
+	ingress := newIngress("", newRule("web.fat.example.com", "web"))
+	ingress.Annotations = map[string]string{"owner": "web"}
+	if _, err := generator.Create(ingress); err != nil {
+		t.Fatal(err)
+	}
+	// the ingress controller sets the upstream vhost of the copy
+	uat, _ := kubeClient.NetworkingV1().Ingresses("devops-uat").Get(ctx, "web", metav1.GetOptions{})
+	uat.Annotations["nginx.ingress.kubernetes.io/upstream-vhost"] = "web.devops-uat.svc.cluster.local"
+	kubeClient.NetworkingV1().Ingresses("devops-uat").Update(ctx, uat, metav1.UpdateOptions{})
+
+	overrides[0].JSONPatch[0].Value = "60"
+	if _, err := generator.Create(ingress); err != nil {
+		t.Fatal(err)
+	}
+	uat, _ = kubeClient.NetworkingV1().Ingresses("devops-uat").Get(ctx, "web", metav1.GetOptions{})
+	if uat.Annotations["timeout"] != "60" || uat.Annotations["nginx.ingress.kubernetes.io/upstream-vhost"] != "web.devops-uat.svc.cluster.local" {
+		t.Errorf("expected the changed override to update the copy and keep its vhost, got %v", uat.Annotations)
+	}
+
+	// ingresses not made by iceberg are left untouched
+	local := newIngress("", newRule("local.example.com", "web"))
+	local.Name, local.Namespace = "local", "devops-uat"
+	kubeClient.NetworkingV1().Ingresses("devops-uat").Create(ctx, local, metav1.CreateOptions{})
+	source := newIngress("", newRule("web.fat.example.com", "web"))
+	source.Name = "local"
+	source.Annotations = map[string]string{"owner": "web"}
+	if _, err := generator.Create(source); err != nil {
+		t.Fatal(err)
+	}
+	local, _ = kubeClient.NetworkingV1().Ingresses("devops-uat").Get(ctx, "local", metav1.GetOptions{})
+	if local.Spec.Rules[0].Host != "local.example.com" || len(local.Annotations) != 0 {
+		t.Errorf("expected the ingress not made by iceberg to be kept, got %v", local)
+	}
+}
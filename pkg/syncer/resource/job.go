@@ -0,0 +1,99 @@
+package resource
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type jobWorkload struct{}
+
+func (jobWorkload) clone(obj interface{}, namespace string, transform *workloadTransform) interface{} {
+	job := obj.(*batchv1.Job).DeepCopy()
+	job.ObjectMeta = cloneObjectMeta(job.ObjectMeta, namespace)
+	job.Status = batchv1.JobStatus{}
+	cloneJobSpec(&job.Spec, transform)
+	return job
+}
+
+func (jobWorkload) exists(ctx context.Context, kubeClient kubernetes.Interface, namespace, selector string) (bool, error) {
+	jobs, err := kubeClient.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return false, err
+	}
+	return len(jobs.Items) != 0, nil
+}
+
+func (jobWorkload) get(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string) (metav1.Object, error) {
+	return kubeClient.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (jobWorkload) create(ctx context.Context, kubeClient kubernetes.Interface, obj interface{}) error {
+	job := obj.(*batchv1.Job)
+	_, err := kubeClient.BatchV1().Jobs(job.Namespace).Create(ctx, job, metav1.CreateOptions{})
+	return err
+}
+
+func (jobWorkload) update(ctx context.Context, kubeClient kubernetes.Interface, obj interface{}, existing metav1.Object) error {
+	job := obj.(*batchv1.Job)
+	// the spec of a job can't be updated once it runs, only its metadata is
+	job.Spec = existing.(*batchv1.Job).Spec
+	_, err := kubeClient.BatchV1().Jobs(job.Namespace).Update(ctx, job, metav1.UpdateOptions{})
+	return err
+}
+
+type cronJobWorkload struct{}
+
+func (cronJobWorkload) clone(obj interface{}, namespace string, transform *workloadTransform) interface{} {
+	cronJob := obj.(*batchv1.CronJob).DeepCopy()
+	cronJob.ObjectMeta = cloneObjectMeta(cronJob.ObjectMeta, namespace)
+	cronJob.Status = batchv1.CronJobStatus{}
+	cloneJobSpec(&cronJob.Spec.JobTemplate.Spec, transform)
+	return cronJob
+}
+
+func (cronJobWorkload) exists(ctx context.Context, kubeClient kubernetes.Interface, namespace, selector string) (bool, error) {
+	cronJobs, err := kubeClient.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return false, err
+	}
+	return len(cronJobs.Items) != 0, nil
+}
+
+func (cronJobWorkload) get(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string) (metav1.Object, error) {
+	return kubeClient.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (cronJobWorkload) create(ctx context.Context, kubeClient kubernetes.Interface, obj interface{}) error {
+	cronJob := obj.(*batchv1.CronJob)
+	_, err := kubeClient.BatchV1().CronJobs(cronJob.Namespace).Create(ctx, cronJob, metav1.CreateOptions{})
+	return err
+}
+
+func (cronJobWorkload) update(ctx context.Context, kubeClient kubernetes.Interface, obj interface{}, existing metav1.Object) error {
+	cronJob := obj.(*batchv1.CronJob)
+	_, err := kubeClient.BatchV1().CronJobs(cronJob.Namespace).Update(ctx, cronJob, metav1.UpdateOptions{})
+	return err
+}
+
+// cloneJobSpec drops the selector the job controller generated for the
+// source, the copy gets its own.
+func cloneJobSpec(spec *batchv1.JobSpec, transform *workloadTransform) {
+	if spec.ManualSelector == nil || !*spec.ManualSelector {
+		spec.Selector = nil
+		for _, label := range strippedTemplateLabels {
+			delete(spec.Template.Labels, label)
+		}
+	}
+	transform.apply(&spec.Template)
+}
+
+func NewJobGenerator(ctx context.Context, kubeClient kubernetes.Interface, namespaces *NamespaceResolver, overrides OverridesFunc) syncer.Generator {
+	return newWorkloadGenerator(ctx, kubeClient, namespaces, overrides, "job", jobWorkload{})
+}
+
+func NewCronJobGenerator(ctx context.Context, kubeClient kubernetes.Interface, namespaces *NamespaceResolver, overrides OverridesFunc) syncer.Generator {
+	return newWorkloadGenerator(ctx, kubeClient, namespaces, overrides, "cronjob", cronJobWorkload{})
+}
@@ -3,8 +3,10 @@ package resource
 import (
 	"context"
 	baseErr "errors"
+	"fmt"
 	tenantv1alpha1 "github.com/hchenc/iceberg/pkg/apis/tenant/v1alpha1"
 	"github.com/hchenc/iceberg/pkg/apis/tenant/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/config"
 	"github.com/hchenc/iceberg/pkg/constants"
 	"github.com/hchenc/iceberg/pkg/syncer"
 	"github.com/hchenc/iceberg/pkg/utils"
@@ -12,6 +14,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -23,10 +26,17 @@ var (
 	}
 )
 
+// EnvironmentNamespace is the name of the namespace created for env in
+// workspace. Namespaces are resolved by their labels, never by this name.
+func EnvironmentNamespace(workspace, env string) string {
+	return workspace + "-" + env
+}
+
 type namespaceInfo struct {
-	client *kubernetes.Clientset
-	logger *logrus.Logger
-	ctx    context.Context
+	client  *kubernetes.Clientset
+	options func() *config.NamespaceOptions
+	logger  *logrus.Logger
+	ctx     context.Context
 }
 
 func (n namespaceInfo) Create(obj interface{}) (interface{}, error) {
@@ -36,14 +46,17 @@ func (n namespaceInfo) Create(obj interface{}) (interface{}, error) {
 		"workspace": workspaceName,
 	}
 	var errs []error
-	candidates := map[string]string{
-		"fat": workspaceName + "-fat",
-		"uat": workspaceName + "-uat",
-		"sit": workspaceName + "-sit",
+	candidates := map[string]string{}
+	for _, env := range n.options().GetEnvs() {
+		candidates[env] = EnvironmentNamespace(workspaceName, env)
 	}
 	creator := workspace.GetAnnotations()[constants.KubesphereCreator]
 
 	for index, namespaceName := range candidates {
+		description, exists := env[index]
+		if !exists {
+			description = index
+		}
 		namespace := assembleResource(workspace, namespaceName, func(obj interface{}, namespace string) interface{} {
 			return &v1.Namespace{
 				ObjectMeta: metav1.ObjectMeta{
@@ -61,16 +74,20 @@ func (n namespaceInfo) Create(obj interface{}) (interface{}, error) {
 						"kubernetes.io/metadata.name": namespaceName,
 						"kubesphere.io/namespace":     namespaceName,
 						"kubesphere.io/workspace":     workspaceName,
+						constants.IcebergEnvironment:  index,
 					},
 					Annotations: map[string]string{
 						"kubesphere.io/creator":     creator,
-						"kubesphere.io/description": env[index],
+						"kubesphere.io/description": description,
 					},
 				},
 			}
 		}).(*v1.Namespace)
 		_, err := n.client.CoreV1().Namespaces().Create(n.ctx, namespace, metav1.CreateOptions{})
-		if err == nil || errors.IsAlreadyExists(err) {
+		if errors.IsAlreadyExists(err) {
+			err = n.labelEnvironment(workspaceName, namespaceName, index)
+		}
+		if err == nil {
 			n.logger.WithFields(nsLogInfo).WithFields(logrus.Fields{
 				"namespace": namespace.Name,
 			}).Info("finish to create namespaced kubernetes namespace")
@@ -90,6 +107,30 @@ func (n namespaceInfo) Create(obj interface{}) (interface{}, error) {
 	}
 }
 
+// labelEnvironment adds the environment label to an existing namespace of
+// the workspace, namespaces created before the label existed or by hand are
+// resolved afterwards. Namespaces of other workspaces are left untouched.
+func (n namespaceInfo) labelEnvironment(workspaceName, namespaceName, env string) error {
+	namespace, err := n.client.CoreV1().Namespaces().Get(n.ctx, namespaceName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if namespace.Labels[constants.KubesphereWorkspace] != workspaceName {
+		n.logger.WithFields(logrus.Fields{
+			"workspace": workspaceName,
+			"namespace": namespaceName,
+			"owner":     namespace.Labels[constants.KubesphereWorkspace],
+		}).Warn("namespace belongs to another workspace, skip it")
+		return nil
+	}
+	if _, exists := namespace.Labels[constants.IcebergEnvironment]; exists {
+		return nil
+	}
+	patch := fmt.Sprintf(`{"metadata":{"labels":{%q:%q}}}`, constants.IcebergEnvironment, env)
+	_, err = n.client.CoreV1().Namespaces().Patch(n.ctx, namespaceName, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
+
 func (n namespaceInfo) Update(objOld interface{}, objNew interface{}) error {
 	panic("implement me")
 }
@@ -113,14 +154,15 @@ func (n namespaceInfo) List(key string) (interface{}, error) {
 	panic("implement me")
 }
 
-func NewNamespaceGenerator(ctx context.Context, client *kubernetes.Clientset) syncer.Generator {
+func NewNamespaceGenerator(ctx context.Context, client *kubernetes.Clientset, options func() *config.NamespaceOptions) syncer.Generator {
 	logger := utils.GetLogger(logrus.Fields{
 		"component": "kubernetes",
 		"resource":  "namespace",
 	})
 	return &namespaceInfo{
-		client: client,
-		ctx:    ctx,
-		logger: logger,
+		client:  client,
+		options: options,
+		ctx:     ctx,
+		logger:  logger,
 	}
 }
@@ -0,0 +1,148 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"sort"
+)
+
+// UnlabeledNamespaceError is returned for a namespace missing the workspace
+// or the environment label, its resources aren't propagated.
+type UnlabeledNamespaceError struct {
+	Namespace string
+	Label     string
+}
+
+func (e *UnlabeledNamespaceError) Error() string {
+	return fmt.Sprintf("namespace %s isn't an environment namespace, it has no %s label", e.Namespace, e.Label)
+}
+
+// IsUnlabeledNamespace reports whether err is an UnlabeledNamespaceError.
+func IsUnlabeledNamespace(err error) bool {
+	_, ok := err.(*UnlabeledNamespaceError)
+	return ok
+}
+
+// NamespaceResolver resolves the workspace and the environment of namespaces
+// from their kubesphere.io/workspace and iceberg.io/environment labels.
+type NamespaceResolver struct {
+	lister corelisters.NamespaceLister
+}
+
+func NewNamespaceResolver(lister corelisters.NamespaceLister) *NamespaceResolver {
+	return &NamespaceResolver{
+		lister: lister,
+	}
+}
+
+// NewCachedNamespaceResolver starts a namespace informer and returns a
+// resolver reading from its cache once it's synced.
+func NewCachedNamespaceResolver(ctx context.Context, kubeClient kubernetes.Interface) (*NamespaceResolver, error) {
+	factory := informers.NewSharedInformerFactory(kubeClient, 0)
+	lister := factory.Core().V1().Namespaces().Lister()
+	factory.Start(ctx.Done())
+	for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return nil, fmt.Errorf("failed to sync the cache of %v", informerType)
+		}
+	}
+	return NewNamespaceResolver(lister), nil
+}
+
+// Resolve returns the workspace and the environment of namespace, an
+// UnlabeledNamespaceError when it lacks either label.
+func (r *NamespaceResolver) Resolve(namespace string) (workspace, env string, err error) {
+	ns, err := r.lister.Get(namespace)
+	if err != nil {
+		return "", "", err
+	}
+	if workspace = ns.Labels[constants.KubesphereWorkspace]; len(workspace) == 0 {
+		return "", "", &UnlabeledNamespaceError{Namespace: namespace, Label: constants.KubesphereWorkspace}
+	}
+	if env = ns.Labels[constants.IcebergEnvironment]; len(env) == 0 {
+		return "", "", &UnlabeledNamespaceError{Namespace: namespace, Label: constants.IcebergEnvironment}
+	}
+	return workspace, env, nil
+}
+
+// Labels returns the labels of namespace.
+func (r *NamespaceResolver) Labels(namespace string) (map[string]string, error) {
+	ns, err := r.lister.Get(namespace)
+	if err != nil {
+		return nil, err
+	}
+	return ns.Labels, nil
+}
+
+// Environments returns the environment namespaces of workspace keyed by
+// namespace with their environment as value.
+func (r *NamespaceResolver) Environments(workspace string) (map[string]string, error) {
+	hasEnv, err := labels.NewRequirement(constants.IcebergEnvironment, selection.Exists, nil)
+	if err != nil {
+		return nil, err
+	}
+	selector := labels.SelectorFromSet(labels.Set{constants.KubesphereWorkspace: workspace}).Add(*hasEnv)
+	namespaces, err := r.lister.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	environments := map[string]string{}
+	for _, ns := range namespaces {
+		if env := ns.Labels[constants.IcebergEnvironment]; len(env) != 0 {
+			environments[ns.Name] = env
+		}
+	}
+	return environments, nil
+}
+
+// Namespaces returns the namespace of every environment of workspace, the
+// first one by name when an environment has several.
+func (r *NamespaceResolver) Namespaces(workspace string) (map[string]string, error) {
+	environments, err := r.Environments(workspace)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(environments))
+	for namespace := range environments {
+		names = append(names, namespace)
+	}
+	sort.Strings(names)
+	namespaces := map[string]string{}
+	for _, namespace := range names {
+		if _, exists := namespaces[environments[namespace]]; !exists {
+			namespaces[environments[namespace]] = namespace
+		}
+	}
+	return namespaces, nil
+}
+
+// Siblings resolves namespace and returns the other environment namespaces
+// of its workspace, keyed by namespace with their environment as value.
+func (r *NamespaceResolver) Siblings(namespace string) (workspace, env string, siblings map[string]string, err error) {
+	if workspace, env, err = r.Resolve(namespace); err != nil {
+		return "", "", nil, err
+	}
+	if siblings, err = r.Environments(workspace); err != nil {
+		return "", "", nil, err
+	}
+	delete(siblings, namespace)
+	return workspace, env, siblings, nil
+}
+
+// skipUnlabeled logs err and drops it when the namespace isn't an
+// environment namespace, which retrying doesn't change.
+func skipUnlabeled(logger *logrus.Entry, err error) error {
+	if IsUnlabeledNamespace(err) {
+		logger.Warn(err)
+		return nil
+	}
+	logger.WithField("message", "failed to resolve environment namespace").Error(err)
+	return err
+}
@@ -0,0 +1,74 @@
+package resource
+
+import (
+	"github.com/hchenc/iceberg/pkg/constants"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"testing"
+)
+
+func newNamespace(name, workspace, env string) *v1.Namespace {
+	namespace := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}},
+	}
+	if len(workspace) != 0 {
+		namespace.Labels[constants.KubesphereWorkspace] = workspace
+	}
+	if len(env) != 0 {
+		namespace.Labels[constants.IcebergEnvironment] = env
+	}
+	return namespace
+}
+
+// newTestResolver returns a resolver of the environment namespaces of the
+// devops workspace and of namespaces.
+func newTestResolver(namespaces ...*v1.Namespace) *NamespaceResolver {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, env := range []string{"fat", "uat", "sit"} {
+		_ = indexer.Add(newNamespace("devops-"+env, "devops", env))
+	}
+	for _, namespace := range namespaces {
+		_ = indexer.Add(namespace)
+	}
+	return NewNamespaceResolver(corelisters.NewNamespaceLister(indexer))
+}
+
+func TestNamespaceResolver(t *testing.T) {
+	resolver := newTestResolver(
+		newNamespace("team-a-dev", "team-a", "dev"),
+		newNamespace("team-a-prod", "team-a", "prod"),
+		newNamespace("team-a-tools", "team-a", ""),
+		newNamespace("kube-system", "", ""),
+	)
+
+	if workspace, env, err := resolver.Resolve("team-a-prod"); err != nil || workspace != "team-a" || env != "prod" {
+		t.Errorf("expected team-a prod, got %s %s %v", workspace, env, err)
+	}
+	if _, _, err := resolver.Resolve("team-a-tools"); !IsUnlabeledNamespace(err) {
+		t.Errorf("expected a namespace without environment to be rejected, got %v", err)
+	}
+	if _, _, err := resolver.Resolve("kube-system"); !IsUnlabeledNamespace(err) {
+		t.Errorf("expected a namespace without workspace to be rejected, got %v", err)
+	}
+	if _, _, err := resolver.Resolve("missing"); err == nil || IsUnlabeledNamespace(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	workspace, env, siblings, err := resolver.Siblings("team-a-dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if workspace != "team-a" || env != "dev" || len(siblings) != 1 || siblings["team-a-prod"] != "prod" {
+		t.Errorf("unexpected siblings %s %s %v", workspace, env, siblings)
+	}
+
+	namespaces, err := resolver.Namespaces("devops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(namespaces) != 3 || namespaces["uat"] != "devops-uat" {
+		t.Errorf("unexpected namespaces %v", namespaces)
+	}
+}
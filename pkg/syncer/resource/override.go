@@ -0,0 +1,65 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/hchenc/iceberg/pkg/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"reflect"
+)
+
+// OverridesFunc returns the environment overrides currently configured.
+type OverridesFunc func() []*config.EnvironmentOverride
+
+// applyOverrides patches obj, the copy of a resource of kind in the env
+// namespace of workspace, with the matching environment overrides in order.
+func applyOverrides(obj metav1.Object, kind, workspace, env string, overrides OverridesFunc) error {
+	if overrides == nil {
+		return nil
+	}
+	var matched []*config.EnvironmentOverride
+	for _, override := range overrides() {
+		if override != nil && override.Matches(kind, workspace, env, obj) {
+			matched = append(matched, override)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	for _, override := range matched {
+		if len(override.JSONPatch) != 0 {
+			document, err := override.JSONPatchDocument()
+			if err != nil {
+				return err
+			}
+			patch, err := jsonpatch.DecodePatch(document)
+			if err != nil {
+				return fmt.Errorf("invalid json patch of %s override: %v", override.Env, err)
+			}
+			if data, err = patch.Apply(data); err != nil {
+				return fmt.Errorf("failed to apply json patch of %s override: %v", override.Env, err)
+			}
+		}
+		if len(override.StrategicMergePatch) != 0 {
+			document, err := override.StrategicMergePatchDocument()
+			if err != nil {
+				return err
+			}
+			if data, err = strategicpatch.StrategicMergePatch(data, document, obj); err != nil {
+				return fmt.Errorf("failed to apply strategic merge patch of %s override: %v", override.Env, err)
+			}
+		}
+	}
+
+	// decode into a zeroed object, json.Unmarshal merges maps otherwise
+	value := reflect.ValueOf(obj).Elem()
+	value.Set(reflect.Zero(value.Type()))
+	return json.Unmarshal(data, obj)
+}
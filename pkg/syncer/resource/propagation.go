@@ -0,0 +1,89 @@
+package resource
+
+import (
+	"github.com/hchenc/iceberg/pkg/constants"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+)
+
+// excludedSecretTypes are bound to their namespace or owned by a tool, they
+// are never propagated
+var excludedSecretTypes = map[v1.SecretType]bool{
+	v1.SecretTypeServiceAccountToken: true,
+	v1.SecretTypeBootstrapToken:      true,
+	"helm.sh/release.v1":             true,
+}
+
+// excludedNames are created in every namespace by the cluster
+var excludedNames = map[string]bool{
+	"kube-root-ca.crt":         true,
+	"openshift-service-ca.crt": true,
+}
+
+// IsExcluded reports whether obj is never propagated whatever its
+// annotations: copies made by iceberg, the pull secret iceberg creates in
+// every environment and the objects IsClusterManaged reports.
+func IsExcluded(obj metav1.Object) bool {
+	return IsPropagated(obj) || obj.GetName() == constants.RegistryPullSecret || IsClusterManaged(obj)
+}
+
+// IsClusterManaged reports whether obj is managed by the cluster it lives
+// in: objects managed by a controller, the objects the cluster creates in
+// every namespace and secrets of the types in excludedSecretTypes.
+func IsClusterManaged(obj metav1.Object) bool {
+	if metav1.GetControllerOf(obj) != nil || excludedNames[obj.GetName()] {
+		return true
+	}
+	if secret, ok := obj.(*v1.Secret); ok {
+		return excludedSecretTypes[secret.Type]
+	}
+	return false
+}
+
+// Propagates reports whether obj is propagated to the other environments.
+// The iceberg.io/propagate annotation decides, then iceberg.io/propagate-to
+// which opts in, byDefault is the rule of the controller otherwise.
+func Propagates(obj metav1.Object, byDefault bool) bool {
+	if IsExcluded(obj) {
+		return false
+	}
+	annotations := obj.GetAnnotations()
+	switch strings.ToLower(annotations[constants.IcebergPropagate]) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if len(propagationEnvironments(obj)) != 0 {
+		return true
+	}
+	return byDefault
+}
+
+// propagationTargets drops the siblings whose environment isn't listed in
+// the iceberg.io/propagate-to annotation of obj, siblings are kept without
+// it.
+func propagationTargets(obj metav1.Object, siblings map[string]string) map[string]string {
+	environments := propagationEnvironments(obj)
+	if len(environments) == 0 {
+		return siblings
+	}
+	targets := map[string]string{}
+	for namespace, env := range siblings {
+		if environments[env] {
+			targets[namespace] = env
+		}
+	}
+	return targets
+}
+
+func propagationEnvironments(obj metav1.Object) map[string]bool {
+	environments := map[string]bool{}
+	for _, env := range strings.Split(obj.GetAnnotations()[constants.IcebergPropagateTo], ",") {
+		if env = strings.TrimSpace(env); len(env) != 0 {
+			environments[env] = true
+		}
+	}
+	return environments
+}
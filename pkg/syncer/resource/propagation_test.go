@@ -0,0 +1,53 @@
+package resource
+
+import (
+	"github.com/hchenc/iceberg/pkg/constants"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestPropagates(t *testing.T) {
+	controller := true
+	newObject := func(name string, annotations map[string]string) metav1.Object {
+		return &metav1.ObjectMeta{Name: name, Namespace: "devops-fat", Annotations: annotations}
+	}
+	owned := newObject("web-7d4b9", nil)
+	owned.SetOwnerReferences([]metav1.OwnerReference{{Kind: "Deployment", Name: "web", Controller: &controller}})
+	copied := newObject("web", map[string]string{constants.IcebergPropagate: "true"})
+	copied.SetLabels(map[string]string{constants.IcebergPropagatedFrom: "devops-fat"})
+
+	for _, c := range []struct {
+		name      string
+		obj       metav1.Object
+		byDefault bool
+		expected  bool
+	}{
+		{"default", newObject("web", nil), true, true},
+		{"default off", newObject("web", nil), false, false},
+		{"opt in", newObject("web", map[string]string{constants.IcebergPropagate: "true"}), false, true},
+		{"opt out", newObject("web", map[string]string{constants.IcebergPropagate: "False"}), true, false},
+		{"propagate-to opts in", newObject("web", map[string]string{constants.IcebergPropagateTo: "uat"}), false, true},
+		{"opt out wins over propagate-to", newObject("web", map[string]string{constants.IcebergPropagate: "false", constants.IcebergPropagateTo: "uat"}), true, false},
+		{"copy", copied, true, false},
+		{"owned", owned, true, false},
+		{"root ca", newObject("kube-root-ca.crt", map[string]string{constants.IcebergPropagate: "true"}), true, false},
+		{"pull secret", newObject(constants.RegistryPullSecret, nil), true, false},
+		{"helm release", &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "sh.helm.release.v1.web.v1"}, Type: "helm.sh/release.v1"}, true, false},
+		{"token", &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "default-token-x"}, Type: v1.SecretTypeServiceAccountToken}, true, false},
+		{"opaque", &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db"}, Type: v1.SecretTypeOpaque}, true, true},
+	} {
+		if propagates := Propagates(c.obj, c.byDefault); propagates != c.expected {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, propagates)
+		}
+	}
+
+	siblings := map[string]string{"devops-uat": "uat", "devops-sit": "sit"}
+	if targets := propagationTargets(newObject("web", nil), siblings); len(targets) != 2 {
+		t.Errorf("expected every sibling without propagate-to, got %v", targets)
+	}
+	targets := propagationTargets(newObject("web", map[string]string{constants.IcebergPropagateTo: " sit, prod"}), siblings)
+	if len(targets) != 1 || targets["devops-sit"] != "sit" {
+		t.Errorf("expected devops-sit only, got %v", targets)
+	}
+}
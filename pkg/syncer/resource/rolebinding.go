@@ -3,19 +3,29 @@ package resource
 import (
 	"context"
 	baseErr "errors"
+	"fmt"
 	"github.com/hchenc/iceberg/pkg/apis/iam/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/config"
 	"github.com/hchenc/iceberg/pkg/constants"
 	"github.com/hchenc/iceberg/pkg/syncer"
 	"github.com/hchenc/iceberg/pkg/utils"
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"strings"
 )
 
+// RolebindingSuffix is the suffix of the RoleBinding of a workspace member in
+// each environment namespace.
+const RolebindingSuffix = "-operator"
+
 type rolebindingInfo struct {
-	kubeclient *kubernetes.Clientset
+	kubeclient kubernetes.Interface
+	options    func() *config.RoleOptions
+	namespaces *NamespaceResolver
 	logger     *logrus.Logger
 	ctx        context.Context
 }
@@ -24,23 +34,38 @@ func (r rolebindingInfo) Create(obj interface{}) (interface{}, error) {
 	workspaceRolebinding := obj.(*v1alpha2.WorkspaceRoleBinding)
 	workspaceName := workspaceRolebinding.Labels[constants.KubesphereWorkspace]
 	userName := workspaceRolebinding.Subjects[0].Name
+	workspaceRole := strings.TrimPrefix(workspaceRolebinding.RoleRef.Name, workspaceName+"-")
+	options := r.options()
 
 	rbLogInfo := logrus.Fields{
 		"rolebinding": workspaceRolebinding.Name,
 	}
+	candidates, err := r.namespaces.Environments(workspaceName)
+	if err != nil {
+		return nil, err
+	} else if len(candidates) == 0 {
+		// retried until the namespace generator created them
+		return nil, fmt.Errorf("workspace %s has no environment namespace yet", workspaceName)
+	}
+
 	var errs []error
 
-	candidates := map[string]string{
-		workspaceName + "-fat": "fat",
-		workspaceName + "-uat": "uat",
-		workspaceName + "-sit": "sit",
-	}
+	for namespace, env := range candidates {
+		roleRef := options.GetRoleRef(env, workspaceRole)
+		if err := r.syncRole(namespace, roleRef, options); err != nil {
+			r.logger.WithFields(rbLogInfo).WithFields(logrus.Fields{
+				"namespace": namespace,
+				"role":      roleRef.Name,
+				"message":   "failed to sync namespaced role",
+			}).Error(err)
+			errs = append(errs, err)
+			continue
+		}
 
-	for namespace := range candidates {
 		rolebinding := assembleResource(workspaceRolebinding, namespace, func(obj interface{}, namespace string) interface{} {
 			return &v1.RoleBinding{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      userName + "-operator",
+					Name:      userName + RolebindingSuffix,
 					Namespace: namespace,
 					Labels: map[string]string{
 						"iam.kubesphere.io/user-ref": userName,
@@ -55,15 +80,15 @@ func (r rolebindingInfo) Create(obj interface{}) (interface{}, error) {
 				},
 				RoleRef: v1.RoleRef{
 					APIGroup: "rbac.authorization.k8s.io",
-					Kind:     "Role",
-					Name:     "operator",
+					Kind:     roleRef.Kind,
+					Name:     roleRef.Name,
 				},
 			}
 		}).(*v1.RoleBinding)
-		_, err := r.kubeclient.RbacV1().RoleBindings(namespace).Create(r.ctx, rolebinding, metav1.CreateOptions{})
-		if err == nil || errors.IsAlreadyExists(err) {
+		if err := r.syncRolebinding(rolebinding); err == nil {
 			r.logger.WithFields(rbLogInfo).WithFields(logrus.Fields{
 				"namespace": namespace,
+				"role":      roleRef.Kind + "/" + roleRef.Name,
 			}).Info("finish to create namespaced kubesphere rolebinding")
 		} else {
 			r.logger.WithFields(rbLogInfo).WithFields(logrus.Fields{
@@ -81,8 +106,61 @@ func (r rolebindingInfo) Create(obj interface{}) (interface{}, error) {
 	}
 }
 
+// syncRole creates or updates the Role referenced by roleRef from its
+// template. Roles without a template and ClusterRoles are left untouched.
+func (r rolebindingInfo) syncRole(namespace string, roleRef config.RoleRef, options *config.RoleOptions) error {
+	if roleRef.Kind != config.RoleKindRole {
+		return nil
+	}
+	template := options.GetTemplate(roleRef.Name)
+	if template == nil {
+		return nil
+	}
+
+	client := r.kubeclient.RbacV1().Roles(namespace)
+	role := &v1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: roleRef.Name, Namespace: namespace, Labels: managedLabels},
+		Rules:      template.PolicyRules(),
+	}
+	existing, err := client.Get(r.ctx, roleRef.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = client.Create(r.ctx, role, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(existing.Rules, role.Rules) {
+		return nil
+	}
+	existing.Rules = role.Rules
+	_, err = client.Update(r.ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// syncRolebinding creates the RoleBinding, or recreates it when the bound role
+// has changed since the role reference of a RoleBinding is immutable.
+func (r rolebindingInfo) syncRolebinding(rolebinding *v1.RoleBinding) error {
+	client := r.kubeclient.RbacV1().RoleBindings(rolebinding.Namespace)
+	existing, err := client.Get(r.ctx, rolebinding.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = client.Create(r.ctx, rolebinding, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+	if existing.RoleRef == rolebinding.RoleRef {
+		return nil
+	}
+	if err := client.Delete(r.ctx, existing.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	_, err = client.Create(r.ctx, rolebinding, metav1.CreateOptions{})
+	return err
+}
+
 func (r rolebindingInfo) Update(objOld interface{}, objNew interface{}) error {
-	panic("implement me")
+	_, err := r.Create(objNew)
+	return err
 }
 
 func (r rolebindingInfo) Delete(name string) error {
@@ -101,13 +179,15 @@ func (r rolebindingInfo) List(key string) (interface{}, error) {
 	panic("implement me")
 }
 
-func NewRolebindingGenerator(ctx context.Context, kubeclient *kubernetes.Clientset) syncer.Generator {
+func NewRolebindingGenerator(ctx context.Context, kubeclient kubernetes.Interface, namespaces *NamespaceResolver, options func() *config.RoleOptions) syncer.Generator {
 	logger := utils.GetLogger(logrus.Fields{
 		"component": "kubesphere",
 		"resource":  "rolebinding",
 	})
 	return rolebindingInfo{
 		kubeclient: kubeclient,
+		options:    options,
+		namespaces: namespaces,
 		ctx:        ctx,
 		logger:     logger,
 	}
@@ -0,0 +1,96 @@
+package resource
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/apis/iam/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/constants"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestRolebindingGenerator(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewSimpleClientset()
+	options := &config.RoleOptions{
+		Templates: []*config.RoleTemplate{
+			{Name: "developer", Rules: []*config.PolicyRule{{Verbs: []string{"*"}, APIGroups: []string{"", "apps"}, Resources: []string{"*"}}}},
+		},
+		Environments: []*config.RoleEnvironmentOptions{
+			{
+				Env:     "fat",
+				Roles:   []*config.RoleMapping{{WorkspaceRole: "viewer", Kind: "ClusterRole", Name: "view"}},
+				Default: &config.RoleRef{Name: "developer"},
+			},
+			{
+				Env:     "uat",
+				Default: &config.RoleRef{Kind: "ClusterRole", Name: "view"},
+			},
+		},
+	}
+	generator := NewRolebindingGenerator(ctx, kubeClient, newTestResolver(), func() *config.RoleOptions {
+		return options
+	})
+
+	workspaceRolebinding := &v1alpha2.WorkspaceRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "tom-devops-regular",
+			Labels: map[string]string{constants.KubesphereWorkspace: "devops"},
+		},
+		Subjects: []rbacv1.Subject{{Kind: "User", Name: "tom"}},
+		RoleRef:  rbacv1.RoleRef{Kind: "WorkspaceRole", Name: "devops-regular"},
+	}
+	if _, err := generator.Create(workspaceRolebinding); err != nil {
+		t.Fatal(err)
+	}
+
+	role, err := kubeClient.RbacV1().Roles("devops-fat").Get(ctx, "developer", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(role.Rules) != 1 || role.Rules[0].APIGroups[1] != "apps" {
+		t.Errorf("unexpected rules %v", role.Rules)
+	}
+	for namespace, expected := range map[string]rbacv1.RoleRef{
+		"devops-fat": {APIGroup: rbacv1.GroupName, Kind: "Role", Name: "developer"},
+		"devops-uat": {APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "view"},
+		"devops-sit": {APIGroup: rbacv1.GroupName, Kind: "Role", Name: config.DefaultEnvironmentRole},
+	} {
+		rolebinding, err := kubeClient.RbacV1().RoleBindings(namespace).Get(ctx, "tom"+RolebindingSuffix, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rolebinding.RoleRef != expected {
+			t.Errorf("expected %v in %s, got %v", expected, namespace, rolebinding.RoleRef)
+		}
+	}
+
+	// the member is downgraded to viewer and the template changes
+	options.Templates[0].Rules[0].Verbs = []string{"get", "list", "watch"}
+	updated := workspaceRolebinding.DeepCopy()
+	updated.RoleRef.Name = "devops-viewer"
+	if err := generator.Update(workspaceRolebinding, updated); err != nil {
+		t.Fatal(err)
+	}
+	rolebinding, err := kubeClient.RbacV1().RoleBindings("devops-fat").Get(ctx, "tom"+RolebindingSuffix, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rolebinding.RoleRef.Kind != "ClusterRole" || rolebinding.RoleRef.Name != "view" {
+		t.Errorf("expected the viewer to be bound to view, got %v", rolebinding.RoleRef)
+	}
+
+	options.Environments[0].Roles = nil
+	if _, err := generator.Create(updated); err != nil {
+		t.Fatal(err)
+	}
+	role, err = kubeClient.RbacV1().Roles("devops-fat").Get(ctx, "developer", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verbs := role.Rules[0].Verbs; len(verbs) != 3 {
+		t.Errorf("expected the role to follow its template, got %v", verbs)
+	}
+}
@@ -1,67 +1,103 @@
 package resource
 
 import (
+	"bytes"
 	"context"
 	baseErr "errors"
+	"github.com/hchenc/iceberg/pkg/constants"
 	"github.com/hchenc/iceberg/pkg/syncer"
 	"github.com/hchenc/iceberg/pkg/utils"
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"strings"
+	"reflect"
+	"sort"
+	"text/template"
 )
 
+const (
+	defaultSecretStoreKind = "ClusterSecretStore"
+	defaultSecretPath      = "{{.Workspace}}/{{.Env}}/{{.Name}}"
+)
+
+var externalSecretResource = schema.GroupVersionResource{
+	Group:    "external-secrets.io",
+	Version:  "v1beta1",
+	Resource: "externalsecrets",
+}
+
+// copyableSecretTypes are the secret types whose values may be copied to
+// the other environments
+var copyableSecretTypes = map[v1.SecretType]bool{
+	v1.SecretTypeTLS: true,
+}
+
+// SecretPath is the data of the remote path template of external secrets.
+type SecretPath struct {
+	Name      string
+	Workspace string
+	Env       string
+}
+
 type secretInfo struct {
-	kubeClient *kubernetes.Clientset
-	logger     *logrus.Logger
-	ctx        context.Context
+	kubeClient    kubernetes.Interface
+	dynamicClient dynamic.Interface
+	namespaces    *NamespaceResolver
+	overrides     OverridesFunc
+	logger        *logrus.Logger
+	ctx           context.Context
 }
 
+// Create propagates the secret to the sibling environments in the mode of
+// its iceberg.io/secret-propagation annotation. Keys added to the source
+// later are added to the copies, values filled in a skeleton are kept. The
+// environment overrides of Secret, or ExternalSecret in external mode, are
+// applied to the copies made by iceberg whenever they are synced.
 func (s secretInfo) Create(obj interface{}) (interface{}, error) {
 	secret := obj.(*v1.Secret)
 	secLogInfo := logrus.Fields{
-		"service": secret.Name,
+		"secret": secret.Name,
+	}
+	if IsExcluded(secret) {
+		return nil, nil
+	}
+	mode := s.mode(secret)
+
+	workspace, _, candidates, err := s.namespaces.Siblings(secret.Namespace)
+	if err != nil {
+		return nil, skipUnlabeled(s.logger.WithFields(secLogInfo), err)
 	}
+	candidates = propagationTargets(secret, candidates)
 	var errs []error
-	namespacePrefix := strings.Split(secret.Namespace, "-")[0]
-	candidates := map[string]string{
-		namespacePrefix + "-fat": "fat",
-		namespacePrefix + "-uat": "uat",
-		namespacePrefix + "-sit": "sit",
-	}
-	delete(candidates, secret.Namespace)
-
-	for namespace := range candidates {
-		//service := assembleService(service, namespace)
-		itemKey := map[string][]byte{}
-		for k := range secret.Data {
-			itemKey[k] = []byte("")
+
+	for namespace, env := range candidates {
+		var err error
+		switch mode {
+		case constants.SecretPropagationCopy:
+			err = s.syncCopy(secret, workspace, namespace, env)
+		case constants.SecretPropagationExternal:
+			err = s.syncExternal(secret, workspace, namespace, env, SecretPath{
+				Name:      secret.Name,
+				Workspace: workspace,
+				Env:       env,
+			})
+		default:
+			err = s.syncSkeleton(secret, workspace, namespace, env)
 		}
-		secret := assembleResource(secret, namespace, func(obj interface{}, namespace string) interface{} {
-			return &v1.Secret{
-				TypeMeta: secret.TypeMeta,
-				ObjectMeta: metav1.ObjectMeta{
-					Name:        secret.Name,
-					Namespace:   namespace,
-					Labels:      secret.Labels,
-					Annotations: secret.Annotations,
-					Finalizers:  secret.Finalizers,
-					ClusterName: secret.ClusterName,
-				},
-				Type: secret.Type,
-				Data: itemKey,
-			}
-		}).(*v1.Secret)
-		_, err := s.kubeClient.CoreV1().Secrets(namespace).Create(s.ctx, secret, metav1.CreateOptions{})
-		if err == nil || errors.IsAlreadyExists(err) {
+		if err == nil {
 			s.logger.WithFields(secLogInfo).WithFields(logrus.Fields{
 				"namespace": namespace,
+				"mode":      mode,
 			}).Info("finish to create namespaced kubernetes secret")
 		} else {
 			s.logger.WithFields(secLogInfo).WithFields(logrus.Fields{
 				"namespace": namespace,
+				"mode":      mode,
 				"message":   "failed to create namespaced kubernetes secret",
 			}).Error(err)
 			errs = append(errs, err)
@@ -75,8 +111,240 @@ func (s secretInfo) Create(obj interface{}) (interface{}, error) {
 	}
 }
 
+// mode returns the propagation mode of the secret, unknown modes and copies
+// of sensitive secrets fall back to skeleton.
+func (s secretInfo) mode(secret *v1.Secret) string {
+	mode := secret.Annotations[constants.IcebergSecretPropagation]
+	switch mode {
+	case "":
+		return constants.SecretPropagationSkeleton
+	case constants.SecretPropagationSkeleton, constants.SecretPropagationExternal:
+		return mode
+	case constants.SecretPropagationCopy:
+		if copyableSecretTypes[secret.Type] {
+			return mode
+		}
+		s.logger.WithFields(logrus.Fields{
+			"secret":    secret.Name,
+			"namespace": secret.Namespace,
+			"type":      secret.Type,
+		}).Warn("secret type is sensitive and can't be copied, fall back to skeleton")
+		return constants.SecretPropagationSkeleton
+	default:
+		s.logger.WithFields(logrus.Fields{
+			"secret":    secret.Name,
+			"namespace": secret.Namespace,
+			"mode":      mode,
+		}).Warn("unknown secret propagation mode, fall back to skeleton")
+		return constants.SecretPropagationSkeleton
+	}
+}
+
+func (s secretInfo) syncSkeleton(source *v1.Secret, workspace, namespace, env string) error {
+	itemKey := map[string][]byte{}
+	for k := range source.Data {
+		itemKey[k] = []byte("")
+	}
+	secret := copySecret(source, namespace, itemKey)
+	if err := applyOverrides(secret, "Secret", workspace, env, s.overrides); err != nil {
+		return err
+	}
+	_, err := s.kubeClient.CoreV1().Secrets(namespace).Create(s.ctx, secret, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := s.kubeClient.CoreV1().Secrets(namespace).Get(s.ctx, secret.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if existing.Labels[constants.IcebergPropagatedFrom] != source.Namespace {
+		s.logger.WithFields(logrus.Fields{
+			"secret":    secret.Name,
+			"namespace": namespace,
+		}).Warn("secret not made by iceberg already exists, skip it")
+		return nil
+	}
+	updated := existing.DeepCopy()
+	if updated.Data == nil {
+		updated.Data = map[string][]byte{}
+	}
+	for k := range itemKey {
+		if _, exists := updated.Data[k]; !exists {
+			updated.Data[k] = []byte("")
+		}
+	}
+	if err := applyOverrides(updated, "Secret", workspace, env, s.overrides); err != nil {
+		return err
+	}
+	if reflect.DeepEqual(updated, existing) {
+		return nil
+	}
+	_, err = s.kubeClient.CoreV1().Secrets(namespace).Update(s.ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
+func (s secretInfo) syncCopy(source *v1.Secret, workspace, namespace, env string) error {
+	secret := copySecret(source, namespace, source.Data)
+	if err := applyOverrides(secret, "Secret", workspace, env, s.overrides); err != nil {
+		return err
+	}
+	_, err := s.kubeClient.CoreV1().Secrets(namespace).Create(s.ctx, secret, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := s.kubeClient.CoreV1().Secrets(namespace).Get(s.ctx, secret.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if existing.Labels[constants.IcebergPropagatedFrom] != source.Namespace {
+		s.logger.WithFields(logrus.Fields{
+			"secret":    secret.Name,
+			"namespace": namespace,
+		}).Info("secret not made by iceberg already exists, skip it")
+		return nil
+	}
+	secret.ResourceVersion = existing.ResourceVersion
+	_, err = s.kubeClient.CoreV1().Secrets(namespace).Update(s.ctx, secret, metav1.UpdateOptions{})
+	return err
+}
+
+func (s secretInfo) syncExternal(source *v1.Secret, workspace, namespace, env string, path SecretPath) error {
+	store := source.Annotations[constants.IcebergSecretStore]
+	if len(store) == 0 {
+		return baseErr.New("annotation " + constants.IcebergSecretStore + " is required by external secrets")
+	}
+	storeKind := source.Annotations[constants.IcebergSecretStoreKind]
+	if len(storeKind) == 0 {
+		storeKind = defaultSecretStoreKind
+	}
+	pathTemplate := source.Annotations[constants.IcebergSecretPath]
+	if len(pathTemplate) == 0 {
+		pathTemplate = defaultSecretPath
+	}
+	remotePath, err := renderSecretPath(pathTemplate, path)
+	if err != nil {
+		return err
+	}
+
+	externalSecret := newExternalSecret(source, namespace, store, storeKind, remotePath)
+	if err := applyOverrides(externalSecret, "ExternalSecret", workspace, env, s.overrides); err != nil {
+		return err
+	}
+	client := s.dynamicClient.Resource(externalSecretResource).Namespace(namespace)
+	_, err = client.Create(s.ctx, externalSecret, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := client.Get(s.ctx, source.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if existing.GetLabels()[constants.IcebergPropagatedFrom] != source.Namespace {
+		s.logger.WithFields(logrus.Fields{
+			"secret":    source.Name,
+			"namespace": namespace,
+		}).Info("external secret not made by iceberg already exists, skip it")
+		return nil
+	}
+	externalSecret.SetResourceVersion(existing.GetResourceVersion())
+	_, err = client.Update(s.ctx, externalSecret, metav1.UpdateOptions{})
+	return err
+}
+
+func copySecret(source *v1.Secret, namespace string, data map[string][]byte) *v1.Secret {
+	return &v1.Secret{
+		TypeMeta: source.TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        source.Name,
+			Namespace:   namespace,
+			Labels:      propagatedLabels(source),
+			Annotations: source.Annotations,
+			Finalizers:  source.Finalizers,
+			ClusterName: source.ClusterName,
+		},
+		Type: source.Type,
+		Data: data,
+	}
+}
+
+func newExternalSecret(source *v1.Secret, namespace, store, storeKind, remotePath string) *unstructured.Unstructured {
+	keys := make([]string, 0, len(source.Data))
+	for k := range source.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	data := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		data = append(data, map[string]interface{}{
+			"secretKey": k,
+			"remoteRef": map[string]interface{}{
+				"key":      remotePath,
+				"property": k,
+			},
+		})
+	}
+
+	labels := map[string]interface{}{}
+	for k, v := range propagatedLabels(source) {
+		labels[k] = v
+	}
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": externalSecretResource.GroupVersion().String(),
+			"kind":       "ExternalSecret",
+			"metadata": map[string]interface{}{
+				"name":      source.Name,
+				"namespace": namespace,
+				"labels":    labels,
+			},
+			"spec": map[string]interface{}{
+				"refreshInterval": "1h",
+				"secretStoreRef": map[string]interface{}{
+					"name": store,
+					"kind": storeKind,
+				},
+				"target": map[string]interface{}{
+					"name":           source.Name,
+					"creationPolicy": "Owner",
+					"template": map[string]interface{}{
+						"type": string(source.Type),
+					},
+				},
+				"data": data,
+			},
+		},
+	}
+}
+
+// propagatedLabels returns the labels of source with the
+// iceberg.io/propagated-from label added.
+func propagatedLabels(source metav1.Object) map[string]string {
+	labels := map[string]string{}
+	for k, v := range source.GetLabels() {
+		labels[k] = v
+	}
+	labels[constants.IcebergPropagatedFrom] = source.GetNamespace()
+	return labels
+}
+
+func renderSecretPath(text string, path SecretPath) (string, error) {
+	tmpl, err := template.New("secret-path").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, path); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 func (s secretInfo) Update(objOld interface{}, objNew interface{}) error {
-	panic("implement me")
+	_, err := s.Create(objNew)
+	return err
 }
 
 func (s secretInfo) Delete(name string) error {
@@ -95,14 +363,17 @@ func (s secretInfo) List(key string) (interface{}, error) {
 	panic("implement me")
 }
 
-func NewSecretGenerator(ctx context.Context, kubeClient *kubernetes.Clientset) syncer.Generator {
+func NewSecretGenerator(ctx context.Context, kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, namespaces *NamespaceResolver, overrides OverridesFunc) syncer.Generator {
 	logger := utils.GetLogger(logrus.Fields{
 		"component": "kubernetes",
-		"resource":  "service",
+		"resource":  "secret",
 	})
 	return secretInfo{
-		kubeClient: kubeClient,
-		ctx:        ctx,
-		logger:     logger,
+		kubeClient:    kubeClient,
+		dynamicClient: dynamicClient,
+		namespaces:    namespaces,
+		overrides:     overrides,
+		ctx:           ctx,
+		logger:        logger,
 	}
 }
@@ -0,0 +1,165 @@
+package resource
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/constants"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func newSecret(name, mode string, secretType v1.SecretType, data map[string]string) *v1.Secret {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "devops-fat",
+			Annotations: map[string]string{},
+		},
+		Type: secretType,
+		Data: map[string][]byte{},
+	}
+	if len(mode) != 0 {
+		secret.Annotations[constants.IcebergSecretPropagation] = mode
+	}
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+	return secret
+}
+
+func TestSecretGenerator(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewSimpleClientset()
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	generator := NewSecretGenerator(ctx, kubeClient, dynamicClient, newTestResolver(), nil)
+
+	// skeleton keeps the values filled in the other environments
+	if _, err := generator.Create(newSecret("db", "", v1.SecretTypeOpaque, map[string]string{"password": "fat"})); err != nil {
+		t.Fatal(err)
+	}
+	uat, _ := kubeClient.CoreV1().Secrets("devops-uat").Get(ctx, "db", metav1.GetOptions{})
+	uat.Data["password"] = []byte("uat")
+	kubeClient.CoreV1().Secrets("devops-uat").Update(ctx, uat, metav1.UpdateOptions{})
+	if err := generator.Update(nil, newSecret("db", "", v1.SecretTypeOpaque, map[string]string{"password": "fat", "user": "fat"})); err != nil {
+		t.Fatal(err)
+	}
+	uat, _ = kubeClient.CoreV1().Secrets("devops-uat").Get(ctx, "db", metav1.GetOptions{})
+	if string(uat.Data["password"]) != "uat" || string(uat.Data["user"]) != "" || len(uat.Data) != 2 {
+		t.Errorf("unexpected skeleton data %v", uat.Data)
+	}
+
+	// secrets not made by iceberg are left untouched
+	local := newSecret("local", "", v1.SecretTypeOpaque, map[string]string{"password": "uat"})
+	local.Namespace = "devops-uat"
+	kubeClient.CoreV1().Secrets("devops-uat").Create(ctx, local, metav1.CreateOptions{})
+	if _, err := generator.Create(newSecret("local", "", v1.SecretTypeOpaque, map[string]string{"password": "fat", "user": "fat"})); err != nil {
+		t.Fatal(err)
+	}
+	local, _ = kubeClient.CoreV1().Secrets("devops-uat").Get(ctx, "local", metav1.GetOptions{})
+	if string(local.Data["password"]) != "uat" || len(local.Data) != 1 {
+		t.Errorf("expected the secret not made by iceberg to be kept, got %v", local.Data)
+	}
+
+	// copy is allowed for tls only
+	if _, err := generator.Create(newSecret("tls", constants.SecretPropagationCopy, v1.SecretTypeTLS, map[string]string{"tls.crt": "crt"})); err != nil {
+		t.Fatal(err)
+	}
+	tls, _ := kubeClient.CoreV1().Secrets("devops-sit").Get(ctx, "tls", metav1.GetOptions{})
+	if string(tls.Data["tls.crt"]) != "crt" || !IsPropagated(tls) {
+		t.Errorf("unexpected copied secret %v", tls)
+	}
+	if _, err := generator.Create(newSecret("token", constants.SecretPropagationCopy, v1.SecretTypeOpaque, map[string]string{"token": "secret"})); err != nil {
+		t.Fatal(err)
+	}
+	token, _ := kubeClient.CoreV1().Secrets("devops-sit").Get(ctx, "token", metav1.GetOptions{})
+	if string(token.Data["token"]) != "" {
+		t.Errorf("expected opaque secret to fall back to skeleton, got %v", token.Data)
+	}
+
+	// external creates an ExternalSecret per environment
+	external := newSecret("api", constants.SecretPropagationExternal, v1.SecretTypeOpaque, map[string]string{"key": "fat"})
+	if _, err := generator.Create(external); err == nil {
+		t.Error("expected a missing secret store to fail")
+	}
+	external.Annotations[constants.IcebergSecretStore] = "vault"
+	if _, err := generator.Create(external); err != nil {
+		t.Fatal(err)
+	}
+	externalSecret, err := dynamicClient.Resource(externalSecretResource).Namespace("devops-uat").Get(ctx, "api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _, _ := unstructured.NestedSlice(externalSecret.Object, "spec", "data")
+	if len(data) != 1 {
+		t.Fatalf("unexpected external secret data %v", data)
+	}
+	if key, _, _ := unstructured.NestedString(data[0].(map[string]interface{}), "remoteRef", "key"); key != "devops/uat/api" {
+		t.Errorf("unexpected remote path %s", key)
+	}
+	if _, err := kubeClient.CoreV1().Secrets("devops-uat").Get(ctx, "api", metav1.GetOptions{}); err == nil {
+		t.Error("expected no skeleton for external secrets")
+	}
+
+	// propagate-to limits the environments, built-in exclusions are skipped
+	limited := newSecret("limited", "", v1.SecretTypeOpaque, map[string]string{"key": "fat"})
+	limited.Annotations[constants.IcebergPropagateTo] = "uat"
+	if _, err := generator.Create(limited); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kubeClient.CoreV1().Secrets("devops-uat").Get(ctx, "limited", metav1.GetOptions{}); err != nil {
+		t.Error(err)
+	}
+	if _, err := kubeClient.CoreV1().Secrets("devops-sit").Get(ctx, "limited", metav1.GetOptions{}); err == nil {
+		t.Error("expected no copy outside of propagate-to")
+	}
+	if _, err := generator.Create(newSecret("default-token", "", v1.SecretTypeServiceAccountToken, map[string]string{"token": "secret"})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kubeClient.CoreV1().Secrets("devops-uat").Get(ctx, "default-token", metav1.GetOptions{}); err == nil {
+		t.Error("expected service account tokens not to be propagated")
+	}
+}
+
+func TestSecretOverrides(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewSimpleClientset()
+	overrides := []*config.EnvironmentOverride{
+		{Env: "uat", Kinds: []string{"Secret"}, JSONPatch: []*config.PatchOperation{{Op: "add", Path: "/metadata/labels/tier", Value: "db"}}},
+	}
+	generator := NewSecretGenerator(ctx, kubeClient, dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), newTestResolver(), func() []*config.EnvironmentOverride {
+		return overrides
+	})
+
+	if _, err := generator.Create(newSecret("db", "", v1.SecretTypeOpaque, map[string]string{"password": "fat"})); err != nil {
+		t.Fatal(err)
+	}
+	uat, _ := kubeClient.CoreV1().Secrets("devops-uat").Get(ctx, "db", metav1.GetOptions{})
+	if uat.Labels["tier"] != "db" {
+		t.Errorf("expected the override to be applied to the skeleton, got %v", uat.Labels)
+	}
+
+	// a changed override reaches the existing skeleton, its values are kept
+	uat.Data["password"] = []byte("uat")
+	kubeClient.CoreV1().Secrets("devops-uat").Update(ctx, uat, metav1.UpdateOptions{})
+	overrides[0].JSONPatch[0].Value = "cache"
+	if _, err := generator.Create(newSecret("db", "", v1.SecretTypeOpaque, map[string]string{"password": "fat"})); err != nil {
+		t.Fatal(err)
+	}
+	uat, _ = kubeClient.CoreV1().Secrets("devops-uat").Get(ctx, "db", metav1.GetOptions{})
+	if uat.Labels["tier"] != "cache" || string(uat.Data["password"]) != "uat" {
+		t.Errorf("expected the changed override to update the skeleton, got %v %v", uat.Labels, uat.Data)
+	}
+
+	if _, err := generator.Create(newSecret("tls", constants.SecretPropagationCopy, v1.SecretTypeTLS, map[string]string{"tls.crt": "crt"})); err != nil {
+		t.Fatal(err)
+	}
+	tls, _ := kubeClient.CoreV1().Secrets("devops-uat").Get(ctx, "tls", metav1.GetOptions{})
+	if tls.Labels["tier"] != "cache" || string(tls.Data["tls.crt"]) != "crt" {
+		t.Errorf("expected the override to be applied to the copy, got %v", tls)
+	}
+}
@@ -3,6 +3,7 @@ package resource
 import (
 	"context"
 	baseErr "errors"
+	"github.com/hchenc/iceberg/pkg/constants"
 	"github.com/hchenc/iceberg/pkg/syncer"
 	"github.com/hchenc/iceberg/pkg/utils"
 	"github.com/sirupsen/logrus"
@@ -10,11 +11,12 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"strings"
 )
 
 type serviceInfo struct {
-	kubeClient *kubernetes.Clientset
+	kubeClient kubernetes.Interface
+	namespaces *NamespaceResolver
+	overrides  OverridesFunc
 	logger     *logrus.Logger
 	ctx        context.Context
 }
@@ -24,18 +26,19 @@ func (s serviceInfo) Create(obj interface{}) (interface{}, error) {
 	svcLogInfo := logrus.Fields{
 		"service": service.Name,
 	}
-	var errs []error
-	namespacePrefix := strings.Split(service.Namespace, "-")[0]
-	candidates := map[string]string{
-		namespacePrefix + "-fat": "fat",
-		namespacePrefix + "-uat": "uat",
-		namespacePrefix + "-sit": "sit",
+	if IsExcluded(service) {
+		return nil, nil
 	}
-	delete(candidates, service.Namespace)
+	workspace, _, candidates, err := s.namespaces.Siblings(service.Namespace)
+	if err != nil {
+		return nil, skipUnlabeled(s.logger.WithFields(svcLogInfo), err)
+	}
+	candidates = propagationTargets(service, candidates)
+	var errs []error
 
-	for namespace := range candidates {
+	for namespace, env := range candidates {
 		//service := assembleService(service, namespace)
-		service := assembleResource(service, namespace, func(obj interface{}, namespace string) interface{} {
+		copied := assembleResource(service, namespace, func(obj interface{}, namespace string) interface{} {
 			var newServicePort []v1.ServicePort
 			for _, port := range service.Spec.Ports {
 				svcPort := v1.ServicePort{
@@ -52,7 +55,7 @@ func (s serviceInfo) Create(obj interface{}) (interface{}, error) {
 				ObjectMeta: metav1.ObjectMeta{
 					Name:        service.Name,
 					Namespace:   namespace,
-					Labels:      service.Labels,
+					Labels:      propagatedLabels(service),
 					Annotations: service.Annotations,
 					Finalizers:  service.Finalizers,
 					ClusterName: service.ClusterName,
@@ -65,8 +68,11 @@ func (s serviceInfo) Create(obj interface{}) (interface{}, error) {
 				},
 			}
 		}).(*v1.Service)
-		_, err := s.kubeClient.CoreV1().Services(namespace).Create(s.ctx, service, metav1.CreateOptions{})
-		if err == nil || errors.IsAlreadyExists(err) {
+		err := applyOverrides(copied, "Service", workspace, env, s.overrides)
+		if err == nil {
+			err = s.sync(service, copied)
+		}
+		if err == nil {
 			s.logger.WithFields(svcLogInfo).WithFields(logrus.Fields{
 				"namespace": namespace,
 			}).Info("finish to create namespaced kubernetes service")
@@ -86,6 +92,41 @@ func (s serviceInfo) Create(obj interface{}) (interface{}, error) {
 	}
 }
 
+// sync creates copied or updates the copy made by iceberg, the cluster ip
+// and the node ports allocated to the copy are kept.
+func (s serviceInfo) sync(source, copied *v1.Service) error {
+	client := s.kubeClient.CoreV1().Services(copied.Namespace)
+	_, err := client.Create(s.ctx, copied, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := client.Get(s.ctx, copied.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if existing.Labels[constants.IcebergPropagatedFrom] != source.Namespace {
+		s.logger.WithFields(logrus.Fields{
+			"service":   copied.Name,
+			"namespace": copied.Namespace,
+		}).Info("service not made by iceberg already exists, skip it")
+		return nil
+	}
+	copied.Spec.ClusterIP = existing.Spec.ClusterIP
+	copied.Spec.ClusterIPs = existing.Spec.ClusterIPs
+	copied.Spec.HealthCheckNodePort = existing.Spec.HealthCheckNodePort
+	for index := range copied.Spec.Ports {
+		port := &copied.Spec.Ports[index]
+		for _, existingPort := range existing.Spec.Ports {
+			if existingPort.Port == port.Port && existingPort.Protocol == port.Protocol && port.NodePort == 0 {
+				port.NodePort = existingPort.NodePort
+			}
+		}
+	}
+	copied.ResourceVersion = existing.ResourceVersion
+	_, err = client.Update(s.ctx, copied, metav1.UpdateOptions{})
+	return err
+}
+
 func (s serviceInfo) Update(objOld interface{}, objNew interface{}) error {
 	panic("implement me")
 }
@@ -106,13 +147,15 @@ func (s serviceInfo) List(key string) (interface{}, error) {
 	panic("implement me")
 }
 
-func NewServiceGenerator(ctx context.Context, kubeClient *kubernetes.Clientset) syncer.Generator {
+func NewServiceGenerator(ctx context.Context, kubeClient kubernetes.Interface, namespaces *NamespaceResolver, overrides OverridesFunc) syncer.Generator {
 	logger := utils.GetLogger(logrus.Fields{
 		"component": "kubernetes",
 		"resource":  "service",
 	})
 	return serviceInfo{
 		kubeClient: kubeClient,
+		namespaces: namespaces,
+		overrides:  overrides,
 		ctx:        ctx,
 		logger:     logger,
 	}
@@ -0,0 +1,87 @@
+package resource
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/constants"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestServiceGenerator(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	generator := NewServiceGenerator(context.Background(), kubeClient, newTestResolver(), nil)
+
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "devops-fat",
+			Labels:    map[string]string{"app": "web"},
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: "10.0.0.1",
+			Ports:     []v1.ServicePort{{Name: "http", Port: 80, NodePort: 30080}},
+			Selector:  map[string]string{"app": "web"},
+		},
+	}
+	if _, err := generator.Create(service); err != nil {
+		t.Fatal(err)
+	}
+
+	uat, err := kubeClient.CoreV1().Services("devops-uat").Get(context.Background(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uat.Spec.ClusterIP != "" || uat.Spec.Ports[0].NodePort != 0 {
+		t.Errorf("expected cluster specific fields to be dropped, got %v", uat.Spec)
+	}
+	if uat.Labels["app"] != "web" || uat.Labels[constants.IcebergPropagatedFrom] != "devops-fat" || Propagates(uat, true) {
+		t.Errorf("expected the uat copy to be marked as propagated, got labels %v", uat.Labels)
+	}
+
+	kubeClient.ClearActions()
+	if _, err := generator.Create(uat); err != nil {
+		t.Fatal(err)
+	}
+	if actions := kubeClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected reconciling a copy to be a no-op, got %v", actions)
+	}
+}
+
+func TestServiceGeneratorUpdate(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewSimpleClientset()
+	overrides := []*config.EnvironmentOverride{
+		{Env: "uat", Kinds: []string{"Service"}, JSONPatch: []*config.PatchOperation{{Path: "/spec/type", Value: "NodePort"}}},
+	}
+	generator := NewServiceGenerator(ctx, kubeClient, newTestResolver(), func() []*config.EnvironmentOverride {
+		return overrides
+	})
+
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "devops-fat"},
+		Spec: v1.ServiceSpec{
+			Type:  v1.ServiceTypeClusterIP,
+			Ports: []v1.ServicePort{{Name: "http", Port: 80, Protocol: v1.ProtocolTCP}},
+		},
+	}
+	if _, err := generator.Create(service); err != nil {
+		t.Fatal(err)
+	}
+	// the cluster allocates the ip and the node port of the copy
+	uat, _ := kubeClient.CoreV1().Services("devops-uat").Get(ctx, "web", metav1.GetOptions{})
+	uat.Spec.ClusterIP = "10.0.0.2"
+	uat.Spec.Ports[0].NodePort = 30080
+	kubeClient.CoreV1().Services("devops-uat").Update(ctx, uat, metav1.UpdateOptions{})
+
+	overrides[0].JSONPatch[0].Value = "LoadBalancer"
+	if _, err := generator.Create(service); err != nil {
+		t.Fatal(err)
+	}
+	uat, _ = kubeClient.CoreV1().Services("devops-uat").Get(ctx, "web", metav1.GetOptions{})
+	if uat.Spec.Type != v1.ServiceTypeLoadBalancer || uat.Spec.ClusterIP != "10.0.0.2" || uat.Spec.Ports[0].NodePort != 30080 {
+		t.Errorf("expected the changed override to update the copy and keep its allocations, got %v", uat.Spec)
+	}
+}
@@ -0,0 +1,61 @@
+package resource
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type statefulSetWorkload struct{}
+
+func (statefulSetWorkload) clone(obj interface{}, namespace string, transform *workloadTransform) interface{} {
+	statefulSet := obj.(*v1.StatefulSet).DeepCopy()
+	statefulSet.ObjectMeta = cloneObjectMeta(statefulSet.ObjectMeta, namespace)
+	statefulSet.Status = v1.StatefulSetStatus{}
+	for index := range statefulSet.Spec.VolumeClaimTemplates {
+		statefulSet.Spec.VolumeClaimTemplates[index].Status = corev1.PersistentVolumeClaimStatus{}
+	}
+	if transform.Replicas != nil {
+		statefulSet.Spec.Replicas = transform.Replicas
+	}
+	transform.apply(&statefulSet.Spec.Template)
+	return statefulSet
+}
+
+func (statefulSetWorkload) exists(ctx context.Context, kubeClient kubernetes.Interface, namespace, selector string) (bool, error) {
+	statefulSets, err := kubeClient.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return false, err
+	}
+	return len(statefulSets.Items) != 0, nil
+}
+
+func (statefulSetWorkload) get(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string) (metav1.Object, error) {
+	return kubeClient.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (statefulSetWorkload) create(ctx context.Context, kubeClient kubernetes.Interface, obj interface{}) error {
+	statefulSet := obj.(*v1.StatefulSet)
+	_, err := kubeClient.AppsV1().StatefulSets(statefulSet.Namespace).Create(ctx, statefulSet, metav1.CreateOptions{})
+	return err
+}
+
+func (statefulSetWorkload) update(ctx context.Context, kubeClient kubernetes.Interface, obj interface{}, existing metav1.Object) error {
+	statefulSet := obj.(*v1.StatefulSet)
+	// only the replicas, the template and the update strategy of a
+	// statefulset can be updated
+	current := existing.(*v1.StatefulSet)
+	statefulSet.Spec.Selector = current.Spec.Selector
+	statefulSet.Spec.ServiceName = current.Spec.ServiceName
+	statefulSet.Spec.VolumeClaimTemplates = current.Spec.VolumeClaimTemplates
+	statefulSet.Spec.PodManagementPolicy = current.Spec.PodManagementPolicy
+	_, err := kubeClient.AppsV1().StatefulSets(statefulSet.Namespace).Update(ctx, statefulSet, metav1.UpdateOptions{})
+	return err
+}
+
+func NewStatefulSetGenerator(ctx context.Context, kubeClient kubernetes.Interface, namespaces *NamespaceResolver, overrides OverridesFunc) syncer.Generator {
+	return newWorkloadGenerator(ctx, kubeClient, namespaces, overrides, "statefulset", statefulSetWorkload{})
+}
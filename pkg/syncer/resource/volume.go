@@ -3,6 +3,7 @@ package resource
 import (
 	"context"
 	baseErr "errors"
+	"github.com/hchenc/iceberg/pkg/constants"
 	"github.com/hchenc/iceberg/pkg/syncer"
 	"github.com/hchenc/iceberg/pkg/utils"
 	"github.com/sirupsen/logrus"
@@ -10,11 +11,13 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"strings"
+	"reflect"
 )
 
 type volumeInfo struct {
-	kubeClient *kubernetes.Clientset
+	kubeClient kubernetes.Interface
+	namespaces *NamespaceResolver
+	overrides  OverridesFunc
 	logger     *logrus.Logger
 	ctx        context.Context
 }
@@ -24,22 +27,23 @@ func (v volumeInfo) Create(obj interface{}) (interface{}, error) {
 	volumeLogInfo := logrus.Fields{
 		"volume": volume.Name,
 	}
-	var errs []error
-	namespacePrefix := strings.Split(volume.Namespace, "-")[0]
-	candidates := map[string]string{
-		namespacePrefix + "-fat": "fat",
-		namespacePrefix + "-uat": "uat",
-		namespacePrefix + "-sit": "sit",
+	if IsExcluded(volume) {
+		return nil, nil
 	}
-	delete(candidates, volume.Namespace)
+	workspace, _, candidates, err := v.namespaces.Siblings(volume.Namespace)
+	if err != nil {
+		return nil, skipUnlabeled(v.logger.WithFields(volumeLogInfo), err)
+	}
+	candidates = propagationTargets(volume, candidates)
+	var errs []error
 
-	for namespace := range candidates {
-		volume := assembleResource(volume, namespace, func(obj interface{}, namespace string) interface{} {
+	for namespace, env := range candidates {
+		copied := assembleResource(volume, namespace, func(obj interface{}, namespace string) interface{} {
 			return &v1.PersistentVolumeClaim{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:        volume.Name,
 					Namespace:   namespace,
-					Labels:      volume.Labels,
+					Labels:      propagatedLabels(volume),
 					Annotations: volume.Annotations,
 				},
 				Spec: v1.PersistentVolumeClaimSpec{
@@ -49,8 +53,11 @@ func (v volumeInfo) Create(obj interface{}) (interface{}, error) {
 				},
 			}
 		}).(*v1.PersistentVolumeClaim)
-		_, err := v.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Create(v.ctx, volume, metav1.CreateOptions{})
-		if err == nil || errors.IsAlreadyExists(err) {
+		err := applyOverrides(copied, "PersistentVolumeClaim", workspace, env, v.overrides)
+		if err == nil {
+			err = v.sync(volume, copied)
+		}
+		if err == nil {
 			v.logger.WithFields(volumeLogInfo).WithFields(logrus.Fields{
 				"namespace": namespace,
 			}).Info("finish to create namespaced kubernetes volume")
@@ -70,6 +77,42 @@ func (v volumeInfo) Create(obj interface{}) (interface{}, error) {
 	}
 }
 
+// sync creates copied or updates the copy made by iceberg. The spec of a
+// claim is immutable but its storage request, which may only grow, and its
+// annotations are managed by the volume controllers, so only the labels and
+// the storage request are updated.
+func (v volumeInfo) sync(source, copied *v1.PersistentVolumeClaim) error {
+	client := v.kubeClient.CoreV1().PersistentVolumeClaims(copied.Namespace)
+	_, err := client.Create(v.ctx, copied, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := client.Get(v.ctx, copied.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if existing.Labels[constants.IcebergPropagatedFrom] != source.Namespace {
+		v.logger.WithFields(logrus.Fields{
+			"volume":    copied.Name,
+			"namespace": copied.Namespace,
+		}).Info("volume not made by iceberg already exists, skip it")
+		return nil
+	}
+	updated := existing.DeepCopy()
+	updated.Labels = copied.Labels
+	if request := copied.Spec.Resources.Requests.Storage(); request.Cmp(*existing.Spec.Resources.Requests.Storage()) > 0 {
+		if updated.Spec.Resources.Requests == nil {
+			updated.Spec.Resources.Requests = v1.ResourceList{}
+		}
+		updated.Spec.Resources.Requests[v1.ResourceStorage] = *request
+	}
+	if reflect.DeepEqual(updated, existing) {
+		return nil
+	}
+	_, err = client.Update(v.ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
 func (v volumeInfo) Update(objOld interface{}, objNew interface{}) error {
 	panic("implement me")
 }
@@ -90,13 +133,15 @@ func (v volumeInfo) List(key string) (interface{}, error) {
 	panic("implement me")
 }
 
-func NewVolumeGenerator(ctx context.Context, clientset *kubernetes.Clientset) syncer.Generator {
+func NewVolumeGenerator(ctx context.Context, clientset kubernetes.Interface, namespaces *NamespaceResolver, overrides OverridesFunc) syncer.Generator {
 	logger := utils.GetLogger(logrus.Fields{
 		"component": "kubernetes",
 		"resource":  "volume",
 	})
 	return volumeInfo{
 		kubeClient: clientset,
+		namespaces: namespaces,
+		overrides:  overrides,
 		logger:     logger,
 		ctx:        ctx,
 	}
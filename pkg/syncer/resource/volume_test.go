@@ -0,0 +1,70 @@
+package resource
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/config"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestVolumeGenerator(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewSimpleClientset()
+	overrides := []*config.EnvironmentOverride{
+		{Env: "uat", Kinds: []string{"PersistentVolumeClaim"}, JSONPatch: []*config.PatchOperation{
+			{Path: "/spec/resources/requests/storage", Value: "2Gi"},
+		}},
+	}
+	generator := NewVolumeGenerator(ctx, kubeClient, newTestResolver(), func() []*config.EnvironmentOverride {
+		return overrides
+	})
+
+	volume := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "devops-fat", Labels: map[string]string{"app": "db"}},
+		Spec: v1.PersistentVolumeClaimSpec{
+			AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")},
+			},
+		},
+	}
+	if _, err := generator.Create(volume); err != nil {
+		t.Fatal(err)
+	}
+	uat, err := kubeClient.CoreV1().PersistentVolumeClaims("devops-uat").Get(ctx, "data", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if storage := uat.Spec.Resources.Requests.Storage(); storage.String() != "2Gi" || !IsPropagated(uat) {
+		t.Errorf("unexpected uat volume %v", uat)
+	}
+
+	// a changed override grows the existing copy, it never shrinks
+	overrides[0].JSONPatch[0].Value = "5Gi"
+	if _, err := generator.Create(volume); err != nil {
+		t.Fatal(err)
+	}
+	uat, _ = kubeClient.CoreV1().PersistentVolumeClaims("devops-uat").Get(ctx, "data", metav1.GetOptions{})
+	if storage := uat.Spec.Resources.Requests.Storage(); storage.String() != "5Gi" {
+		t.Errorf("expected the changed override to grow the copy, got %s", storage)
+	}
+	overrides[0].JSONPatch[0].Value = "3Gi"
+	if _, err := generator.Create(volume); err != nil {
+		t.Fatal(err)
+	}
+	uat, _ = kubeClient.CoreV1().PersistentVolumeClaims("devops-uat").Get(ctx, "data", metav1.GetOptions{})
+	if storage := uat.Spec.Resources.Requests.Storage(); storage.String() != "5Gi" {
+		t.Errorf("expected the copy not to shrink, got %s", storage)
+	}
+
+	kubeClient.ClearActions()
+	if _, err := generator.Create(uat); err != nil {
+		t.Fatal(err)
+	}
+	if actions := kubeClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected reconciling a copy to be a no-op, got %v", actions)
+	}
+}
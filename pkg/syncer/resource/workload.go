@@ -0,0 +1,324 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	baseErr "errors"
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/utils"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// strippedAnnotations are set by the cluster on the source and must not be
+// copied to the other environments
+var strippedAnnotations = []string{
+	"deployment.kubernetes.io/revision",
+	"kubectl.kubernetes.io/last-applied-configuration",
+}
+
+// strippedTemplateLabels are set on job templates by the job controller
+var strippedTemplateLabels = []string{
+	"controller-uid",
+	"job-name",
+	"batch.kubernetes.io/controller-uid",
+	"batch.kubernetes.io/job-name",
+}
+
+// workload adapts a kind of workload to workloadInfo.
+type workload interface {
+	// clone returns the copy of obj in namespace with transform applied
+	clone(obj interface{}, namespace string, transform *workloadTransform) interface{}
+	// exists reports whether a workload labelled selector exists in namespace
+	exists(ctx context.Context, kubeClient kubernetes.Interface, namespace, selector string) (bool, error)
+	get(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string) (metav1.Object, error)
+	create(ctx context.Context, kubeClient kubernetes.Interface, obj interface{}) error
+	// update replaces existing, the copy made by iceberg, with obj
+	update(ctx context.Context, kubeClient kubernetes.Interface, obj interface{}, existing metav1.Object) error
+}
+
+// workloadInfo copies a workload into the sibling environments, the copy
+// keeps the whole pod spec and only drops the status and the fields set by
+// the cluster. Per environment transforms are declared on the source with
+// <env>.transform.iceberg.io/ annotations:
+//
+//	replicas                   number of replicas
+//	[<container>.]image-tag    tag of the image
+//	[<container>.]resources    resource requirements as json, merged into the container's
+//	[<container>.]env.<NAME>   value of the environment variable NAME
+//
+// Container scoped transforms also apply to init containers, the others to
+// every container. The environment overrides are applied after the
+// transforms, copies made by iceberg are updated with them.
+type workloadInfo struct {
+	kind       string
+	workload   workload
+	namespaces *NamespaceResolver
+	overrides  OverridesFunc
+	kubeClient kubernetes.Interface
+	logger     *logrus.Logger
+	ctx        context.Context
+}
+
+func (w workloadInfo) Create(obj interface{}) (interface{}, error) {
+	source := obj.(metav1.Object)
+	wlLogInfo := logrus.Fields{
+		w.kind: source.GetName(),
+	}
+	if IsExcluded(source) {
+		return nil, nil
+	}
+	workspace, _, candidates, err := w.namespaces.Siblings(source.GetNamespace())
+	if err != nil {
+		return nil, skipUnlabeled(w.logger.WithFields(wlLogInfo), err)
+	}
+	candidates = propagationTargets(source, candidates)
+	var errs []error
+
+	for namespace, env := range candidates {
+		err := w.sync(obj, workspace, namespace, env)
+		if err == nil || errors.IsAlreadyExists(err) {
+			w.logger.WithFields(wlLogInfo).WithFields(logrus.Fields{
+				"namespace": namespace,
+			}).Infof("finish to create namespaced kubernetes %s", w.kind)
+		} else {
+			w.logger.WithFields(wlLogInfo).WithFields(logrus.Fields{
+				"namespace": namespace,
+				"message":   fmt.Sprintf("failed to create namespaced kubernetes %s", w.kind),
+			}).Error(err)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return nil, baseErr.New("failed to sync kubernetes " + w.kind)
+	} else {
+		w.logger.WithFields(wlLogInfo).Infof("finish to sync kubernetes %s", w.kind)
+		return nil, nil
+	}
+}
+
+func (w workloadInfo) sync(obj interface{}, workspace, namespace, env string) error {
+	source := obj.(metav1.Object)
+	transform, err := parseWorkloadTransform(source.GetAnnotations(), env)
+	if err != nil {
+		return err
+	}
+	copied := w.workload.clone(obj, namespace, transform)
+	if err := applyOverrides(copied.(metav1.Object), w.kind, workspace, env, w.overrides); err != nil {
+		return err
+	}
+
+	existing, err := w.workload.get(w.ctx, w.kubeClient, namespace, source.GetName())
+	if errors.IsNotFound(err) {
+		// the application is already deployed in the namespace, possibly
+		// under another name
+		if appName := source.GetLabels()[constants.KubesphereAppName]; len(appName) != 0 {
+			exists, err := w.workload.exists(w.ctx, w.kubeClient, namespace, fmt.Sprintf("%s=%s", constants.KubesphereAppName, appName))
+			if err != nil || exists {
+				return err
+			}
+		}
+		return w.workload.create(w.ctx, w.kubeClient, copied)
+	} else if err != nil {
+		return err
+	}
+	if existing.GetLabels()[constants.IcebergPropagatedFrom] != source.GetNamespace() {
+		w.logger.WithFields(logrus.Fields{
+			w.kind:      source.GetName(),
+			"namespace": namespace,
+		}).Infof("%s not made by iceberg already exists, skip it", w.kind)
+		return nil
+	}
+	copied.(metav1.Object).SetResourceVersion(existing.GetResourceVersion())
+	return w.workload.update(w.ctx, w.kubeClient, copied, existing)
+}
+
+func (w workloadInfo) Update(objOld interface{}, objNew interface{}) error {
+	panic("implement me")
+}
+
+func (w workloadInfo) Delete(name string) error {
+	panic("implement me")
+}
+
+func (w workloadInfo) GetByName(name string) (interface{}, error) {
+	panic("implement me")
+}
+
+func (w workloadInfo) GetByID(id int) (interface{}, error) {
+	panic("implement me")
+}
+
+func (w workloadInfo) List(key string) (interface{}, error) {
+	panic("implement me")
+}
+
+func newWorkloadGenerator(ctx context.Context, kubeClient kubernetes.Interface, namespaces *NamespaceResolver, overrides OverridesFunc, kind string, workload workload) workloadInfo {
+	logger := utils.GetLogger(logrus.Fields{
+		"component": "kubernetes",
+		"resource":  kind,
+	})
+	return workloadInfo{
+		kind:       kind,
+		workload:   workload,
+		namespaces: namespaces,
+		overrides:  overrides,
+		kubeClient: kubeClient,
+		ctx:        ctx,
+		logger:     logger,
+	}
+}
+
+// cloneObjectMeta returns the metadata of the copy of source in namespace.
+func cloneObjectMeta(source metav1.ObjectMeta, namespace string) metav1.ObjectMeta {
+	annotations := map[string]string{}
+	for key, value := range source.Annotations {
+		if strings.HasSuffix(strings.SplitN(key, "/", 2)[0], "."+constants.IcebergTransformDomain) {
+			continue
+		}
+		annotations[key] = value
+	}
+	for _, key := range strippedAnnotations {
+		delete(annotations, key)
+	}
+	return metav1.ObjectMeta{
+		Name:        source.Name,
+		Namespace:   namespace,
+		Labels:      propagatedLabels(&source),
+		Annotations: annotations,
+	}
+}
+
+type containerTransform struct {
+	ImageTag  string
+	Resources *corev1.ResourceRequirements
+	Env       map[string]string
+}
+
+type workloadTransform struct {
+	Replicas *int32
+	// Containers are the transforms by container name, "" applies to every
+	// container
+	Containers map[string]*containerTransform
+}
+
+func parseWorkloadTransform(annotations map[string]string, env string) (*workloadTransform, error) {
+	transform := &workloadTransform{
+		Containers: map[string]*containerTransform{},
+	}
+	prefix := env + "." + constants.IcebergTransformDomain + "/"
+	for key, value := range annotations {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		key = strings.TrimPrefix(key, prefix)
+		if key == "replicas" {
+			replicas, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid annotation %s%s: %v", prefix, key, err)
+			}
+			transform.Replicas = new(int32)
+			*transform.Replicas = int32(replicas)
+			continue
+		}
+
+		container, field := "", key
+		if parts := strings.SplitN(key, ".", 2); len(parts) == 2 && parts[0] != "env" {
+			container, field = parts[0], parts[1]
+		}
+		if transform.Containers[container] == nil {
+			transform.Containers[container] = &containerTransform{Env: map[string]string{}}
+		}
+		target := transform.Containers[container]
+		switch {
+		case field == "image-tag":
+			target.ImageTag = value
+		case field == "resources":
+			target.Resources = &corev1.ResourceRequirements{}
+			if err := json.Unmarshal([]byte(value), target.Resources); err != nil {
+				return nil, fmt.Errorf("invalid annotation %s%s: %v", prefix, key, err)
+			}
+		case strings.HasPrefix(field, "env."):
+			target.Env[strings.TrimPrefix(field, "env.")] = value
+		default:
+			return nil, fmt.Errorf("unknown annotation %s%s", prefix, key)
+		}
+	}
+	return transform, nil
+}
+
+// apply strips the cluster specific fields of template and applies the
+// container transforms.
+func (t *workloadTransform) apply(template *corev1.PodTemplateSpec) {
+	template.Spec.NodeName = ""
+	for index := range template.Spec.InitContainers {
+		if transform := t.Containers[template.Spec.InitContainers[index].Name]; transform != nil {
+			transform.apply(&template.Spec.InitContainers[index])
+		}
+	}
+	for index := range template.Spec.Containers {
+		container := &template.Spec.Containers[index]
+		if transform := t.Containers[""]; transform != nil {
+			transform.apply(container)
+		}
+		if transform := t.Containers[container.Name]; transform != nil {
+			transform.apply(container)
+		}
+	}
+}
+
+func (t *containerTransform) apply(container *corev1.Container) {
+	if len(t.ImageTag) != 0 {
+		container.Image = replaceImageTag(container.Image, t.ImageTag)
+	}
+	if t.Resources != nil {
+		if len(t.Resources.Limits) != 0 && container.Resources.Limits == nil {
+			container.Resources.Limits = corev1.ResourceList{}
+		}
+		for name, quantity := range t.Resources.Limits {
+			container.Resources.Limits[name] = quantity
+		}
+		if len(t.Resources.Requests) != 0 && container.Resources.Requests == nil {
+			container.Resources.Requests = corev1.ResourceList{}
+		}
+		for name, quantity := range t.Resources.Requests {
+			container.Resources.Requests[name] = quantity
+		}
+	}
+	names := make([]string, 0, len(t.Env))
+	for name := range t.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := t.Env[name]
+		found := false
+		for index := range container.Env {
+			if container.Env[index].Name == name {
+				container.Env[index] = corev1.EnvVar{Name: name, Value: value}
+				found = true
+			}
+		}
+		if !found {
+			container.Env = append(container.Env, corev1.EnvVar{Name: name, Value: value})
+		}
+	}
+}
+
+// replaceImageTag returns image with its tag, or digest, replaced by tag.
+func replaceImageTag(image, tag string) string {
+	if index := strings.Index(image, "@"); index >= 0 {
+		image = image[:index]
+	}
+	if index := strings.LastIndex(image, ":"); index > strings.LastIndex(image, "/") {
+		image = image[:index]
+	}
+	return image + ":" + tag
+}
@@ -0,0 +1,255 @@
+package resource
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/constants"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestDeploymentGenerator(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewSimpleClientset()
+	generator := NewDeploymentGenerator(ctx, kubeClient, newTestResolver(), nil)
+
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "web",
+			Namespace:       "devops-fat",
+			ResourceVersion: "42",
+			UID:             "uid",
+			Annotations: map[string]string{
+				"deployment.kubernetes.io/revision":                           "2",
+				"uat." + constants.IcebergTransformDomain + "/replicas":       "1",
+				"uat." + constants.IcebergTransformDomain + "/image-tag":      "v2",
+				"uat." + constants.IcebergTransformDomain + "/resources":      `{"limits":{"memory":"1Gi"}}`,
+				"uat." + constants.IcebergTransformDomain + "/env.DEBUG":      "false",
+				"uat." + constants.IcebergTransformDomain + "/init.image-tag": "v3",
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					NodeSelector:      map[string]string{"disk": "ssd"},
+					Tolerations:       []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+					PriorityClassName: "high",
+					InitContainers:    []corev1.Container{{Name: "init", Image: "registry:5000/init@sha256:abc"}},
+					Containers: []corev1.Container{{
+						Name:  "web",
+						Image: "registry:5000/web:v1",
+						Env:   []corev1.EnvVar{{Name: "DEBUG", Value: "true"}},
+						Resources: corev1.ResourceRequirements{
+							Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+						},
+					}},
+				},
+			},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: 3},
+	}
+	if _, err := generator.Create(deployment); err != nil {
+		t.Fatal(err)
+	}
+
+	uat, err := kubeClient.AppsV1().Deployments("devops-uat").Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uat.Annotations) != 0 || len(uat.UID) != 0 || uat.Status.Replicas != 0 || !IsPropagated(uat) {
+		t.Errorf("unexpected uat metadata %v", uat.ObjectMeta)
+	}
+	spec := uat.Spec.Template.Spec
+	if spec.NodeSelector["disk"] != "ssd" || len(spec.Tolerations) != 1 || spec.PriorityClassName != "high" {
+		t.Errorf("expected the pod spec to be kept, got %v", spec)
+	}
+	if *uat.Spec.Replicas != 1 || spec.Containers[0].Image != "registry:5000/web:v2" || spec.InitContainers[0].Image != "registry:5000/init:v3" {
+		t.Errorf("unexpected uat transform %v", uat.Spec)
+	}
+	if limits := spec.Containers[0].Resources.Limits; limits.Memory().String() != "1Gi" || limits.Cpu().String() != "1" {
+		t.Errorf("unexpected uat resources %v", limits)
+	}
+	if env := spec.Containers[0].Env; len(env) != 1 || env[0].Value != "false" {
+		t.Errorf("unexpected uat env %v", env)
+	}
+
+	sit, err := kubeClient.AppsV1().Deployments("devops-sit").Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *sit.Spec.Replicas != 3 || sit.Spec.Template.Spec.Containers[0].Image != "registry:5000/web:v1" {
+		t.Errorf("expected sit to be copied as is, got %v", sit.Spec)
+	}
+
+	deployment.Annotations["sit."+constants.IcebergTransformDomain+"/replicas"] = "many"
+	deployment.Name = "api"
+	if _, err := generator.Create(deployment); err == nil {
+		t.Error("expected an invalid transform to fail")
+	}
+}
+
+func TestJobGenerator(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewSimpleClientset()
+	generator := NewJobGenerator(ctx, kubeClient, newTestResolver(), nil)
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "devops-fat"},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"controller-uid": "uid"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"controller-uid": "uid", "job-name": "migrate", "app": "db"}},
+			},
+		},
+	}
+	if _, err := generator.Create(job); err != nil {
+		t.Fatal(err)
+	}
+	uat, err := kubeClient.BatchV1().Jobs("devops-uat").Get(ctx, "migrate", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uat.Spec.Selector != nil || len(uat.Spec.Template.Labels) != 1 {
+		t.Errorf("expected the generated selector to be dropped, got %v", uat.Spec)
+	}
+
+	// jobs created by cronjobs are not copied
+	owned := job.DeepCopy()
+	owned.Name = "backup-1"
+	isController := true
+	owned.OwnerReferences = []metav1.OwnerReference{{Kind: "CronJob", Name: "backup", Controller: &isController}}
+	if _, err := generator.Create(owned); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kubeClient.BatchV1().Jobs("devops-uat").Get(ctx, "backup-1", metav1.GetOptions{}); err == nil {
+		t.Error("expected owned job to be skipped")
+	}
+}
+
+func TestWorkloadOverrides(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewSimpleClientset()
+	overrides := []*config.EnvironmentOverride{
+		{
+			Env:      "uat",
+			Kinds:    []string{"deployment"},
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "web"}},
+			JSONPatch: []*config.PatchOperation{
+				{Path: "/spec/replicas", Value: 1},
+				{Op: "add", Path: "/metadata/labels/env", Value: "uat"},
+			},
+			StrategicMergePatch: map[string]interface{}{
+				"spec": map[interface{}]interface{}{
+					"template": map[interface{}]interface{}{
+						"spec": map[interface{}]interface{}{
+							"containers": []interface{}{
+								map[interface{}]interface{}{"name": "web", "env": []interface{}{
+									map[interface{}]interface{}{"name": "DEBUG", "value": "false"},
+								}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{Env: "uat", Kinds: []string{"StatefulSet"}, JSONPatch: []*config.PatchOperation{{Path: "/spec/replicas", Value: 5}}},
+	}
+	generator := NewDeploymentGenerator(ctx, kubeClient, newTestResolver(), func() []*config.EnvironmentOverride {
+		return overrides
+	})
+
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "devops-fat", Labels: map[string]string{"tier": "web"}},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "web", Image: "web:v1", Env: []corev1.EnvVar{{Name: "DEBUG", Value: "true"}, {Name: "PORT", Value: "80"}}},
+						{Name: "proxy", Image: "proxy:v1"},
+					},
+				},
+			},
+		},
+	}
+	if _, err := generator.Create(deployment); err != nil {
+		t.Fatal(err)
+	}
+
+	uat, err := kubeClient.AppsV1().Deployments("devops-uat").Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *uat.Spec.Replicas != 1 || uat.Labels["env"] != "uat" {
+		t.Errorf("expected the json patch to be applied, got %v %v", *uat.Spec.Replicas, uat.Labels)
+	}
+	containers := uat.Spec.Template.Spec.Containers
+	if len(containers) != 2 || len(containers[0].Env) != 2 || containers[0].Env[0].Value != "false" {
+		t.Errorf("expected the strategic merge patch to be applied, got %v", containers)
+	}
+	sit, _ := kubeClient.AppsV1().Deployments("devops-sit").Get(ctx, "web", metav1.GetOptions{})
+	if *sit.Spec.Replicas != 3 {
+		t.Errorf("expected sit not to be patched, got %d", *sit.Spec.Replicas)
+	}
+
+	// a changed override reaches the existing copies, the others are kept
+	overrides[0].JSONPatch[0].Value = 2
+	local := deployment.DeepCopy()
+	local.Name, local.Namespace = "api", "devops-uat"
+	kubeClient.AppsV1().Deployments("devops-uat").Create(ctx, local, metav1.CreateOptions{})
+	for _, name := range []string{"web", "api"} {
+		source := deployment.DeepCopy()
+		source.Name = name
+		if _, err := generator.Create(source); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uat, _ = kubeClient.AppsV1().Deployments("devops-uat").Get(ctx, "web", metav1.GetOptions{})
+	if *uat.Spec.Replicas != 2 {
+		t.Errorf("expected the changed override to update the copy, got %d", *uat.Spec.Replicas)
+	}
+	local, _ = kubeClient.AppsV1().Deployments("devops-uat").Get(ctx, "api", metav1.GetOptions{})
+	if *local.Spec.Replicas != 3 || IsPropagated(local) {
+		t.Errorf("expected the deployment not made by iceberg to be kept, got %v", local)
+	}
+}
+
+func TestJobGeneratorUpdate(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewSimpleClientset()
+	overrides := []*config.EnvironmentOverride{
+		{Env: "uat", JSONPatch: []*config.PatchOperation{{Op: "add", Path: "/metadata/labels/env", Value: "uat"}}},
+	}
+	generator := NewJobGenerator(ctx, kubeClient, newTestResolver(), func() []*config.EnvironmentOverride {
+		return overrides
+	})
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "devops-fat"},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "migrate", Image: "migrate:v1"}}},
+			},
+		},
+	}
+	if _, err := generator.Create(job); err != nil {
+		t.Fatal(err)
+	}
+	overrides[0].JSONPatch[0].Value = "staging"
+	job.Spec.Template.Spec.Containers[0].Image = "migrate:v2"
+	if _, err := generator.Create(job); err != nil {
+		t.Fatal(err)
+	}
+	uat, _ := kubeClient.BatchV1().Jobs("devops-uat").Get(ctx, "migrate", metav1.GetOptions{})
+	if uat.Labels["env"] != "staging" || uat.Spec.Template.Spec.Containers[0].Image != "migrate:v1" {
+		t.Errorf("expected only the metadata of the job copy to be updated, got %v %v", uat.Labels, uat.Spec)
+	}
+}
@@ -0,0 +1,115 @@
+package syncer
+
+// AccessLevel is the provider neutral role of a member in a group or repository.
+type AccessLevel string
+
+const (
+	DeveloperAccess  AccessLevel = "developer"
+	MaintainerAccess AccessLevel = "maintainer"
+)
+
+// SCMGroup is a gitlab group or a gitea organization.
+type SCMGroup struct {
+	ID          int
+	Name        string
+	FullPath    string
+	Description string
+	// ParentID is the group the group is created in, zero for a top level
+	// group. Only providers with nested groups support it.
+	ParentID int
+}
+
+// GroupLayout places the groups of workspaces in the source code management
+// system.
+type GroupLayout struct {
+	// Parent is the full path or ID of the group workspace groups are created
+	// in, workspace groups are top level if it's blank.
+	Parent string
+	// SubGroups are created in every workspace group.
+	SubGroups []string
+}
+
+// SCMRepository is a gitlab project or a gitea repository.
+type SCMRepository struct {
+	ID          int
+	Name        string
+	FullPath    string
+	Description string
+	// GroupID and GroupPath identify the group the repository belongs to,
+	// providers use whichever their api requires.
+	GroupID   int
+	GroupPath string
+	// CIConfigPath is the pipeline definition used by the repository.
+	CIConfigPath string
+	// Template is the provider specific template the repository is created
+	// from, a gitlab instance template name or a gitea template repository.
+	Template string
+	// Topics classify the repository, nil leaves them untouched on update.
+	Topics []string
+	// Archived repositories are read-only.
+	Archived bool
+	// DefaultBranch is the branch files are committed to.
+	DefaultBranch string
+}
+
+// SCMFile is a file committed to a repository.
+type SCMFile struct {
+	Path    string
+	Content []byte
+}
+
+// SCMUser is a user of the source code management system.
+type SCMUser struct {
+	ID       int
+	Username string
+	Name     string
+	Email    string
+}
+
+// SCM abstracts the source code management system that workspaces,
+// applications, users and workspace members are synced into.
+//
+// Create methods return a kubernetes AlreadyExists error when the resource
+// exists, and Get methods return a NotFound error when it doesn't, so that
+// generators can adopt existing resources regardless of the provider.
+type SCM interface {
+	// Provider returns the name of the backend, e.g. gitlab or gitea
+	Provider() string
+
+	CreateGroup(group *SCMGroup) (*SCMGroup, error)
+	// GetGroup looks a group up by its full path
+	GetGroup(fullPath string) (*SCMGroup, error)
+	// UpdateGroup updates the description of the group
+	UpdateGroup(group *SCMGroup) error
+
+	CreateRepository(repository *SCMRepository) (*SCMRepository, error)
+	// GetRepository looks a repository up by its full path or its ID
+	GetRepository(fullPath string) (*SCMRepository, error)
+	// UpdateRepository updates the description and the topics of the repository
+	UpdateRepository(repository *SCMRepository) error
+	// TransferRepository moves the repository to another group
+	TransferRepository(repository *SCMRepository, group *SCMGroup) (*SCMRepository, error)
+	// SetArchived archives the repository, making it read-only, or unarchives it
+	SetArchived(repository *SCMRepository, archived bool) error
+	// SetCIConfigPath points the repository's pipeline to a new definition
+	SetCIConfigPath(repository *SCMRepository, ciConfigPath string) error
+
+	// ListFiles returns the paths of the files in the repository at ref,
+	// the default branch if ref is blank
+	ListFiles(repository *SCMRepository, ref string) ([]string, error)
+	// GetFile returns the content of a file in the repository at ref, the
+	// default branch if ref is blank
+	GetFile(repository *SCMRepository, path, ref string) ([]byte, error)
+	// CommitFiles creates or overwrites the files on the default branch,
+	// in a single commit if the provider supports it
+	CommitFiles(repository *SCMRepository, message string, files []*SCMFile) error
+
+	CreateUser(user *SCMUser) (*SCMUser, error)
+	// GetUser looks a user up by its username
+	GetUser(username string) (*SCMUser, error)
+
+	// AddGroupMember adds a user to a group, adding an existing member is not an error
+	AddGroupMember(group *SCMGroup, user *SCMUser, level AccessLevel) error
+	// AddRepositoryMember adds a user to a repository, adding an existing member is not an error
+	AddRepositoryMember(repository *SCMRepository, user *SCMUser, level AccessLevel) error
+}
@@ -0,0 +1,174 @@
+package scm
+
+import (
+	"bytes"
+	"github.com/hchenc/application/pkg/apis/app/v1beta1"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const templateSuffix = ".tmpl"
+
+// bootstrapData is what the bootstrap templates are rendered with, e.g.
+// {{.Image}} or {{index .Namespaces "uat"}}.
+type bootstrapData struct {
+	// Name is the name of the application and its project
+	Name      string
+	Namespace string
+	Workspace string
+	Pipeline  string
+	// Registry is the registry host and RegistryProject the registry
+	// namespace of the workspace
+	Registry        string
+	RegistryProject string
+	// Image is the image repository of the application
+	Image string
+	// Namespaces maps every environment to the namespace of the workspace
+	Namespaces map[string]string
+}
+
+func newBootstrapData(application *v1beta1.Application, workspace, pipeline, registry string, namespaces map[string]string) *bootstrapData {
+	return &bootstrapData{
+		Name:            application.Name,
+		Namespace:       application.Namespace,
+		Workspace:       workspace,
+		Pipeline:        pipeline,
+		Registry:        registry,
+		RegistryProject: workspace,
+		Image:           path.Join(registry, workspace, application.Name),
+		Namespaces:      namespaces,
+	}
+}
+
+// bootstrap renders the templates of the pipeline and commits them to the
+// project, files of the project are kept unless overwrite is true.
+func (p projectInfo) bootstrap(application *v1beta1.Application, workspace string, project *syncer.SCMRepository, pipeline *config.IntegrateOption, overwrite bool) error {
+	templates, err := p.loadTemplates(pipeline.Bootstrap)
+	if err != nil {
+		return err
+	}
+	namespaces, err := p.namespaces.Namespaces(workspace)
+	if err != nil {
+		return err
+	}
+	files, err := renderTemplates(templates, newBootstrapData(application, workspace, pipeline.Pipeline, p.registryServer(), namespaces))
+	if err != nil {
+		return err
+	}
+	if !overwrite {
+		existing, err := p.scm.ListFiles(project, "")
+		if err != nil {
+			return err
+		}
+		files = excludeFiles(files, existing)
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	return p.scm.CommitFiles(project, pipeline.Bootstrap.GetCommitMessage(), files)
+}
+
+// loadTemplates reads the templates from the directory or the repository of
+// options, keyed by their relative path.
+func (p projectInfo) loadTemplates(options *config.BootstrapOptions) (map[string][]byte, error) {
+	if len(options.Dir) != 0 {
+		return loadTemplateDir(options.Dir)
+	}
+	repository, err := p.scm.GetRepository(options.Repository)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := p.scm.ListFiles(repository, options.Ref)
+	if err != nil {
+		return nil, err
+	}
+	templates := map[string][]byte{}
+	for _, filePath := range paths {
+		content, err := p.scm.GetFile(repository, filePath, options.Ref)
+		if err != nil {
+			return nil, err
+		}
+		templates[filePath] = content
+	}
+	return templates, nil
+}
+
+// loadTemplateDir reads the files of dir recursively. Hidden entries of
+// mounted ConfigMaps(..data, ..<timestamp>) are skipped and symlinks are
+// followed to files only.
+func loadTemplateDir(dir string) (map[string][]byte, error) {
+	templates := map[string][]byte{}
+	err := filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), "..") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if info, err = os.Stat(filePath); err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		templates[filepath.ToSlash(relPath)] = content
+		return nil
+	})
+	return templates, err
+}
+
+// renderTemplates executes every template with data, the files are sorted by
+// path so that commits are reproducible.
+func renderTemplates(templates map[string][]byte, data *bootstrapData) ([]*syncer.SCMFile, error) {
+	files := make([]*syncer.SCMFile, 0, len(templates))
+	for filePath, content := range templates {
+		tmpl, err := template.New(filePath).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			return nil, err
+		}
+		var rendered bytes.Buffer
+		if err := tmpl.Execute(&rendered, data); err != nil {
+			return nil, err
+		}
+		files = append(files, &syncer.SCMFile{
+			Path:    strings.TrimSuffix(filePath, templateSuffix),
+			Content: rendered.Bytes(),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+func excludeFiles(files []*syncer.SCMFile, paths []string) []*syncer.SCMFile {
+	excluded := map[string]bool{}
+	for _, filePath := range paths {
+		excluded[filePath] = true
+	}
+	kept := files[:0]
+	for _, file := range files {
+		if !excluded[file.Path] {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
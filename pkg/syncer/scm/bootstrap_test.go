@@ -0,0 +1,75 @@
+package scm
+
+import (
+	"github.com/hchenc/application/pkg/apis/app/v1beta1"
+	"io/ioutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTemplateDir(t *testing.T) {
+	// a mounted ConfigMap links its keys to the current ..data directory
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	revision := filepath.Join(dir, "..2021_08_01")
+	if err := os.Mkdir(revision, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(revision, "Dockerfile.tmpl"), []byte("FROM golang"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(revision, filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..data", "Dockerfile.tmpl"), filepath.Join(dir, "Dockerfile.tmpl")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "deploy"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "deploy", "deployment.yaml"), []byte("kind: Deployment"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := loadTemplateDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 2 || string(templates["Dockerfile.tmpl"]) != "FROM golang" || string(templates["deploy/deployment.yaml"]) != "kind: Deployment" {
+		t.Errorf("unexpected templates %v", templates)
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	application := &v1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "devops-fat"},
+	}
+	data := newBootstrapData(application, "devops", "go", "harbor.hchenc.com", map[string]string{"fat": "devops-fat", "uat": "devops-uat"})
+	files, err := renderTemplates(map[string][]byte{
+		"Dockerfile.tmpl": []byte("# {{.Pipeline}}"),
+		".gitlab-ci.yml":  []byte(`image: {{.Image}} namespace: {{index .Namespaces "uat"}}`),
+	}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Path != ".gitlab-ci.yml" || files[1].Path != "Dockerfile" {
+		t.Fatalf("unexpected files %v", files)
+	}
+	if content := string(files[0].Content); content != "image: harbor.hchenc.com/devops/web namespace: devops-uat" {
+		t.Errorf("unexpected content %q", content)
+	}
+
+	if _, err := renderTemplates(map[string][]byte{"Dockerfile": []byte("{{.Missing}}")}, data); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+
+	files = excludeFiles(files, []string{"Dockerfile", "README.md"})
+	if len(files) != 1 || files[0].Path != ".gitlab-ci.yml" {
+		t.Errorf("unexpected files %v", files)
+	}
+}
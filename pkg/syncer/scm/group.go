@@ -0,0 +1,189 @@
+package scm
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/apis/tenant/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"github.com/hchenc/iceberg/pkg/utils"
+	"github.com/hchenc/pager/pkg/apis/devops/v1alpha1"
+	pager "github.com/hchenc/pager/pkg/client/clientset/versioned"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
+)
+
+type groupInfo struct {
+	scm         syncer.SCM
+	layout      func() *syncer.GroupLayout
+	pagerClient *pager.Clientset
+	logger      *logrus.Logger
+	ctx         context.Context
+}
+
+func (g groupInfo) Create(obj interface{}) (interface{}, error) {
+	workspace := obj.(*v1alpha2.WorkspaceTemplate)
+	workspaceLogInfo := logrus.Fields{
+		"workspace": workspace.Name,
+		"provider":  g.scm.Provider(),
+	}
+	g.logger.WithFields(workspaceLogInfo).Info("start to create scm group")
+
+	layout := g.layout()
+	request := &syncer.SCMGroup{
+		Name:        workspace.Name,
+		FullPath:    workspace.Name,
+		Description: syncer.MarkDescription(workspace.GetAnnotations()[constants.KubesphereDescription]),
+	}
+	if layout != nil && len(layout.Parent) != 0 {
+		parent, err := g.scm.GetGroup(layout.Parent)
+		if err != nil {
+			g.logger.WithFields(workspaceLogInfo).WithFields(logrus.Fields{
+				"parent":  layout.Parent,
+				"message": "failed to get scm parent group",
+			}).Error(err)
+			return nil, err
+		}
+		request.ParentID = parent.ID
+		request.FullPath = parent.FullPath + "/" + workspace.Name
+	}
+
+	group, err := g.scm.CreateGroup(request)
+	if errors.IsAlreadyExists(err) {
+		group, err = g.scm.GetGroup(request.FullPath)
+		if err == nil {
+			err = g.adopt(workspace, group)
+		}
+		if err == nil {
+			g.logger.WithFields(workspaceLogInfo).WithFields(logrus.Fields{
+				"groupName": group.FullPath,
+				"groupId":   group.ID,
+			}).Info("group already exist, finish to get scm group")
+		}
+	}
+	if err != nil {
+		g.logger.WithFields(workspaceLogInfo).WithFields(logrus.Fields{
+			"message": "failed to create scm group",
+		}).Error(err)
+		return nil, err
+	}
+
+	if layout != nil {
+		if err := g.createSubGroups(group, layout.SubGroups); err != nil {
+			g.logger.WithFields(workspaceLogInfo).WithFields(logrus.Fields{
+				"message": "failed to create scm subgroups",
+			}).Error(err)
+			return group, err
+		}
+	}
+
+	_, err = g.pagerClient.
+		DevopsV1alpha1().
+		Pagers(constants.DevopsNamespace).
+		Create(g.ctx, &v1alpha1.Pager{
+			ObjectMeta: v1.ObjectMeta{
+				Name: "workspace-" + workspace.Name,
+			},
+			Spec: v1alpha1.PagerSpec{
+				MessageID:   strconv.Itoa(group.ID),
+				MessageName: group.FullPath,
+				MessageType: workspace.Kind,
+			},
+		}, v1.CreateOptions{})
+	if err == nil || errors.IsAlreadyExists(err) {
+		return group, nil
+	} else {
+		g.logger.WithFields(workspaceLogInfo).WithFields(logrus.Fields{
+			"message": "failed to create pager",
+			"pager":   "workspace-" + workspace.Name,
+		}).Error(err)
+		return group, err
+	}
+}
+
+// adopt marks the existing group if the workspace may take it over.
+func (g groupInfo) adopt(workspace *v1alpha2.WorkspaceTemplate, group *syncer.SCMGroup) error {
+	logger := g.logger.WithFields(logrus.Fields{
+		"workspace": workspace.Name,
+		"groupName": group.FullPath,
+		"groupId":   group.ID,
+		"provider":  g.scm.Provider(),
+	})
+	unmarked, err := adoption(g.ctx, g.pagerClient, logger, workspace, "group "+group.FullPath, "workspace-"+workspace.Name, group.Description, group.ID)
+	if err != nil || !unmarked {
+		return err
+	}
+	group.Description = syncer.MarkDescription(group.Description)
+	return g.scm.UpdateGroup(group)
+}
+
+// createSubGroups creates the subgroups of the workspace group, existing ones
+// are kept.
+func (g groupInfo) createSubGroups(group *syncer.SCMGroup, subGroups []string) error {
+	for _, name := range subGroups {
+		_, err := g.scm.CreateGroup(&syncer.SCMGroup{
+			Name:        name,
+			FullPath:    group.FullPath + "/" + name,
+			Description: syncer.MarkDescription(""),
+			ParentID:    group.ID,
+		})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g groupInfo) Update(objOld interface{}, objNew interface{}) error {
+	panic("implement me")
+}
+
+func (g groupInfo) Delete(workspaceName string) error {
+	pagerName := "workspace-" + workspaceName
+
+	workspaceLogInfo := logrus.Fields{
+		"workspace": workspaceName,
+	}
+	g.logger.WithFields(workspaceLogInfo).Info("start to delete scm group pager")
+
+	err := g.pagerClient.DevopsV1alpha1().Pagers(constants.DevopsNamespace).Delete(g.ctx, pagerName, v1.DeleteOptions{})
+	if err == nil || errors.IsNotFound(err) {
+		g.logger.WithFields(workspaceLogInfo).WithFields(logrus.Fields{
+			"pager": pagerName,
+		}).Info("finish to delete scm group pager")
+		return nil
+	} else {
+		g.logger.WithFields(workspaceLogInfo).WithFields(logrus.Fields{
+			"message": "failed to delete scm group pager",
+			"pager":   pagerName,
+		}).Error(err)
+		return err
+	}
+}
+
+func (g groupInfo) GetByName(key string) (interface{}, error) {
+	return g.scm.GetGroup(key)
+}
+
+func (g groupInfo) GetByID(id int) (interface{}, error) {
+	panic("implement me")
+}
+
+func (g groupInfo) List(key string) (interface{}, error) {
+	panic("implement me")
+}
+
+func NewGroupGenerator(ctx context.Context, scm syncer.SCM, layout func() *syncer.GroupLayout, pagerClient *pager.Clientset) syncer.Generator {
+	logger := utils.GetLogger(logrus.Fields{
+		"component": scm.Provider(),
+		"resource":  "group",
+	})
+	return &groupInfo{
+		scm:         scm,
+		layout:      layout,
+		pagerClient: pagerClient,
+		ctx:         ctx,
+		logger:      logger,
+	}
+}
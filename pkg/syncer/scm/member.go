@@ -0,0 +1,157 @@
+package scm
+
+import (
+	"context"
+	iamv1alpha2 "github.com/hchenc/iceberg/pkg/apis/iam/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"github.com/hchenc/iceberg/pkg/utils"
+	"github.com/hchenc/pager/pkg/apis/devops/v1alpha1"
+	pager "github.com/hchenc/pager/pkg/client/clientset/versioned"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
+	"strings"
+)
+
+type memberInfo struct {
+	scm         syncer.SCM
+	pagerClient *pager.Clientset
+	logger      *logrus.Logger
+	ctx         context.Context
+}
+
+func (m memberInfo) Create(obj interface{}) (interface{}, error) {
+	rolebinding := obj.(*iamv1alpha2.WorkspaceRoleBinding)
+	groupName := rolebinding.Labels[constants.KubesphereWorkspace]
+	userName := rolebinding.Subjects[0].Name
+	memberLogInfo := logrus.Fields{
+		"rolebinding": rolebinding.Name,
+		"workspace":   groupName,
+		"user":        userName,
+		"provider":    m.scm.Provider(),
+	}
+
+	group, err := getGroupRecord(m.ctx, m.pagerClient, groupName)
+	if err != nil {
+		m.logger.WithFields(memberLogInfo).WithFields(logrus.Fields{
+			"message": "failed to get group pager",
+		}).Error(err)
+		return nil, err
+	}
+	user, err := getUserRecord(m.ctx, m.pagerClient, userName)
+	if err != nil {
+		m.logger.WithFields(memberLogInfo).WithFields(logrus.Fields{
+			"message": "failed to get user pager",
+		}).Error(err)
+		return nil, err
+	}
+
+	if err := m.scm.AddGroupMember(group, user, syncer.DeveloperAccess); err != nil {
+		m.logger.WithFields(memberLogInfo).WithFields(logrus.Fields{
+			"message": "failed to add scm group member",
+		}).Error(err)
+		return nil, err
+	}
+
+	_, err = m.pagerClient.
+		DevopsV1alpha1().
+		Pagers(constants.DevopsNamespace).
+		Create(m.ctx, &v1alpha1.Pager{
+			ObjectMeta: v1.ObjectMeta{
+				Name: "member-" + rolebinding.Name,
+			},
+			Spec: v1alpha1.PagerSpec{
+				MessageID:   strconv.Itoa(user.ID),
+				MessageName: user.Username,
+				MessageType: rolebinding.Kind,
+			},
+		}, v1.CreateOptions{})
+	if err == nil || errors.IsAlreadyExists(err) {
+		return user, nil
+	} else {
+		return user, err
+	}
+}
+
+func (m memberInfo) Update(objOld interface{}, objNew interface{}) error {
+	panic("implement me")
+}
+
+// Delete drops the record of the rolebinding. Records made before they were
+// keyed by rolebinding are named after the user, the one of the longest
+// username prefixing the rolebinding name is dropped.
+func (m memberInfo) Delete(rolebindingName string) error {
+	memberLogInfo := logrus.Fields{
+		"rolebinding": rolebindingName,
+	}
+	m.logger.WithFields(memberLogInfo).Info("start to delete scm member pager")
+
+	pagers := m.pagerClient.DevopsV1alpha1().Pagers(constants.DevopsNamespace)
+	pagerName := "member-" + rolebindingName
+	err := pagers.Delete(m.ctx, pagerName, v1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		pagerName, err = m.legacyRecord(rolebindingName)
+		if err == nil && len(pagerName) != 0 {
+			err = pagers.Delete(m.ctx, pagerName, v1.DeleteOptions{})
+		}
+	}
+	if err == nil || errors.IsNotFound(err) {
+		m.logger.WithFields(memberLogInfo).WithFields(logrus.Fields{
+			"pager": pagerName,
+		}).Info("finish to delete scm member pager")
+		return nil
+	} else {
+		m.logger.WithFields(memberLogInfo).WithFields(logrus.Fields{
+			"message": "failed to delete scm member pager",
+			"pager":   pagerName,
+		}).Error(err)
+		return err
+	}
+}
+
+// legacyRecord returns the name of the user keyed record of the rolebinding,
+// empty if there's none.
+func (m memberInfo) legacyRecord(rolebindingName string) (string, error) {
+	records, err := m.pagerClient.DevopsV1alpha1().Pagers(constants.DevopsNamespace).List(m.ctx, v1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+	var username string
+	for _, record := range records.Items {
+		name := record.Spec.MessageName
+		if record.Name == "member-"+name && strings.HasPrefix(rolebindingName, name+"-") && len(name) > len(username) {
+			username = name
+		}
+	}
+	if len(username) == 0 {
+		return "", nil
+	}
+	return "member-" + username, nil
+}
+
+func (m memberInfo) GetByName(name string) (interface{}, error) {
+	panic("implement me")
+}
+
+func (m memberInfo) GetByID(id int) (interface{}, error) {
+	panic("implement me")
+}
+
+func (m memberInfo) List(key string) (interface{}, error) {
+	panic("implement me")
+}
+
+func NewMemberGenerator(ctx context.Context, scm syncer.SCM, pagerClient *pager.Clientset) syncer.Generator {
+	logger := utils.GetLogger(logrus.Fields{
+		"component": scm.Provider(),
+		"resource":  "member",
+	})
+	return &memberInfo{
+		scm:         scm,
+		pagerClient: pagerClient,
+		logger:      logger,
+		ctx:         ctx,
+	}
+}
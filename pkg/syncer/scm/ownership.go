@@ -0,0 +1,44 @@
+package scm
+
+import (
+	"context"
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	pager "github.com/hchenc/pager/pkg/client/clientset/versioned"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
+)
+
+// adoption decides whether the existing scm resource, e.g. "group devops",
+// sharing the name of owner may be synced, true is returned if it has to be
+// marked first. Marked resources were created by iceberg, unmarked ones
+// are taken over if owner is annotated with constants.IcebergAdopt or if the
+// pager record of owner already points to them, which is the case for the
+// resources created before they were marked. The decision is logged.
+func adoption(ctx context.Context, pagerClient *pager.Clientset, logger *logrus.Entry, owner v1.Object, resource, pagerName, description string, id int) (bool, error) {
+	if syncer.IsMarked(description) {
+		return false, nil
+	}
+
+	var reason string
+	if syncer.AdoptionAllowed(owner) {
+		reason = "annotated with " + constants.IcebergAdopt
+	} else if record, err := pagerClient.DevopsV1alpha1().Pagers(constants.DevopsNamespace).Get(ctx, pagerName, v1.GetOptions{}); err == nil && record.Spec.MessageID == strconv.Itoa(id) {
+		reason = "recorded by pager " + pagerName
+	}
+	if len(reason) == 0 {
+		err := fmt.Errorf("%s exists but is not managed by iceberg, annotate %s with %s=true to adopt it", resource, owner.GetName(), constants.IcebergAdopt)
+		logger.WithFields(logrus.Fields{
+			"decision": "refused",
+			"message":  "refuse to adopt unmarked scm resource",
+		}).Error(err)
+		return false, err
+	}
+	logger.WithFields(logrus.Fields{
+		"decision": "adopted",
+		"reason":   reason,
+	}).Warn("adopt unmarked scm resource")
+	return true, nil
+}
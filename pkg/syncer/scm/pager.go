@@ -0,0 +1,54 @@
+package scm
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	pager "github.com/hchenc/pager/pkg/client/clientset/versioned"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
+)
+
+// getGroupRecord rebuilds the scm group of a workspace from its pager record.
+func getGroupRecord(ctx context.Context, pagerClient *pager.Clientset, workspaceName string) (*syncer.SCMGroup, error) {
+	record, err := pagerClient.DevopsV1alpha1().Pagers(constants.DevopsNamespace).Get(ctx, "workspace-"+workspaceName, v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.Atoi(record.Spec.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	return &syncer.SCMGroup{
+		ID:       id,
+		Name:     workspaceName,
+		FullPath: record.Spec.MessageName,
+	}, nil
+}
+
+// applicationRecordName is the name of the pager record of the project of an
+// application. Workspace and application names are DNS labels, the dot keeps
+// the records of equally named applications of two workspaces apart.
+func applicationRecordName(workspace, appName string) string {
+	return "application-" + workspace + "." + appName
+}
+
+// getUserRecord rebuilds the scm user of a kubesphere user from its pager record.
+func getUserRecord(ctx context.Context, pagerClient *pager.Clientset, userName string) (*syncer.SCMUser, error) {
+	record, err := pagerClient.DevopsV1alpha1().Pagers(constants.DevopsNamespace).Get(ctx, "user-"+userName, v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.Atoi(record.Spec.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	username := record.Spec.MessageName
+	if len(username) == 0 {
+		username = userName
+	}
+	return &syncer.SCMUser{
+		ID:       id,
+		Username: username,
+	}, nil
+}
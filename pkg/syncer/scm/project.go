@@ -0,0 +1,440 @@
+package scm
+
+import (
+	"context"
+	"github.com/hchenc/application/pkg/apis/app/v1beta1"
+	"github.com/hchenc/iceberg/pkg/clients/clientset"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"github.com/hchenc/iceberg/pkg/syncer/resource"
+	"github.com/hchenc/iceberg/pkg/utils"
+	"github.com/hchenc/pager/pkg/apis/devops/v1alpha1"
+	pager "github.com/hchenc/pager/pkg/client/clientset/versioned"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"strconv"
+	"strings"
+)
+
+type projectInfo struct {
+	scm             syncer.SCM
+	deletionPolicy  func() string
+	registryServer  func() string
+	namespaces      *resource.NamespaceResolver
+	integrateClient *clientset.IntegrateClient
+	pagerClient     *pager.Clientset
+	logger          *logrus.Logger
+	ctx             context.Context
+}
+
+func (p projectInfo) Create(obj interface{}) (interface{}, error) {
+	application := obj.(*v1beta1.Application)
+	appLogInfo := logrus.Fields{
+		"application": application.Name,
+		"namespace":   application.Namespace,
+		"provider":    p.scm.Provider(),
+	}
+	p.logger.WithFields(appLogInfo).Info("start to create scm project")
+	workspaceName, _, err := p.namespaces.Resolve(application.Namespace)
+	if resource.IsUnlabeledNamespace(err) {
+		p.logger.WithFields(appLogInfo).Warn(err)
+		return nil, nil
+	} else if err != nil {
+		p.logger.WithFields(appLogInfo).WithFields(logrus.Fields{
+			"message": "failed to resolve the workspace of namespace",
+		}).Error(err)
+		return nil, err
+	}
+	pipeline, err := p.integrateClient.GetIntegrateOption(workspaceName, application.Labels)
+	if err != nil {
+		p.logger.WithFields(appLogInfo).WithFields(logrus.Fields{
+			"appType": application.Labels[constants.KubesphereAppType],
+			"message": "failed to get integrate option",
+		}).Error(err)
+		return nil, err
+	}
+	creator := application.Annotations[constants.KubesphereCreator]
+	if creator == "admin" {
+		p.logger.WithFields(appLogInfo).Warn("admin user create action not work")
+		return nil, nil
+	}
+
+	group, err := getGroupRecord(p.ctx, p.pagerClient, workspaceName)
+	if err != nil {
+		p.logger.WithFields(appLogInfo).WithFields(logrus.Fields{
+			"message": "failed to get pager record workspace-" + workspaceName,
+		}).Error(err)
+		return nil, err
+	}
+
+	// the recorded project is updated in place, and transferred if the
+	// application moved to another workspace, instead of creating another one
+	project, err := p.getRecordedProject(workspaceName, application.Name)
+	if err != nil {
+		p.logger.WithFields(appLogInfo).WithFields(logrus.Fields{
+			"message": "failed to get recorded scm project",
+		}).Error(err)
+		return nil, err
+	}
+	if project != nil {
+		if err := p.syncProject(application, project, group, pipeline.CiConfigPath); err != nil {
+			p.logger.WithFields(appLogInfo).WithFields(logrus.Fields{
+				"project": project.FullPath,
+				"message": "failed to update scm project",
+			}).Error(err)
+			return nil, err
+		}
+	} else {
+		project, err = p.createProject(application, workspaceName, group, pipeline)
+		if err != nil {
+			return project, err
+		}
+	}
+	p.logger.WithFields(appLogInfo).Info("finish to create scm project")
+	if creator == "" {
+		return project, nil
+	}
+
+	user, err := getUserRecord(p.ctx, p.pagerClient, creator)
+	if err != nil {
+		p.logger.WithFields(appLogInfo).WithFields(logrus.Fields{
+			"message": "failed to get application creator",
+		}).Error(err)
+		return project, err
+	}
+	if err := p.scm.AddRepositoryMember(project, user, syncer.MaintainerAccess); err != nil {
+		p.logger.WithFields(appLogInfo).WithFields(logrus.Fields{
+			"message": "failed to add maintainer role to project",
+		}).Error(err)
+		return project, err
+	}
+	return project, nil
+}
+
+// createProject creates the project of the application in group, or adopts
+// the existing one, and records it. New projects are bootstrapped from the
+// templates of the pipeline before they're recorded, so that a failed
+// bootstrap is retried on the marked project left behind without
+// overwriting the files it already has.
+func (p projectInfo) createProject(application *v1beta1.Application, workspace string, group *syncer.SCMGroup, pipeline *config.IntegrateOption) (*syncer.SCMRepository, error) {
+	project, err := p.scm.CreateRepository(&syncer.SCMRepository{
+		Name:         application.Name,
+		Description:  syncer.MarkDescription(application.GetAnnotations()[constants.KubesphereDescription]),
+		GroupID:      group.ID,
+		GroupPath:    group.FullPath,
+		CIConfigPath: pipeline.CiConfigPath,
+		Template:     pipeline.Template,
+		Topics:       topicsOf(application),
+	})
+	created, retried := err == nil, false
+	if errors.IsAlreadyExists(err) {
+		project, err = p.scm.GetRepository(group.FullPath + "/" + application.Name)
+		if err == nil {
+			retried = syncer.IsMarked(project.Description)
+			err = p.adopt(application, workspace, project)
+		}
+	}
+	if err != nil {
+		p.logger.WithFields(logrus.Fields{
+			"application": application.Name,
+			"namespace":   application.Namespace,
+			"provider":    p.scm.Provider(),
+			"message":     "failed to create scm project",
+		}).Error(err)
+		return nil, err
+	}
+
+	if (created || retried) && pipeline.Bootstrap != nil {
+		if err := p.bootstrap(application, workspace, project, pipeline, created); err != nil {
+			p.logger.WithFields(logrus.Fields{
+				"application": application.Name,
+				"namespace":   application.Namespace,
+				"project":     project.FullPath,
+				"pipeline":    pipeline.Pipeline,
+				"message":     "failed to bootstrap scm project",
+			}).Error(err)
+			return nil, err
+		}
+		p.logger.WithFields(logrus.Fields{
+			"application": application.Name,
+			"project":     project.FullPath,
+			"pipeline":    pipeline.Pipeline,
+		}).Info("finish to bootstrap scm project")
+	}
+
+	_, err = p.pagerClient.
+		DevopsV1alpha1().
+		Pagers(constants.DevopsNamespace).
+		Create(p.ctx, &v1alpha1.Pager{
+			ObjectMeta: v1.ObjectMeta{
+				Name: applicationRecordName(workspace, application.Name),
+			},
+			Spec: v1alpha1.PagerSpec{
+				MessageID:   strconv.Itoa(project.ID),
+				MessageName: project.Name,
+				MessageType: application.Kind,
+			},
+		}, v1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return project, err
+	}
+	return project, nil
+}
+
+// getRecord returns the pager record of the project of the application, nil
+// if there's none. A record made before records were keyed by workspace is
+// moved to the workspace of the first application looking it up.
+func (p projectInfo) getRecord(workspace, appName string) (*v1alpha1.Pager, error) {
+	pagers := p.pagerClient.DevopsV1alpha1().Pagers(constants.DevopsNamespace)
+	record, err := pagers.Get(p.ctx, applicationRecordName(workspace, appName), v1.GetOptions{})
+	if err == nil || !errors.IsNotFound(err) {
+		return record, err
+	}
+	legacy, err := pagers.Get(p.ctx, "application-"+appName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	record, err = pagers.Create(p.ctx, &v1alpha1.Pager{
+		ObjectMeta: v1.ObjectMeta{
+			Name: applicationRecordName(workspace, appName),
+		},
+		Spec: legacy.Spec,
+	}, v1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if err := pagers.Delete(p.ctx, legacy.Name, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	p.logger.WithFields(logrus.Fields{
+		"application": appName,
+		"workspace":   workspace,
+		"pager":       record.Name,
+	}).Info("move application pager to its workspace")
+	return record, nil
+}
+
+// getRecordedProject returns the project recorded for the application, nil if
+// there's none. A record of a project deleted from the scm is dropped.
+func (p projectInfo) getRecordedProject(workspace, appName string) (*syncer.SCMRepository, error) {
+	record, err := p.getRecord(workspace, appName)
+	if err != nil || record == nil {
+		return nil, err
+	}
+	if _, err := strconv.Atoi(record.Spec.MessageID); err != nil {
+		return nil, err
+	}
+	project, err := p.scm.GetRepository(record.Spec.MessageID)
+	if errors.IsNotFound(err) {
+		err := p.pagerClient.DevopsV1alpha1().Pagers(constants.DevopsNamespace).Delete(p.ctx, record.Name, v1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return project, err
+}
+
+// syncProject brings the recorded project in line with the application: it
+// is unarchived, transferred to the group of the application's workspace and
+// gets the description, topics and pipeline of the application.
+func (p projectInfo) syncProject(application *v1beta1.Application, project *syncer.SCMRepository, group *syncer.SCMGroup, ciConfigPath string) error {
+	logger := p.logger.WithFields(logrus.Fields{
+		"application": application.Name,
+		"namespace":   application.Namespace,
+		"project":     project.FullPath,
+		"provider":    p.scm.Provider(),
+	})
+
+	if project.Archived {
+		if err := p.scm.SetArchived(project, false); err != nil {
+			return err
+		}
+		logger.Info("unarchive scm project")
+	}
+	if project.GroupID != group.ID {
+		transferred, err := p.scm.TransferRepository(project, group)
+		if err != nil {
+			return err
+		}
+		logger.WithFields(logrus.Fields{
+			"group": group.FullPath,
+		}).Info("transfer scm project")
+		*project = *transferred
+	}
+
+	description := syncer.MarkDescription(application.GetAnnotations()[constants.KubesphereDescription])
+	topics := topicsOf(application)
+	if project.Description != description || (topics != nil && !equalTopics(project.Topics, topics)) {
+		project.Description = description
+		project.Topics = topics
+		if err := p.scm.UpdateRepository(project); err != nil {
+			return err
+		}
+		logger.Info("update scm project description and topics")
+	}
+	if project.CIConfigPath != ciConfigPath {
+		if err := p.scm.SetCIConfigPath(project, ciConfigPath); err != nil {
+			return err
+		}
+		project.CIConfigPath = ciConfigPath
+		logger.WithFields(logrus.Fields{
+			"ciConfigPath": ciConfigPath,
+		}).Info("update scm project pipeline")
+	}
+	return nil
+}
+
+// topicsOf returns the topics annotated on the application, nil if there's
+// no annotation.
+func topicsOf(application *v1beta1.Application) []string {
+	value, exists := application.GetAnnotations()[constants.IcebergTopics]
+	if !exists {
+		return nil
+	}
+	topics := []string{}
+	for _, topic := range strings.Split(value, ",") {
+		if topic = strings.TrimSpace(topic); len(topic) != 0 {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+func equalTopics(current, expected []string) bool {
+	if len(current) != len(expected) {
+		return false
+	}
+	for i := range current {
+		if current[i] != expected[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// adopt marks the existing project if the application may take it over.
+func (p projectInfo) adopt(application *v1beta1.Application, workspace string, project *syncer.SCMRepository) error {
+	logger := p.logger.WithFields(logrus.Fields{
+		"application": application.Name,
+		"namespace":   application.Namespace,
+		"project":     project.FullPath,
+		"provider":    p.scm.Provider(),
+	})
+	unmarked, err := adoption(p.ctx, p.pagerClient, logger, application, "project "+project.FullPath, applicationRecordName(workspace, application.Name), project.Description, project.ID)
+	if err != nil || !unmarked {
+		return err
+	}
+	project.Description = syncer.MarkDescription(project.Description)
+	return p.scm.UpdateRepository(project)
+}
+
+func (p projectInfo) Update(objOld interface{}, objNew interface{}) error {
+	_, err := p.Create(objNew)
+	return err
+}
+
+// Delete archives the project of the application according to the deletion
+// policy, only projects created or adopted by iceberg are archived, then
+// drops its record. The application is given by its namespace/name key.
+func (p projectInfo) Delete(key string) error {
+	namespace, appName, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	appLogInfo := logrus.Fields{
+		"application": appName,
+		"namespace":   namespace,
+	}
+	workspaceName, _, err := p.namespaces.Resolve(namespace)
+	if resource.IsUnlabeledNamespace(err) || errors.IsNotFound(err) {
+		p.logger.WithFields(appLogInfo).Warn(err)
+		return nil
+	} else if err != nil {
+		p.logger.WithFields(appLogInfo).WithFields(logrus.Fields{
+			"message": "failed to resolve the workspace of namespace",
+		}).Error(err)
+		return err
+	}
+	record, err := p.getRecord(workspaceName, appName)
+	if err != nil {
+		p.logger.WithFields(appLogInfo).WithFields(logrus.Fields{
+			"message": "failed to get kubesphere application pager",
+		}).Error(err)
+		return err
+	} else if record == nil {
+		return nil
+	}
+
+	if policy := p.deletionPolicy(); policy == config.ProjectDeletionPolicyArchive {
+		project, err := p.getRecordedProject(workspaceName, appName)
+		if err != nil {
+			p.logger.WithFields(appLogInfo).WithFields(logrus.Fields{
+				"message": "failed to get recorded scm project",
+			}).Error(err)
+			return err
+		}
+		if project != nil && !project.Archived && syncer.IsMarked(project.Description) {
+			if err := p.scm.SetArchived(project, true); err != nil {
+				p.logger.WithFields(appLogInfo).WithFields(logrus.Fields{
+					"project": project.FullPath,
+					"message": "failed to archive scm project",
+				}).Error(err)
+				return err
+			}
+			p.logger.WithFields(appLogInfo).WithFields(logrus.Fields{
+				"project": project.FullPath,
+				"policy":  policy,
+			}).Info("finish to archive scm project")
+		}
+	}
+
+	p.logger.WithFields(appLogInfo).Info("start to delete kubesphere application pager")
+	err = p.pagerClient.DevopsV1alpha1().Pagers(constants.DevopsNamespace).Delete(p.ctx, record.Name, v1.DeleteOptions{})
+	if err == nil || errors.IsNotFound(err) {
+		p.logger.WithFields(appLogInfo).WithFields(logrus.Fields{
+			"pager": record.Name,
+		}).Info("finish to delete kubesphere application pager")
+		return nil
+	} else {
+		p.logger.WithFields(appLogInfo).WithFields(logrus.Fields{
+			"message": "failed to delete kubesphere application pager",
+			"pager":   record.Name,
+		}).Error(err)
+		return err
+	}
+}
+
+func (p projectInfo) GetByName(key string) (interface{}, error) {
+	return p.scm.GetRepository(key)
+}
+
+func (p projectInfo) GetByID(id int) (interface{}, error) {
+	panic("implement me")
+}
+
+func (p projectInfo) List(key string) (interface{}, error) {
+	panic("implement me")
+}
+
+func NewProjectGenerator(ctx context.Context, scm syncer.SCM, deletionPolicy func() string, registryServer func() string, namespaces *resource.NamespaceResolver, integrateClient *clientset.IntegrateClient, pagerClient *pager.Clientset) syncer.Generator {
+	logger := utils.GetLogger(logrus.Fields{
+		"component": scm.Provider(),
+		"resource":  "project",
+	})
+	return &projectInfo{
+		scm:             scm,
+		deletionPolicy:  deletionPolicy,
+		registryServer:  registryServer,
+		namespaces:      namespaces,
+		integrateClient: integrateClient,
+		pagerClient:     pagerClient,
+		logger:          logger,
+		ctx:             ctx,
+	}
+}
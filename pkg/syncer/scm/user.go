@@ -0,0 +1,115 @@
+package scm
+
+import (
+	"context"
+	"github.com/hchenc/iceberg/pkg/apis/iam/v1alpha2"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/syncer"
+	"github.com/hchenc/iceberg/pkg/utils"
+	"github.com/hchenc/pager/pkg/apis/devops/v1alpha1"
+	pager "github.com/hchenc/pager/pkg/client/clientset/versioned"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
+)
+
+type userInfo struct {
+	scm         syncer.SCM
+	pagerClient *pager.Clientset
+	logger      *logrus.Logger
+	ctx         context.Context
+}
+
+func (u userInfo) Create(obj interface{}) (interface{}, error) {
+	user := obj.(*v1alpha2.User)
+	userLogInfo := logrus.Fields{
+		"user":     user.Name,
+		"provider": u.scm.Provider(),
+	}
+
+	scmUser, err := u.scm.CreateUser(&syncer.SCMUser{
+		Username: user.Name,
+		Name:     user.Name,
+		Email:    user.Spec.Email,
+	})
+	if errors.IsAlreadyExists(err) {
+		scmUser, err = u.scm.GetUser(user.Name)
+	}
+	if err != nil {
+		u.logger.WithFields(userLogInfo).WithFields(logrus.Fields{
+			"message": "failed to create scm user",
+		}).Error(err)
+		return nil, err
+	}
+
+	_, err = u.pagerClient.
+		DevopsV1alpha1().
+		Pagers(constants.DevopsNamespace).
+		Create(u.ctx, &v1alpha1.Pager{
+			ObjectMeta: v1.ObjectMeta{
+				Name: "user-" + user.Name,
+			},
+			Spec: v1alpha1.PagerSpec{
+				MessageID:   strconv.Itoa(scmUser.ID),
+				MessageName: scmUser.Username,
+				MessageType: user.Kind,
+			},
+		}, v1.CreateOptions{})
+	if err == nil || errors.IsAlreadyExists(err) {
+		return scmUser, nil
+	} else {
+		return scmUser, err
+	}
+}
+
+func (u userInfo) Update(objOld interface{}, objNew interface{}) error {
+	panic("implement me")
+}
+
+func (u userInfo) Delete(userName string) error {
+	pagerName := "user-" + userName
+	userLogInfo := logrus.Fields{
+		"user": userName,
+	}
+	u.logger.WithFields(userLogInfo).Info("start to delete scm user pager")
+
+	err := u.pagerClient.DevopsV1alpha1().Pagers(constants.DevopsNamespace).Delete(u.ctx, pagerName, v1.DeleteOptions{})
+	if err == nil || errors.IsNotFound(err) {
+		u.logger.WithFields(userLogInfo).WithFields(logrus.Fields{
+			"pager": pagerName,
+		}).Info("finish to delete scm user pager")
+		return nil
+	} else {
+		u.logger.WithFields(userLogInfo).WithFields(logrus.Fields{
+			"message": "failed to delete scm user pager",
+			"pager":   pagerName,
+		}).Error(err)
+		return err
+	}
+}
+
+func (u userInfo) GetByName(name string) (interface{}, error) {
+	return u.scm.GetUser(name)
+}
+
+func (u userInfo) GetByID(id int) (interface{}, error) {
+	panic("implement me")
+}
+
+func (u userInfo) List(key string) (interface{}, error) {
+	panic("implement me")
+}
+
+func NewUserGenerator(ctx context.Context, scm syncer.SCM, pagerClient *pager.Clientset) syncer.Generator {
+	logger := utils.GetLogger(logrus.Fields{
+		"component": scm.Provider(),
+		"resource":  "user",
+	})
+	return &userInfo{
+		scm:         scm,
+		pagerClient: pagerClient,
+		ctx:         ctx,
+		logger:      logger,
+	}
+}
@@ -10,3 +10,18 @@ func TestClientService(t *testing.T) {
 	//service.Add("")
 	return
 }
+
+func TestMarkDescription(t *testing.T) {
+	for description, expected := range map[string]string{
+		"":                         ManagedMarker,
+		"payment service ":         "payment service " + ManagedMarker,
+		"billing " + ManagedMarker: "billing " + ManagedMarker,
+	} {
+		if marked := MarkDescription(description); marked != expected || !IsMarked(marked) {
+			t.Errorf("expected %q to be marked as %q, got %q", description, expected, marked)
+		}
+	}
+	if IsMarked("managed by iceberg") {
+		t.Error("expected a description without the marker not to be marked")
+	}
+}
@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"fmt"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/syncer/resource"
+	"regexp"
+	"strings"
+)
+
+// maxNamespaceLength is the length limit of namespace names, environment
+// namespaces are named <workspace>-<env>
+const maxNamespaceLength = 63
+
+var (
+	// harbor project names are lowercase words joined by single separators
+	harborProjectName = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*$`)
+	// gitlab paths start and end with a letter, a digit or _
+	gitlabPath = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]*[a-zA-Z0-9_]$|^[a-zA-Z0-9_]$`)
+
+	// top level paths reserved by gitlab, which groups and users share
+	gitlabReservedNames = sets("admin", "api", "assets", "dashboard", "explore", "files", "groups", "health_check",
+		"help", "import", "jwt", "login", "oauth", "profile", "projects", "public", "s", "search", "sitemap",
+		"snippets", "unsubscribes", "uploads", "users", "v2")
+	// names reserved by gitea for organizations and users
+	giteaReservedNames = sets("admin", "api", "assets", "attachments", "avatars", "captcha", "commits", "debug",
+		"error", "explore", "ghost", "help", "install", "issues", "less", "login", "metrics", "milestones", "new",
+		"notifications", "org", "plugins", "pulls", "raw", "repo", "search", "stars", "template", "user")
+	// harbor's default project, it's never managed by iceberg
+	harborReservedNames = sets("library")
+)
+
+func sets(names ...string) map[string]bool {
+	set := map[string]bool{}
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// validation is the outcome of a name check, errors reject the object and
+// warnings are returned to the client.
+type validation struct {
+	errors   []string
+	warnings []string
+}
+
+func (v *validation) errorf(format string, args ...interface{}) {
+	v.errors = append(v.errors, fmt.Sprintf(format, args...))
+}
+
+func (v *validation) warnf(format string, args ...interface{}) {
+	v.warnings = append(v.warnings, fmt.Sprintf(format, args...))
+}
+
+// validateWorkspaceName checks a workspace name against the environment
+// namespaces and the group and registry project created after it.
+func validateWorkspaceName(name string, conf *config.IntegrationConfig) *validation {
+	v := &validation{}
+	envs := conf.NamespaceOptions.GetEnvs()
+	if maxLength := maxNamespaceLength - maxEnvLength(envs); len(name) > maxLength {
+		v.errorf("workspace name %q must be at most %d characters, the environment namespaces %s are limited to %d", name, maxLength, resource.EnvironmentNamespace(name, "<env>"), maxNamespaceLength)
+	}
+	for _, env := range envs {
+		if name == env {
+			v.errorf("workspace name %q is reserved for the environment suffix", name)
+		}
+	}
+	// workspace groups nested in a gitlab parent group aren't top level
+	topLevel := config.GetSCMProvider(conf.SCMProvider) == config.SCMProviderGitea || conf.GitlabOptions == nil || len(conf.GitlabOptions.ParentGroup) == 0
+	validateSCMName(v, "workspace", name, topLevel, conf)
+
+	switch config.GetRegistryProvider(conf.RegistryProvider) {
+	case config.RegistryProviderHarbor:
+		if !harborProjectName.MatchString(name) {
+			v.errorf("workspace name %q is not a valid harbor project name, it must match %s", name, harborProjectName.String())
+		} else if harborReservedNames[name] {
+			v.warnf("workspace name %q is a harbor built-in project, iceberg won't adopt it without the %s annotation", name, constants.IcebergAdopt)
+		}
+	}
+	return v
+}
+
+// maxEnvLength is the length the longest environment adds to the names of
+// the environment namespaces.
+func maxEnvLength(envs []string) int {
+	length := 0
+	for _, env := range envs {
+		if envLength := len(resource.EnvironmentNamespace("", env)); envLength > length {
+			length = envLength
+		}
+	}
+	return length
+}
+
+// validateApplicationName checks an application name against the project
+// created after it.
+func validateApplicationName(name string) *validation {
+	v := &validation{}
+	if !gitlabPath.MatchString(name) || strings.HasSuffix(name, ".git") || strings.HasSuffix(name, ".atom") {
+		v.errorf("application name %q is not a valid project path, it must not start or end with '-' or '.' nor end with .git or .atom", name)
+	}
+	return v
+}
+
+// validateUserName checks a user name against the scm user created after
+// it, scm users and groups share the same namespace.
+func validateUserName(name string, conf *config.IntegrationConfig) *validation {
+	v := &validation{}
+	validateSCMName(v, "user", name, true, conf)
+	return v
+}
+
+func validateSCMName(v *validation, kind, name string, topLevel bool, conf *config.IntegrationConfig) {
+	if !gitlabPath.MatchString(name) || strings.HasSuffix(name, ".git") || strings.HasSuffix(name, ".atom") {
+		v.errorf("%s name %q is not a valid scm path, it must not start or end with '-' or '.' nor end with .git or .atom", kind, name)
+	}
+	reserved := gitlabReservedNames
+	if config.GetSCMProvider(conf.SCMProvider) == config.SCMProviderGitea {
+		reserved = giteaReservedNames
+	}
+	if topLevel && reserved[strings.ToLower(name)] {
+		v.errorf("%s name %q is reserved by %s", kind, name, config.GetSCMProvider(conf.SCMProvider))
+	}
+}
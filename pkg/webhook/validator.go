@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	application "github.com/hchenc/application/pkg/apis/app/v1beta1"
+	"github.com/hchenc/iceberg/pkg/config"
+	"github.com/hchenc/iceberg/pkg/constants"
+	"github.com/hchenc/iceberg/pkg/syncer/resource"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	iamv1alpha2 "github.com/hchenc/iceberg/pkg/apis/iam/v1alpha2"
+	workspace "github.com/hchenc/iceberg/pkg/apis/tenant/v1alpha2"
+)
+
+// nameValidator validates the name of created objects, names are immutable
+// so updates and deletes are always allowed.
+type nameValidator struct {
+	reader   client.Reader
+	config   func() *config.IntegrationConfig
+	validate func(ctx context.Context, v *nameValidator, obj *metav1.PartialObjectMetadata) (*validation, error)
+}
+
+func (n *nameValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create {
+		return admission.Allowed("")
+	}
+	obj := &metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(req.Object.Raw, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if len(obj.Name) == 0 {
+		obj.Name = req.Name
+	}
+	if len(obj.Namespace) == 0 {
+		obj.Namespace = req.Namespace
+	}
+	result, err := n.validate(ctx, n, obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(result.errors) != 0 {
+		return admission.Denied(strings.Join(result.errors, "; ")).WithWarnings(result.warnings...)
+	}
+	return admission.Allowed("").WithWarnings(result.warnings...)
+}
+
+// validateWorkspace rejects workspaces whose environment namespaces, as the
+// namespace generator names them for the configured environments, belong to
+// another workspace or whose group would collide with a user.
+func validateWorkspace(ctx context.Context, n *nameValidator, obj *metav1.PartialObjectMetadata) (*validation, error) {
+	conf := n.config()
+	v := validateWorkspaceName(obj.Name, conf)
+	for _, env := range conf.NamespaceOptions.GetEnvs() {
+		namespace := &corev1.Namespace{}
+		err := n.reader.Get(ctx, types.NamespacedName{Name: resource.EnvironmentNamespace(obj.Name, env)}, namespace)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if owner := namespace.Labels[constants.KubesphereWorkspace]; owner != obj.Name {
+			v.errorf("namespace %s of environment %s already belongs to workspace %q", namespace.Name, env, owner)
+		}
+	}
+	if err := n.reader.Get(ctx, types.NamespacedName{Name: obj.Name}, &iamv1alpha2.User{}); err == nil {
+		v.errorf("workspace name %q is taken by a user, scm groups and users share the same paths", obj.Name)
+	} else if !errors.IsNotFound(err) {
+		return nil, err
+	}
+	return v, nil
+}
+
+// validateApplication rejects applications named like one of another
+// workspace, scm projects are recorded by application name. Applications in
+// namespaces without environment get a warning since they aren't propagated.
+func validateApplication(ctx context.Context, n *nameValidator, obj *metav1.PartialObjectMetadata) (*validation, error) {
+	v := validateApplicationName(obj.Name)
+
+	namespace := &corev1.Namespace{}
+	if err := n.reader.Get(ctx, types.NamespacedName{Name: obj.Namespace}, namespace); err != nil {
+		return nil, err
+	}
+	owner := namespace.Labels[constants.KubesphereWorkspace]
+	if len(owner) != 0 && len(namespace.Labels[constants.IcebergEnvironment]) == 0 {
+		v.warnf("namespace %s of workspace %q has no %s label, the application isn't propagated to the other environments", obj.Namespace, owner, constants.IcebergEnvironment)
+	}
+
+	applications := &application.ApplicationList{}
+	if err := n.reader.List(ctx, applications, client.MatchingFields{"metadata.name": obj.Name}); err != nil {
+		return nil, err
+	}
+	for _, existing := range applications.Items {
+		if existing.Name != obj.Name || existing.Namespace == obj.Namespace {
+			continue
+		}
+		existingNamespace := &corev1.Namespace{}
+		if err := n.reader.Get(ctx, types.NamespacedName{Name: existing.Namespace}, existingNamespace); err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		if len(owner) == 0 || existingNamespace.Labels[constants.KubesphereWorkspace] != owner {
+			v.errorf("application name %q is taken by an application of namespace %s", obj.Name, existing.Namespace)
+			break
+		}
+	}
+	return v, nil
+}
+
+// validateUser rejects users whose scm user would collide with the group of
+// a workspace.
+func validateUser(ctx context.Context, n *nameValidator, obj *metav1.PartialObjectMetadata) (*validation, error) {
+	v := validateUserName(obj.Name, n.config())
+	if err := n.reader.Get(ctx, types.NamespacedName{Name: obj.Name}, &workspace.WorkspaceTemplate{}); err == nil {
+		v.errorf("user name %q is taken by a workspace, scm groups and users share the same paths", obj.Name)
+	} else if !errors.IsNotFound(err) {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,35 @@
+package webhook
+
+import (
+	"github.com/hchenc/iceberg/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+const (
+	WorkspaceTemplatePath = "/validate-tenant-kubesphere-io-v1alpha2-workspacetemplate"
+	ApplicationPath       = "/validate-app-k8s-io-v1beta1-application"
+	UserPath              = "/validate-iam-kubesphere-io-v1alpha2-user"
+)
+
+// SetUp registers the validating webhooks of workspace, application and user
+// names on the webhook server of mgr. Objects are read from the api server
+// directly, a rejected name must not depend on a stale cache.
+func SetUp(mgr manager.Manager, conf func() *config.IntegrationConfig) {
+	server := mgr.GetWebhookServer()
+	server.Register(WorkspaceTemplatePath, &webhook.Admission{Handler: &nameValidator{
+		reader:   mgr.GetAPIReader(),
+		config:   conf,
+		validate: validateWorkspace,
+	}})
+	server.Register(ApplicationPath, &webhook.Admission{Handler: &nameValidator{
+		reader:   mgr.GetAPIReader(),
+		config:   conf,
+		validate: validateApplication,
+	}})
+	server.Register(UserPath, &webhook.Admission{Handler: &nameValidator{
+		reader:   mgr.GetAPIReader(),
+		config:   conf,
+		validate: validateUser,
+	}})
+}
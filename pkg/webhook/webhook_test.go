@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	application "github.com/hchenc/application/pkg/apis/app/v1beta1"
+	"github.com/hchenc/iceberg/pkg/config"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	iamv1alpha2 "github.com/hchenc/iceberg/pkg/apis/iam/v1alpha2"
+	workspace "github.com/hchenc/iceberg/pkg/apis/tenant/v1alpha2"
+)
+
+func TestValidateNames(t *testing.T) {
+	gitlab := &config.IntegrationConfig{SCMProvider: config.SCMProviderGitlab, RegistryProvider: config.RegistryProviderHarbor}
+	nested := &config.IntegrationConfig{GitlabOptions: &config.GitlabOptions{ParentGroup: "platform"}}
+	gitea := &config.IntegrationConfig{SCMProvider: config.SCMProviderGitea, RegistryProvider: config.RegistryProviderDistribution}
+	custom := &config.IntegrationConfig{NamespaceOptions: &config.NamespaceOptions{Envs: []string{"dev", "production"}}}
+	// 53 characters, too long for the -production namespace only
+	long := "abcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyza"
+
+	for _, c := range []struct {
+		name     string
+		conf     *config.IntegrationConfig
+		errors   int
+		warnings int
+	}{
+		{"devops", gitlab, 0, 0},
+		{"dev-ops", gitlab, 0, 0},
+		{"uat", gitlab, 1, 0},
+		{"admin", gitlab, 1, 0},
+		{"admin", nested, 0, 0},
+		{"library", gitlab, 0, 1},
+		{"a..b", gitlab, 1, 0},
+		{"a..b", gitea, 0, 0},
+		{"org", gitea, 1, 0},
+		{"abcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijk", gitlab, 1, 0},
+		{"uat", custom, 0, 0},
+		{"production", custom, 1, 0},
+		{long, gitlab, 0, 0},
+		{long, custom, 1, 0},
+	} {
+		v := validateWorkspaceName(c.name, c.conf)
+		if len(v.errors) != c.errors || len(v.warnings) != c.warnings {
+			t.Errorf("workspace %s: expected %d errors and %d warnings, got %v and %v", c.name, c.errors, c.warnings, v.errors, v.warnings)
+		}
+	}
+
+	for name, errors := range map[string]int{"web": 0, "web.git": 1, "web.": 1} {
+		if v := validateApplicationName(name); len(v.errors) != errors {
+			t.Errorf("application %s: expected %d errors, got %v", name, errors, v.errors)
+		}
+	}
+	for name, errors := range map[string]int{"alice": 0, "users": 1, "alice.atom": 1} {
+		if v := validateUserName(name, gitlab); len(v.errors) != errors {
+			t.Errorf("user %s: expected %d errors, got %v", name, errors, v.errors)
+		}
+	}
+}
+
+func TestNameValidator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = application.AddToScheme(scheme)
+	_ = iamv1alpha2.AddToScheme(scheme)
+	_ = workspace.AddToScheme(scheme)
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "devops-fat", Labels: map[string]string{"kubesphere.io/workspace": "devops", "iceberg.io/environment": "fat"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "devops-uat", Labels: map[string]string{"kubesphere.io/workspace": "devops", "iceberg.io/environment": "uat"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ops-fat", Labels: map[string]string{"kubesphere.io/workspace": "devops"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-fat", Labels: map[string]string{"kubesphere.io/workspace": "team", "iceberg.io/environment": "fat"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "sandbox"}},
+		&application.Application{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "devops-fat"}},
+		&iamv1alpha2.User{ObjectMeta: metav1.ObjectMeta{Name: "alice"}},
+		&workspace.WorkspaceTemplate{ObjectMeta: metav1.ObjectMeta{Name: "team"}},
+	).Build()
+	conf := func() *config.IntegrationConfig { return &config.IntegrationConfig{} }
+
+	for _, c := range []struct {
+		validate  func(ctx context.Context, v *nameValidator, obj *metav1.PartialObjectMetadata) (*validation, error)
+		name      string
+		namespace string
+		operation admissionv1.Operation
+		allowed   bool
+	}{
+		{validateWorkspace, "devops", "", admissionv1.Create, true},
+		{validateWorkspace, "ops", "", admissionv1.Create, false},
+		{validateWorkspace, "alice", "", admissionv1.Create, false},
+		{validateApplication, "web", "devops-uat", admissionv1.Create, true},
+		{validateApplication, "web", "team-fat", admissionv1.Create, false},
+		{validateApplication, "web", "ops-fat", admissionv1.Create, true},
+		{validateApplication, "web", "sandbox", admissionv1.Create, false},
+		{validateApplication, "api", "sandbox", admissionv1.Create, true},
+		{validateApplication, "api", "ops-fat", admissionv1.Update, true},
+		{validateUser, "team", "", admissionv1.Create, false},
+		{validateUser, "bob", "", admissionv1.Create, true},
+	} {
+		raw, _ := json.Marshal(&metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: c.name, Namespace: c.namespace}})
+		req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: c.operation,
+			Name:      c.name,
+			Namespace: c.namespace,
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+		validator := &nameValidator{reader: reader, config: conf, validate: c.validate}
+		if resp := validator.Handle(context.Background(), req); resp.Allowed != c.allowed {
+			t.Errorf("%s/%s: expected allowed %v, got %+v", c.namespace, c.name, c.allowed, resp.Result)
+		}
+	}
+
+	// namespaces of the configured environments are checked
+	custom := func() *config.IntegrationConfig {
+		return &config.IntegrationConfig{NamespaceOptions: &config.NamespaceOptions{Envs: []string{"dev"}}}
+	}
+	validator := &nameValidator{reader: reader, config: custom, validate: validateWorkspace}
+	raw, _ := json.Marshal(&metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "ops"}})
+	resp := validator.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Name:      "ops",
+		Object:    runtime.RawExtension{Raw: raw},
+	}})
+	if !resp.Allowed {
+		t.Errorf("expected ops to be allowed without a fat environment, got %+v", resp.Result)
+	}
+}